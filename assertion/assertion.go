@@ -1,8 +1,11 @@
 package assertion
 
 import (
+	"bytes"
 	"encoding/json"
 	"testing"
+
+	core "github.com/menabrealabs/marlowe/v1/language/core"
 )
 
 func Json[T any](t *testing.T, contract T, target string) {
@@ -18,3 +21,78 @@ func Json[T any](t *testing.T, contract T, target string) {
 		t.Logf("Marshalled JSON: %v", string(jbytes))
 	}
 }
+
+// JsonConforms asserts that the JSON marshalling of contract matches the
+// reference JSON once both have been normalized, so that key order and
+// whitespace do not affect the comparison.
+func JsonConforms[T any](t *testing.T, contract T, reference []byte) {
+	jbytes, err := json.Marshal(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Normalize(jbytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := Normalize(reference)
+	if err != nil {
+		t.Fatalf("Invalid reference JSON: %v", err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		t.Errorf("%s [Expected]", expected)
+		t.Errorf("%s [Got]", got)
+	}
+}
+
+// Normalize re-emits JSON through a canonical marshaller: object keys are
+// sorted, insignificant whitespace is dropped and numbers are kept verbatim,
+// so that arbitrary-precision integers survive the round trip.
+func Normalize(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// AssertFormatsAgree asserts that the contract survives a round trip through
+// both JSON and CBOR, and that the original and the two decoded contracts are
+// all Equal, so that neither codec can diverge from the other unnoticed.
+func AssertFormatsAgree(t *testing.T, c core.Contract) {
+	t.Helper()
+
+	jbytes, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := core.UnmarshalContract(jbytes)
+	if err != nil {
+		t.Fatalf("Decoding the JSON: %v", err)
+	}
+
+	cbor, err := core.MarshalCBOR(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromCBOR, err := core.UnmarshalCBOR(cbor)
+	if err != nil {
+		t.Fatalf("Decoding the CBOR: %v", err)
+	}
+
+	if !core.Equal(c, fromJSON) {
+		t.Errorf("The JSON round trip changed the contract: %s", jbytes)
+	}
+	if !core.Equal(c, fromCBOR) {
+		t.Errorf("The CBOR round trip changed the contract: %x", cbor)
+	}
+	if !core.Equal(fromJSON, fromCBOR) {
+		t.Error("The contracts decoded from JSON and from CBOR differ")
+	}
+}
@@ -2,4 +2,9 @@ module github.com/menabrealabs/marlowe
 
 go 1.19
 
-require github.com/btcsuite/btcutil v1.0.2
+require (
+	github.com/btcsuite/btcutil v1.0.2
+	golang.org/x/crypto v0.10.0
+)
+
+require golang.org/x/sys v0.9.0 // indirect
@@ -17,7 +17,11 @@
 // See: https://github.com/input-output-hk/marlowe-cardano/blob/main/marlowe/src/Language/Marlowe/Core/V1/Semantics/Types.hs
 package language
 
-import "math/big"
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
 
 // "2.1.6 Actions and inputs
 //
@@ -65,8 +69,35 @@ type ChoiceId struct {
 // type is a tuple of integers that represents an inclusive lower and upper
 // bound." (§2.1.4)
 type Bound struct {
-	Upper uint64 `json:"from"`
-	Lower uint64 `json:"to"`
+	Lower uint64 `json:"from"`
+	Upper uint64 `json:"to"`
+}
+
+// Validate that the bound is not inverted. An inverted bound accepts no
+// number, so a Choice guarded only by inverted bounds can never be taken.
+func (b Bound) Validate() error {
+	if b.Lower > b.Upper {
+		return fmt.Errorf("bound is inverted: lower %v is greater than upper %v", b.Lower, b.Upper)
+	}
+	return nil
+}
+
+// AcceptedNumbers returns the membership predicate of the bounds: a number is
+// accepted if it lies within any of the bounds, inclusive of both ends. For
+// example, [Bound 0 0, Bound 3 5] accepts exactly 0, 3, 4 and 5 (§2.1.6).
+func AcceptedNumbers(bounds []Bound) func(*big.Int) bool {
+	return func(n *big.Int) bool {
+		if n.Sign() < 0 || !n.IsUint64() {
+			return false
+		}
+		num := n.Uint64()
+		for _, b := range bounds {
+			if b.Lower <= num && num <= b.Upper {
+				return true
+			}
+		}
+		return false
+	}
 }
 
 // "A notification can be triggered by anyone as long as the Observation evaluates
@@ -85,7 +116,68 @@ func (a Notify) isAction() {}
 // datatype Input = IDeposit AccountId Party Token int
 // | IChoice ChoiceId ChosenNum
 // | INotify"
-type ChosenNum int
+//
+// As with Constant, ChosenNum is arbitrary precision, since choices are
+// unbounded integers, and holds its integer by pointer: the integer is shared
+// by copies of the ChosenNum and must not be modified. The zero ChosenNum is 0.
+type ChosenNum struct {
+	n *big.Int
+}
+
+// NewChosenNum returns the ChosenNum holding n, which the ChosenNum takes
+// over: n must not be modified afterwards.
+func NewChosenNum(n *big.Int) ChosenNum {
+	return ChosenNum{n: n}
+}
+
+// Int returns the integer held by the ChosenNum, which must not be modified.
+func (n ChosenNum) Int() *big.Int {
+	if n.n == nil {
+		return new(big.Int)
+	}
+	return n.n
+}
+
+// Marshal the ChosenNum as a JSON number of any size.
+func (n ChosenNum) MarshalJSON() ([]byte, error) {
+	return NewConstant(n.Int()).MarshalJSON()
+}
+
+// Unmarshal the ChosenNum from a JSON integer of any size.
+func (n *ChosenNum) UnmarshalJSON(data []byte) error {
+	var c Constant
+	if err := c.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*n = NewChosenNum(c.Int())
+	return nil
+}
+
+func (n ChosenNum) String() string {
+	return n.Int().String()
+}
+
+// SetChosenNum returns the ChosenNum for the decimal integer s, which it
+// assumes to be valid: any other string yields zero. Use SetChosenNumChecked
+// for input that is not known to be valid.
+func SetChosenNum(s string) ChosenNum {
+	num, err := SetChosenNumChecked(s)
+	if err != nil {
+		return ChosenNum{}
+	}
+	return num
+}
+
+// SetChosenNumChecked returns the ChosenNum for the decimal integer s, with an
+// optional sign, or an error if s is not one.
+func SetChosenNumChecked(s string) (ChosenNum, error) {
+	num, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return ChosenNum{}, fmt.Errorf("chosen number %q is not a decimal integer", s)
+	}
+	return NewChosenNum(num), nil
+}
+
 type Input interface{ isInput() }
 
 // "Deposit uses a Value while IDeposit has the int it was evaluated to
@@ -94,11 +186,33 @@ type IDeposit struct {
 	AccountId AccountId
 	Party     Party
 	Token     Token
-	Value     big.Int
+	Value     *big.Int
+}
+
+// NewIDeposit returns a deposit of value of the token by the party into the
+// account.
+func NewIDeposit(account AccountId, party Party, token Token, value *big.Int) IDeposit {
+	return IDeposit{AccountId: account, Party: party, Token: token, Value: value}
 }
 
 func (i IDeposit) isInput() {}
 
+// Marshal the IDeposit as the input JSON of marlowe-cardano:
+//
+//	{"input_from_party": party, "that_deposits": n, "of_token": token, "into_account": account}
+func (i IDeposit) MarshalJSON() ([]byte, error) {
+	if i.Value == nil {
+		return nil, fmt.Errorf("deposit into %v has no value", i.AccountId)
+	}
+
+	return json.Marshal(struct {
+		Party     Party       `json:"input_from_party"`
+		Value     json.Number `json:"that_deposits"`
+		Token     Token       `json:"of_token"`
+		AccountId AccountId   `json:"into_account"`
+	}{i.Party, json.Number(i.Value.String()), i.Token, i.AccountId})
+}
+
 // "Choice defines a list of valid Bounds while IChoice has the actual ChosenNum." (§2.1.6)
 type IChoice struct {
 	ChoiceId  ChoiceId
@@ -107,8 +221,62 @@ type IChoice struct {
 
 func (i IChoice) isInput() {}
 
+// Marshal the IChoice as {"for_choice_id": choiceId, "input_that_chooses_num": n}.
+func (i IChoice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ChoiceId  ChoiceId  `json:"for_choice_id"`
+		ChosenNum ChosenNum `json:"input_that_chooses_num"`
+	}{i.ChoiceId, i.ChosenNum})
+}
+
 // "Notify has an Observation while INotify does not have arguments, the
 // Observation must evaluate to true inside the Transaction." (§2.1.6)
 type INotify struct{}
 
 func (i INotify) isInput() {}
+
+// Marshal the INotify as the string "input_notify".
+func (i INotify) MarshalJSON() ([]byte, error) {
+	return []byte(`"input_notify"`), nil
+}
+
+// A MerkleizedInput applies its Input to a merkleized case, supplying the
+// continuation that the case holds only the Hash of.
+//
+//	data Input = NormalInput InputContent
+//		| MerkleizedInput InputContent BuiltinByteString Contract
+type MerkleizedInput struct {
+	Input        Input
+	Hash         Hash
+	Continuation Contract
+}
+
+func (i MerkleizedInput) isInput() {}
+
+// Marshal the MerkleizedInput as marlowe-cardano does: the object of its
+// input, or no keys for an INotify, with the keys "continuation_hash" and
+// "merkleized_continuation" added.
+func (i MerkleizedInput) MarshalJSON() ([]byte, error) {
+	content, err := json.Marshal(i.Input)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := json.Marshal(struct {
+		Hash         Hash     `json:"continuation_hash"`
+		Continuation Contract `json:"merkleized_continuation"`
+	}{i.Hash, i.Continuation})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, notify := i.Input.(INotify); notify {
+		return extra, nil
+	}
+	if len(content) < 2 || content[0] != '{' {
+		return nil, fmt.Errorf("merkleized input %s is not a JSON object", content)
+	}
+	if string(content) == "{}" {
+		return extra, nil
+	}
+	return append(append(content[:len(content)-1], ','), extra[1:]...), nil
+}
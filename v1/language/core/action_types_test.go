@@ -18,6 +18,7 @@
 package language_test
 
 import (
+	"math/big"
 	"testing"
 
 	assert "github.com/menabrealabs/marlowe/assertion"
@@ -62,3 +63,67 @@ func TestTypes_Notify(t *testing.T) {
 	)
 	assert.Json(t, contract, `{"when":[{"case":{"notify_if":{"value":{"use_value":"val"},"gt":10}},"then":"close"}],"timeout":1666078977926,"timeout_continuation":"close"}`)
 }
+
+func TestSetChosenNumChecked(t *testing.T) {
+	for _, s := range []string{"0", "-5", "+7", "123456789012345678901234567890"} {
+		num, err := m.SetChosenNumChecked(s)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", s, err)
+			continue
+		}
+		if expected, _ := new(big.Int).SetString(s, 10); num.String() != expected.String() {
+			t.Errorf("Expected %v, got: %v", expected, num)
+		}
+	}
+
+	for _, s := range []string{"", "12x", "0x10", "1.5"} {
+		if num, err := m.SetChosenNumChecked(s); err == nil {
+			t.Errorf("Expected an error for %q, got: %v", s, num)
+		}
+		if num := m.SetChosenNum(s); num.String() != "0" {
+			t.Errorf("Expected zero for %q, got: %v", s, num)
+		}
+	}
+}
+
+func TestChosenNum_Int(t *testing.T) {
+	if n := (m.ChosenNum{}).Int(); n.Sign() != 0 {
+		t.Errorf("Expected the zero ChosenNum to be 0, got: %v", n)
+	}
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	num := m.NewChosenNum(huge)
+	if num.Int() != huge {
+		t.Error("Expected the ChosenNum to hold the integer it was built from")
+	}
+	assert.Json(t, m.IChoice{ChoiceId: m.ChoiceId{Name: "c", Owner: m.Role{Name: "r"}}, ChosenNum: num},
+		`{"for_choice_id":{"choice_name":"c","choice_owner":{"role_token":"r"}},"input_that_chooses_num":123456789012345678901234567890}`)
+}
+
+func TestTypes_Bound(t *testing.T) {
+	assert.Json(t, m.Bound{Lower: 2, Upper: 3}, `{"from":2,"to":3}`)
+
+	if err := (m.Bound{Lower: 3, Upper: 3}).Validate(); err != nil {
+		t.Errorf("Expected a single-number bound to be valid, got: %v", err)
+	}
+	if err := (m.Bound{Lower: 3, Upper: 2}).Validate(); err == nil {
+		t.Error("Expected an inverted bound to be invalid")
+	}
+}
+
+// The spec example: [Bound 0 0, Bound 3 5] accepts exactly 0, 3, 4 and 5.
+func TestAcceptedNumbers_SpecExample(t *testing.T) {
+	accepted := m.AcceptedNumbers([]m.Bound{{Lower: 0, Upper: 0}, {Lower: 3, Upper: 5}})
+
+	for n := int64(-2); n <= 8; n++ {
+		want := n == 0 || n == 3 || n == 4 || n == 5
+		if got := accepted(big.NewInt(n)); got != want {
+			t.Errorf("AcceptedNumbers(%v) = %v, want %v", n, got, want)
+		}
+	}
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if accepted(huge) {
+		t.Errorf("Expected %v to be rejected", huge)
+	}
+}
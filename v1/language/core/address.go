@@ -0,0 +1,276 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// A Network is the Cardano network an address belongs to, identified by the
+// network id in the low bits of its header byte (CIP-19).
+type Network uint8
+
+const (
+	Testnet Network = 0
+	Mainnet Network = 1
+)
+
+func (n Network) String() string {
+	switch n {
+	case Testnet:
+		return "testnet"
+	case Mainnet:
+		return "mainnet"
+	}
+	return fmt.Sprintf("network %d", uint8(n))
+}
+
+// The human-readable part of the Bech32 encoding of a Shelley address.
+func (n Network) addressPrefix() string {
+	if n == Mainnet {
+		return "addr"
+	}
+	return "addr_test"
+}
+
+// The hash of the Marlowe V1 semantics validator, under which every contract
+// UTxO is locked.
+const marloweValidatorHash = "6a9391d6aa51af28dd876ebb5565b69d1e83e5ac7861506bd29b56b0"
+
+// The CIP-19 header type of an enterprise address with a script payment
+// credential and no stake credential.
+const enterpriseScriptHeader byte = 0b0111 << 4
+
+// ScriptAddress returns the Bech32 address of the Marlowe validator on the
+// network, at which contract UTxOs are found. The Marlowe V1 validator is not
+// parameterized by MarloweParams, since the roles currency lives in the datum,
+// so every contract on a network shares the address; the roles currency is
+// only checked to be a valid (or empty, for contracts without roles) policy id.
+func ScriptAddress(params MarloweParams, net Network) (Address, error) {
+	if policy, err := hex.DecodeString(params.RolesCurrency); err != nil || (len(policy) != 0 && len(policy) != 28) {
+		return "", fmt.Errorf("roles currency %q is not a policy id", params.RolesCurrency)
+	}
+	if net != Testnet && net != Mainnet {
+		return "", fmt.Errorf("unsupported %v", net)
+	}
+
+	hash, err := hex.DecodeString(marloweValidatorHash)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := bech32.ConvertBits(append([]byte{enterpriseScriptHeader | byte(net)}, hash...), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := bech32.Encode(net.addressPrefix(), data)
+	if err != nil {
+		return "", err
+	}
+
+	return Address(encoded), nil
+}
+
+// The Bech32 alphabet, in the order of the 5-bit values it encodes.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Decode the Bech32 encoding of a Shelley address into its human-readable
+// prefix and its bytes, starting with the header. A base address is longer
+// than the 90 characters bech32.Decode accepts, so the checksum is verified by
+// encoding the data again and comparing the result.
+func (a Address) decode() (string, []byte, error) {
+	encoded := strings.ToLower(string(a))
+	if encoded != string(a) && strings.ToUpper(string(a)) != string(a) {
+		return "", nil, fmt.Errorf("address %q mixes upper and lower case", string(a))
+	}
+
+	sep := strings.LastIndexByte(encoded, '1')
+	if sep < 1 || sep+7 > len(encoded) {
+		return "", nil, fmt.Errorf("address %q is not Bech32 encoded", string(a))
+	}
+	hrp, chars := encoded[:sep], encoded[sep+1:]
+
+	data := make([]byte, len(chars))
+	for i := 0; i < len(chars); i++ {
+		index := strings.IndexByte(bech32Charset, chars[i])
+		if index < 0 {
+			return "", nil, fmt.Errorf("address %q has an invalid character %q", string(a), chars[i])
+		}
+		data[i] = byte(index)
+	}
+	data = data[:len(data)-6]
+
+	if checked, err := bech32.Encode(hrp, data); err != nil || checked != encoded {
+		return "", nil, fmt.Errorf("address %q has an invalid checksum", string(a))
+	}
+
+	bytes, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(bytes) == 0 {
+		return "", nil, fmt.Errorf("address %q has no header", string(a))
+	}
+
+	return hrp, bytes, nil
+}
+
+// Network decodes the network of the address from the low bits of its header
+// byte (CIP-19). Byron addresses, which are not Bech32 encoded, are rejected,
+// as are addresses whose prefix disagrees with their header.
+func (a Address) Network() (Network, error) {
+	hrp, bytes, err := a.decode()
+	if err != nil {
+		return 0, err
+	}
+
+	net := Network(bytes[0] & 0x0f)
+	if net != Testnet && net != Mainnet {
+		return 0, fmt.Errorf("address %q is for an unsupported %v", string(a), net)
+	}
+	if hrp != net.addressPrefix() {
+		return 0, fmt.Errorf("address %q has prefix %q but a %v header", string(a), hrp, net)
+	}
+
+	return net, nil
+}
+
+// ValidateNetwork returns an error unless the address belongs to the expected
+// network.
+func (a Address) ValidateNetwork(expected Network) error {
+	net, err := a.Network()
+	if err != nil {
+		return err
+	}
+	if net != expected {
+		return fmt.Errorf("address %q is a %v address, expected %v", string(a), net, expected)
+	}
+	return nil
+}
+
+// A CredentialKind says whether a Credential is the hash of a verification key
+// or of a script.
+type CredentialKind uint8
+
+const (
+	KeyHash CredentialKind = iota
+	ScriptHash
+)
+
+func (k CredentialKind) String() string {
+	if k == ScriptHash {
+		return "script hash"
+	}
+	return "key hash"
+}
+
+// A Credential is a payment or stake credential held in a Shelley address:
+// the hex encoding of a 28-byte hash, together with what was hashed.
+type Credential struct {
+	Kind CredentialKind
+	Hash string
+}
+
+func (c Credential) String() string {
+	return fmt.Sprintf("%v %v", c.Kind, c.Hash)
+}
+
+// The length in bytes of the hashes held by credentials.
+const credentialSize = 28
+
+// The CIP-19 header types of the address kinds that hold credentials.
+const (
+	baseAddress       = 0b0000 // through 0b0011
+	pointerAddress    = 0b0100 // and 0b0101
+	enterpriseAddress = 0b0110 // and 0b0111
+)
+
+// Decode the address and return the kind of address from its header type,
+// ignoring the bits that give the kinds of its credentials.
+func (a Address) shelley() (byte, []byte, error) {
+	_, bytes, err := a.decode()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	header := bytes[0] >> 4
+	var kind byte
+	switch {
+	case header <= 0b0011:
+		kind = baseAddress
+	case header <= 0b0101:
+		kind = pointerAddress
+	case header <= 0b0111:
+		kind = enterpriseAddress
+	default:
+		return 0, nil, fmt.Errorf("address %q has unsupported header type %04b", string(a), header)
+	}
+
+	size := 1 + credentialSize
+	if kind == baseAddress {
+		size += credentialSize
+	}
+	if len(bytes) < size || (kind != pointerAddress && len(bytes) != size) {
+		return 0, nil, fmt.Errorf("address %q has %d bytes, which is the wrong length for its header", string(a), len(bytes))
+	}
+
+	return kind, bytes, nil
+}
+
+// PaymentCredential returns the payment credential of a base, pointer or
+// enterprise address, which is a script hash when bit 4 of the header is set.
+func (a Address) PaymentCredential() (Credential, error) {
+	_, bytes, err := a.shelley()
+	if err != nil {
+		return Credential{}, err
+	}
+
+	kind := KeyHash
+	if bytes[0]&0b0001_0000 != 0 {
+		kind = ScriptHash
+	}
+
+	return Credential{Kind: kind, Hash: hex.EncodeToString(bytes[1 : 1+credentialSize])}, nil
+}
+
+// StakeCredential returns the stake credential of a base address, which is a
+// script hash when bit 5 of the header is set. Pointer addresses refer to a
+// stake registration certificate instead, and enterprise addresses have no
+// stake credential, so both are errors.
+func (a Address) StakeCredential() (Credential, error) {
+	kind, bytes, err := a.shelley()
+	if err != nil {
+		return Credential{}, err
+	}
+
+	switch kind {
+	case pointerAddress:
+		return Credential{}, fmt.Errorf("address %q is a pointer address, which has a stake pointer rather than a stake credential", string(a))
+	case enterpriseAddress:
+		return Credential{}, fmt.Errorf("address %q is an enterprise address, which has no stake credential", string(a))
+	}
+
+	credential := KeyHash
+	if bytes[0]&0b0010_0000 != 0 {
+		credential = ScriptHash
+	}
+
+	return Credential{Kind: credential, Hash: hex.EncodeToString(bytes[1+credentialSize:])}, nil
+}
@@ -0,0 +1,122 @@
+package language_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcutil/bech32"
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestScriptAddress(t *testing.T) {
+	params := m.MarloweParams{RolesCurrency: "85bb65085bb65085bb65085bb65085bb65085bb65085bb65085bb650"}
+
+	// The published addresses of the Marlowe V1 validator, as enterprise
+	// script addresses for its hash.
+	for net, expected := range map[m.Network]m.Address{
+		m.Mainnet: "addr1w94f8ywk4fg672xasahtk4t9k6w3aql943uxz5rt62d4dvq8evxaf",
+		m.Testnet: "addr_test1wp4f8ywk4fg672xasahtk4t9k6w3aql943uxz5rt62d4dvqu3c6jv",
+	} {
+		address, err := m.ScriptAddress(params, net)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != expected {
+			t.Errorf("Expected the %v address %v, got: %v", net, expected, address)
+		}
+	}
+
+	// A contract without roles has the same address.
+	if address, err := m.ScriptAddress(m.MarloweParams{}, m.Mainnet); err != nil || address != "addr1w94f8ywk4fg672xasahtk4t9k6w3aql943uxz5rt62d4dvq8evxaf" {
+		t.Errorf("Expected the mainnet address without roles, got: %v %v", address, err)
+	}
+
+	if _, err := m.ScriptAddress(m.MarloweParams{RolesCurrency: "not hex"}, m.Testnet); err == nil {
+		t.Error("Expected an invalid roles currency to be rejected")
+	}
+}
+
+func TestAddress_Network(t *testing.T) {
+	addresses := map[m.Address]m.Network{
+		// CIP-19 test vectors: a base, pointer and enterprise address on mainnet,
+		// where the header byte ends in 0001, and a base address on testnet,
+		// where it ends in 0000.
+		"addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x":      m.Mainnet,
+		"addr1gx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrzqf96k":                                          m.Mainnet,
+		"addr1vx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzers66hrl8":                                                   m.Mainnet,
+		"addr_test1qz2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgs68faae": m.Testnet,
+		"addr_test1vz3ppzmmzuz0nlsjeyrqjm4pvdxl3cyfe8x06eg6htj2gwgv02qjt":                                              m.Testnet,
+	}
+
+	for address, want := range addresses {
+		got, err := address.Network()
+		if err != nil || got != want {
+			t.Errorf("%v: expected %v, got: %v %v", address, want, got, err)
+		}
+		if err := address.ValidateNetwork(want); err != nil {
+			t.Errorf("%v: %v", address, err)
+		}
+		if err := address.ValidateNetwork(1 - want); err == nil {
+			t.Errorf("%v: expected the other network to be rejected", address)
+		}
+	}
+}
+
+func TestAddress_Network_Invalid(t *testing.T) {
+	// A testnet header under the mainnet prefix.
+	data, _ := bech32.ConvertBits([]byte{0x60, 0x01, 0x02, 0x03}, 8, 5, true)
+	mismatched, _ := bech32.Encode("addr", data)
+
+	// An unsupported network id in the header.
+	data, _ = bech32.ConvertBits([]byte{0x62, 0x01, 0x02, 0x03}, 8, 5, true)
+	unsupported, _ := bech32.Encode("addr_test", data)
+
+	for _, address := range []m.Address{
+		m.Address(mismatched),
+		m.Address(unsupported),
+		"addr1vx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzers66hrl9", // bad checksum
+		"addr1_buyer",
+	} {
+		if net, err := address.Network(); err == nil {
+			t.Errorf("%v: expected an error, got: %v", address, net)
+		}
+	}
+}
+
+func TestAddress_Credentials(t *testing.T) {
+	paymentKey := m.Credential{Kind: m.KeyHash, Hash: "9493315cd92eb5d8c4304e67b7e16ae36d61d34502694657811a2c8e"}
+	paymentScript := m.Credential{Kind: m.ScriptHash, Hash: "c37b1b5dc0669f1d3c61a6fddb2e8fde96be87b881c60bce8e8d542f"}
+	stakeKey := m.Credential{Kind: m.KeyHash, Hash: "337b62cfff6403a06a3acbc34f8c46003c69fe79a3628cefa9c47251"}
+
+	tests := []struct {
+		address m.Address
+		payment m.Credential
+		stake   *m.Credential
+	}{
+		{"addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x", paymentKey, &stakeKey},
+		{"addr1z8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gten0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgs9yc0hh", paymentScript, &stakeKey},
+		{"addr1gx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrzqf96k", paymentKey, nil},
+		{"addr1vx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzers66hrl8", paymentKey, nil},
+		{"addr1w8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcyjy7wx", paymentScript, nil},
+	}
+
+	for _, test := range tests {
+		if payment, err := test.address.PaymentCredential(); err != nil || payment != test.payment {
+			t.Errorf("%v: expected payment credential %v, got: %v %v", test.address, test.payment, payment, err)
+		}
+		stake, err := test.address.StakeCredential()
+		if test.stake == nil {
+			if err == nil {
+				t.Errorf("%v: expected no stake credential, got: %v", test.address, stake)
+			}
+		} else if err != nil || stake != *test.stake {
+			t.Errorf("%v: expected stake credential %v, got: %v %v", test.address, *test.stake, stake, err)
+		}
+	}
+
+	// A reward address holds only a stake credential, and is not supported.
+	data, _ := bech32.ConvertBits(append([]byte{0xe1}, make([]byte, 28)...), 8, 5, true)
+	reward, _ := bech32.Encode("stake", data)
+	if credential, err := m.Address(reward).PaymentCredential(); err == nil {
+		t.Errorf("Expected a reward address to be rejected, got: %v", credential)
+	}
+}
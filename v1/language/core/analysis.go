@@ -0,0 +1,248 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// A Counterexample is a sequence of transactions that, computed in order from
+// the initial state, makes the contract issue the warning in its last
+// transaction.
+type Counterexample struct {
+	Transactions []TransactionInput
+	Warning      Warning
+}
+
+// The most transactions AnalyzeWarnings computes before giving up.
+const maxAnalysisTransactions = 100_000
+
+// AnalyzeWarnings searches the executions of the contract from the initial
+// state for transactions that issue a NonPositivePay, PartialPay or
+// AssertionFailed warning. Rather than solving for every possible input, as
+// the static analysis of marlowe-cardano does, it explores a bounded set of
+// executions explicitly: at each When it tries every case, both as early as
+// the state allows and just before the timeout, and lets the When time out.
+// A deposit pays the amount its action asks for, a choice picks either end
+// of each of its bounds, and a notification is tried whenever its observation
+// holds. Every execution ends at the contract's timeouts, so the search is
+// finite, but a contract with very many executions makes it return an error.
+//
+// Each distinct warning is reported once, with the fewest transactions found
+// to trigger it, in the order the warnings are found.
+func AnalyzeWarnings(c Contract, initial State) ([]Counterexample, error) {
+	type node struct {
+		state    State
+		contract Contract
+		txs      []TransactionInput
+	}
+
+	var found []Counterexample
+	seen := make(map[string]bool)
+
+	queue := []node{{state: initial, contract: c}}
+	computed := 0
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		candidates, err := analysisTransactions(n.state, n.contract)
+		if err != nil {
+			return found, err
+		}
+
+		for _, tx := range candidates {
+			if computed++; computed > maxAnalysisTransactions {
+				return found, fmt.Errorf("analysis stopped after %d transactions", maxAnalysisTransactions)
+			}
+
+			out := ComputeTransaction(tx, n.state, n.contract)
+			if out.Error != nil {
+				continue
+			}
+
+			txs := append(append([]TransactionInput{}, n.txs...), tx)
+			for _, w := range out.Warnings {
+				switch w.(type) {
+				case NonPositivePay, PartialPay, AssertionFailed:
+				default:
+					continue
+				}
+				key, err := json.Marshal(w)
+				if err != nil {
+					return found, err
+				}
+				if !seen[string(key)] {
+					seen[string(key)] = true
+					found = append(found, Counterexample{Transactions: txs, Warning: w})
+				}
+			}
+
+			if out.Contract != Close {
+				queue = append(queue, node{state: out.State, contract: out.Contract, txs: txs})
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// The transactions to try next: those taking each case of a When, early and
+// late, and the one timing it out, or else one reducing the contract.
+func analysisTransactions(state State, c Contract) ([]TransactionInput, error) {
+	now := state.MinTime
+
+	when, ok := c.(When)
+	if !ok {
+		return []TransactionInput{{Interval: NewTimeInterval(now, now)}}, nil
+	}
+
+	timeout, ok := when.Timeout.(POSIXTime)
+	if !ok {
+		return nil, fmt.Errorf("cannot analyze a When with timeout %v", when.Timeout)
+	}
+
+	var txs []TransactionInput
+	times := []POSIXTime{now}
+	if timeout-1 > now {
+		times = append(times, timeout-1)
+	}
+	if now < timeout {
+		for _, t := range times {
+			env := Environment{TimeInterval: NewTimeInterval(t, t)}
+			for _, cs := range when.Cases {
+				for _, input := range analysisInputs(env, state, cs.Action) {
+					txs = append(txs, TransactionInput{Interval: env.TimeInterval, Inputs: []Input{input}})
+				}
+			}
+		}
+	}
+
+	if timeout < now {
+		timeout = now
+	}
+	return append(txs, TransactionInput{Interval: NewTimeInterval(timeout, timeout)}), nil
+}
+
+// The inputs to try for the action.
+func analysisInputs(env Environment, state State, action Action) []Input {
+	switch a := action.(type) {
+	case Deposit:
+		return []Input{NewIDeposit(a.IntoAccount, a.Party, a.Token, EvalValue(env, state, a.Deposits))}
+	case Choice:
+		var inputs []Input
+		chosen := make(map[uint64]bool)
+		for _, b := range a.Bounds {
+			for _, n := range []uint64{b.Lower, b.Upper} {
+				if !chosen[n] {
+					chosen[n] = true
+					inputs = append(inputs, IChoice{ChoiceId: a.ChoiceId, ChosenNum: NewChosenNum(new(big.Int).SetUint64(n))})
+				}
+			}
+		}
+		return inputs
+	case Notify:
+		if EvalObservation(env, state, a.If) {
+			return []Input{INotify{}}
+		}
+	}
+
+	return nil
+}
+
+// Bisimilar reports whether the two contracts behave identically from the
+// state: every transaction computed on both issues the same payments and
+// warnings, or fails with the same error, and leaves both in the same state,
+// closed or not. The transactions tried are those AnalyzeWarnings tries on
+// either contract, starting no earlier than the interval of the environment,
+// and the search follows at most maxSteps transactions in a row, so two
+// contracts that only diverge later are reported bisimilar. Contracts whose
+// timeouts are not POSIXTimes cannot be explored and are never bisimilar.
+func Bisimilar(a, b Contract, env Environment, state State, maxSteps int) bool {
+	if start := env.TimeInterval.start; start > state.MinTime {
+		state.MinTime = start
+	}
+
+	var bisimilar func(a, b Contract, sa, sb State, steps int) bool
+	bisimilar = func(a, b Contract, sa, sb State, steps int) bool {
+		if steps == 0 {
+			return true
+		}
+
+		candidates, err := analysisTransactions(sa, a)
+		if err != nil {
+			return false
+		}
+		more, err := analysisTransactions(sb, b)
+		if err != nil {
+			return false
+		}
+
+		tried := make(map[string]bool)
+		for _, tx := range append(candidates, more...) {
+			key, err := json.Marshal(tx)
+			if err != nil {
+				return false
+			}
+			if tried[string(key)] {
+				continue
+			}
+			tried[string(key)] = true
+
+			outA, outB := ComputeTransaction(tx, sa, a), ComputeTransaction(tx, sb, b)
+			if !sameOutput(outA, outB) {
+				return false
+			}
+			if outA.Error != nil || outA.Contract == Close {
+				continue
+			}
+			if !bisimilar(outA.Contract, outB.Contract, outA.State, outB.State, steps-1) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return bisimilar(a, b, state, state, maxSteps)
+}
+
+// Whether two transaction outputs are indistinguishable but for the
+// continuation contracts.
+func sameOutput(x, y TransactionOutput) bool {
+	if x.Error != nil || y.Error != nil {
+		return x.Error != nil && y.Error != nil && transactionErrorName(x.Error) == transactionErrorName(y.Error)
+	}
+	if (x.Contract == Close) != (y.Contract == Close) || !EqualStates(x.State, y.State) {
+		return false
+	}
+
+	// Payments and warnings compare by their JSON, so that amounts compare
+	// by value.
+	for _, pair := range [][2]any{{x.Payments, y.Payments}, {x.Warnings, y.Warnings}} {
+		p, err := json.Marshal(pair[0])
+		if err != nil {
+			return false
+		}
+		q, err := json.Marshal(pair[1])
+		if err != nil || string(p) != string(q) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,129 @@
+package language_test
+
+import (
+	"reflect"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestAnalyzeWarnings(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	amount := m.ChoiceId{Name: "amount", Owner: buyer}
+
+	// The buyer deposits 10 and then chooses to pay between 0 and 20 of it,
+	// which must happen before time 1500.
+	contract := m.When{
+		Cases: []m.Case{{
+			Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")},
+			Then: m.When{
+				Cases: []m.Case{{
+					Action: m.Choice{ChoiceId: amount, Bounds: []m.Bound{{Lower: 0, Upper: 20}}},
+					Then: m.Assert{
+						Observe: m.ValueLT{Value: m.TimeIntervalStart, Lt: m.SetConstant("1500")},
+						Then:    m.Pay{From: buyer, To: m.Payee{Party: seller}, Token: m.Ada, Pay: m.ChoiceValue{Value: amount}, Then: m.Close},
+					},
+				}},
+				Timeout: m.POSIXTime(2000),
+				Then:    m.Close,
+			},
+		}},
+		Timeout: m.POSIXTime(1000),
+		Then:    m.Close,
+	}
+
+	found, err := m.AnalyzeWarnings(contract, m.State{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 {
+		t.Fatalf("Expected 3 counterexamples, got: %v", found)
+	}
+
+	if _, ok := found[0].Warning.(m.NonPositivePay); !ok {
+		t.Errorf("Expected a NonPositivePay first, got: %v", found[0].Warning)
+	}
+	if w, ok := found[1].Warning.(m.PartialPay); !ok || w.Paid.Int64() != 10 || w.Expected.Int64() != 20 {
+		t.Errorf("Expected a PartialPay of 10 out of 20, got: %v", found[1].Warning)
+	}
+	if _, ok := found[2].Warning.(m.AssertionFailed); !ok {
+		t.Errorf("Expected an AssertionFailed, got: %v", found[2].Warning)
+	}
+
+	// Replaying each counterexample issues its warning in the last transaction.
+	for _, ce := range found {
+		txs := ce.Transactions
+		state, c, _, _, err := m.PlayTrace(m.State{}, contract, txs[:len(txs)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := m.ComputeTransaction(txs[len(txs)-1], state, c)
+		issued := false
+		for _, w := range out.Warnings {
+			issued = issued || reflect.DeepEqual(w, ce.Warning)
+		}
+		if out.Error != nil || !issued {
+			t.Errorf("Expected %v to issue %v, got: %+v", txs, ce.Warning, out)
+		}
+	}
+}
+
+func TestAnalyzeWarnings_Safe(t *testing.T) {
+	found, err := m.AnalyzeWarnings(setupEscrowContract(), m.State{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Errorf("Expected the escrow to issue no warnings, got: %v", found)
+	}
+}
+
+func TestBisimilar(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	price := m.AddValue{Add: m.MulValue{Multiply: m.SetConstant("2"), By: m.SetConstant("25")}, To: m.SetConstant("0")}
+
+	contract := func(price, half m.Value) m.Contract {
+		return m.When{
+			Cases: []m.Case{{
+				Action: m.Deposit{IntoAccount: seller, Party: buyer, Token: m.Ada, Deposits: price},
+				Then: m.When{
+					Cases: []m.Case{{
+						Action: m.Choice{ChoiceId: m.ChoiceId{Name: "refund", Owner: seller}, Bounds: []m.Bound{{Lower: 0, Upper: 1}}},
+						Then: m.If{
+							Observe: m.ValueEQ{Value: m.ChoiceValue{Value: m.ChoiceId{Name: "refund", Owner: seller}}, Eq: m.SetConstant("1")},
+							Then:    m.Pay{From: seller, To: m.Payee{Party: buyer}, Token: m.Ada, Pay: half, Then: m.Close},
+							Else:    m.Close,
+						},
+					}},
+					Timeout: m.POSIXTime(2000),
+					Then:    m.Close,
+				},
+			}},
+			Timeout: m.POSIXTime(1000),
+			Then:    m.Close,
+		}
+	}
+	half := m.DivValue{Divide: price, By: m.SetConstant("2")}
+	original := contract(price, half)
+	env := m.Environment{TimeInterval: m.NewTimeInterval(0, 0)}
+
+	simplified := contract(m.SimplifyValue(price), m.SimplifyValue(half))
+	if !m.Bisimilar(original, simplified, env, m.State{}, 5) {
+		t.Error("Expected the contract to be bisimilar to its simplified form")
+	}
+
+	// Refunding 24 rather than 25 only shows once the seller chooses.
+	changed := contract(price, m.SetConstant("24"))
+	if !m.Bisimilar(original, changed, env, m.State{}, 1) {
+		t.Error("Expected the contracts to agree on their first transaction")
+	}
+	if m.Bisimilar(original, changed, env, m.State{}, 5) {
+		t.Error("Expected the contracts to differ in their refund")
+	}
+
+	// Dropping an unused Let changes the bound values of the state.
+	let := m.Let{Name: "unused", Value: m.SetConstant("1"), Then: original}
+	if m.Bisimilar(let, m.CoalesceLets(let), env, m.State{}, 5) {
+		t.Error("Expected the dropped binding to show in the state")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// BoundProblem classifies the problems CheckBounds reports.
+type BoundProblem int
+
+const (
+	// InvertedBound marks a bound whose lower end exceeds its upper end, so
+	// that it accepts no number.
+	InvertedBound BoundProblem = iota
+	// OverlappingBounds marks two bounds that accept some of the same numbers.
+	OverlappingBounds
+	// DuplicateBound marks a bound that repeats an earlier one exactly.
+	DuplicateBound
+)
+
+func (p BoundProblem) String() string {
+	switch p {
+	case InvertedBound:
+		return "inverted"
+	case OverlappingBounds:
+		return "overlapping"
+	case DuplicateBound:
+		return "duplicate"
+	}
+	return fmt.Sprintf("BoundProblem(%d)", int(p))
+}
+
+// A BoundWarning reports a problem with the bounds of a Choice: Bound is the
+// offending bound and, for overlaps and duplicates, Other is the earlier bound
+// it overlaps or repeats.
+type BoundWarning struct {
+	ChoiceId ChoiceId
+	Kind     BoundProblem
+	Bound    Bound
+	Other    Bound
+}
+
+func (w BoundWarning) String() string {
+	switch w.Kind {
+	case InvertedBound:
+		return fmt.Sprintf("choice %q has an inverted bound [%v, %v]", w.ChoiceId.Name, w.Bound.Lower, w.Bound.Upper)
+	case OverlappingBounds:
+		return fmt.Sprintf("choice %q has overlapping bounds [%v, %v] and [%v, %v]",
+			w.ChoiceId.Name, w.Other.Lower, w.Other.Upper, w.Bound.Lower, w.Bound.Upper)
+	case DuplicateBound:
+		return fmt.Sprintf("choice %q repeats the bound [%v, %v]", w.ChoiceId.Name, w.Bound.Lower, w.Bound.Upper)
+	}
+	return fmt.Sprintf("choice %q has a %v bound [%v, %v]", w.ChoiceId.Name, w.Kind, w.Bound.Lower, w.Bound.Upper)
+}
+
+// CheckBounds reports each inverted bound of the choice, each bound that
+// repeats an earlier one exactly, and each pair of other bounds that overlap,
+// in the order of the bounds. Overlapping bounds are legal, since a number is
+// accepted if any bound accepts it, but usually a mistake.
+func (c Choice) CheckBounds() []BoundWarning {
+	var warnings []BoundWarning
+	var checked []Bound
+
+	for _, b := range c.Bounds {
+		if b.Validate() != nil {
+			warnings = append(warnings, BoundWarning{ChoiceId: c.ChoiceId, Kind: InvertedBound, Bound: b})
+			continue
+		}
+
+		duplicate := false
+		for _, other := range checked {
+			if other == b {
+				warnings = append(warnings, BoundWarning{ChoiceId: c.ChoiceId, Kind: DuplicateBound, Bound: b, Other: other})
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		for _, other := range checked {
+			if other.Lower <= b.Upper && b.Lower <= other.Upper {
+				warnings = append(warnings, BoundWarning{ChoiceId: c.ChoiceId, Kind: OverlappingBounds, Bound: b, Other: other})
+			}
+		}
+		checked = append(checked, b)
+	}
+
+	return warnings
+}
+
+// CheckChoiceBounds runs CheckBounds over every Choice in the contract, in the
+// order they appear.
+func CheckChoiceBounds(c Contract) []BoundWarning {
+	var warnings []BoundWarning
+
+	inspect(c, func(node any) {
+		if choice, ok := node.(Choice); ok {
+			warnings = append(warnings, choice.CheckBounds()...)
+		}
+	})
+
+	return warnings
+}
+
+// The most numbers AcceptedValues enumerates.
+const maxAcceptedValues = 1024
+
+// AcceptedValues returns, in ascending order and without repeats, every number
+// the choice accepts: those within any of its bounds, inclusive of both ends.
+// For example, the bounds [Bound 0 0, Bound 3 5] accept 0, 3, 4 and 5
+// (§2.1.6). If the bounds accept more than 1024 numbers it returns false
+// instead; AcceptedNumbers then tells whether a given number is accepted.
+func (c Choice) AcceptedValues() ([]*big.Int, bool) {
+	var ranges []Bound
+	for _, b := range c.Bounds {
+		if b.Validate() == nil {
+			ranges = append(ranges, b)
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Lower < ranges[j].Lower })
+
+	// Merge the overlapping ranges, so that each number is counted once.
+	var merged []Bound
+	for _, b := range ranges {
+		if last := len(merged) - 1; last >= 0 && b.Lower <= merged[last].Upper {
+			if b.Upper > merged[last].Upper {
+				merged[last].Upper = b.Upper
+			}
+			continue
+		}
+		merged = append(merged, b)
+	}
+
+	var count uint64
+	for _, b := range merged {
+		// Compare the span before adding one, which could overflow.
+		if b.Upper-b.Lower >= maxAcceptedValues-count {
+			return nil, false
+		}
+		count += b.Upper - b.Lower + 1
+	}
+
+	values := make([]*big.Int, 0, count)
+	for _, b := range merged {
+		for n := b.Lower; ; n++ {
+			values = append(values, new(big.Int).SetUint64(n))
+			if n == b.Upper {
+				break
+			}
+		}
+	}
+	return values, true
+}
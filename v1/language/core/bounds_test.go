@@ -0,0 +1,83 @@
+package language_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestChoice_CheckBounds(t *testing.T) {
+	id := m.ChoiceId{Name: "option", Owner: m.Role{Name: "buyer"}}
+	choice := m.Choice{ChoiceId: id, Bounds: []m.Bound{
+		{Lower: 0, Upper: 5},
+		{Lower: 3, Upper: 8},
+		{Lower: 9, Upper: 2},
+		{Lower: 0, Upper: 5},
+		{Lower: 10, Upper: 10},
+	}}
+
+	expected := []m.BoundWarning{
+		{ChoiceId: id, Kind: m.OverlappingBounds, Bound: m.Bound{Lower: 3, Upper: 8}, Other: m.Bound{Lower: 0, Upper: 5}},
+		{ChoiceId: id, Kind: m.InvertedBound, Bound: m.Bound{Lower: 9, Upper: 2}},
+		{ChoiceId: id, Kind: m.DuplicateBound, Bound: m.Bound{Lower: 0, Upper: 5}, Other: m.Bound{Lower: 0, Upper: 5}},
+	}
+	if got := choice.CheckBounds(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got: %v", expected, got)
+	}
+
+	// Bounds that only touch end to end do not overlap.
+	adjacent := m.Choice{ChoiceId: id, Bounds: []m.Bound{{Lower: 0, Upper: 2}, {Lower: 3, Upper: 5}}}
+	if got := adjacent.CheckBounds(); len(got) != 0 {
+		t.Errorf("Expected no warnings, got: %v", got)
+	}
+}
+
+func TestCheckChoiceBounds(t *testing.T) {
+	id := m.ChoiceId{Name: "option", Owner: m.Role{Name: "buyer"}}
+	contract := setupWhenContract(m.Choice{ChoiceId: id, Bounds: []m.Bound{{Lower: 1, Upper: 1}, {Lower: 1, Upper: 1}}})
+
+	got := m.CheckChoiceBounds(contract)
+	if len(got) != 1 || got[0].Kind != m.DuplicateBound {
+		t.Fatalf("Expected a duplicate bound, got: %v", got)
+	}
+
+	// Validate reports the redundant bound as a warning, not an error.
+	report := m.Validate(contract, m.ValidateOptions{Bounds: true})
+	if len(report.Findings) != 1 || report.HasErrors() || report.Findings[0].Message != got[0].String() {
+		t.Errorf("Expected a single bounds warning, got: %v", report.Findings)
+	}
+}
+
+func TestChoice_AcceptedValues(t *testing.T) {
+	for _, tc := range []struct {
+		bounds   []m.Bound
+		expected string
+	}{
+		// The example of §2.1.6.
+		{[]m.Bound{{Lower: 0, Upper: 0}, {Lower: 3, Upper: 5}}, "[0 3 4 5]"},
+		// Overlapping, repeated, unordered and inverted bounds.
+		{[]m.Bound{{Lower: 4, Upper: 6}, {Lower: 1, Upper: 2}, {Lower: 2, Upper: 5}, {Lower: 1, Upper: 2}, {Lower: 9, Upper: 8}}, "[1 2 3 4 5 6]"},
+		{[]m.Bound{{Lower: math.MaxUint64, Upper: math.MaxUint64}}, "[18446744073709551615]"},
+		{nil, "[]"},
+	} {
+		values, ok := m.Choice{Bounds: tc.bounds}.AcceptedValues()
+		if got := fmt.Sprint(values); !ok || got != tc.expected {
+			t.Errorf("%v: expected %v, got: %v (%v)", tc.bounds, tc.expected, got, ok)
+		}
+	}
+
+	for _, bounds := range [][]m.Bound{
+		{{Lower: 0, Upper: math.MaxUint64}},
+		{{Lower: 0, Upper: 1023}, {Lower: 2000, Upper: 2000}},
+	} {
+		if values, ok := (m.Choice{Bounds: bounds}).AcceptedValues(); ok {
+			t.Errorf("%v: expected too many values to enumerate, got: %v", bounds, len(values))
+		}
+	}
+	if values, ok := (m.Choice{Bounds: []m.Bound{{Lower: 0, Upper: 1023}}}).AcceptedValues(); !ok || len(values) != 1024 {
+		t.Errorf("Expected 1024 values, got: %v (%v)", len(values), ok)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Builder accumulates a contract step by step. Each construct added may
+// leave its continuations nil, e.g. a When without its timeout continuation;
+// these open continuations are filled, depth-first and in source order, by the
+// constructs added after it. Validate can be called at any point to catch
+// problems before the contract is finished.
+type Builder struct {
+	// Options are the checks run by Validate once the contract is complete.
+	Options ValidateOptions
+
+	contract Contract
+}
+
+// NewBuilder returns an empty Builder that runs every check.
+func NewBuilder() *Builder {
+	return &Builder{Options: DefaultValidateOptions()}
+}
+
+// Add places the construct at the first open continuation of the contract, or
+// at its root if the Builder is empty.
+func (b *Builder) Add(c Contract) error {
+	if c == nil {
+		return errors.New("cannot add a nil contract")
+	}
+	if b.contract == nil {
+		b.contract = c
+		return nil
+	}
+
+	contract, ok := fillContinuation(b.contract, c)
+	if !ok {
+		return errors.New("contract has no open continuation")
+	}
+	b.contract = contract
+	return nil
+}
+
+// Open returns the paths of the open continuations, in the order Add fills
+// them, e.g. "when[0].then" or "timeout_continuation".
+func (b *Builder) Open() []string {
+	if b.contract == nil {
+		return []string{"$"}
+	}
+	return openContinuations(b.contract, "")
+}
+
+// Validate reports each open continuation, and each construct missing a
+// timeout, value, observation or action, as a CheckComplete error. Once the
+// contract is complete, the checks enabled in Options are run over it.
+func (b *Builder) Validate() ValidationReport {
+	var findings []Finding
+	for _, path := range b.Open() {
+		findings = append(findings, Finding{
+			Check:    CheckComplete,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("continuation %v is not set", path),
+		})
+	}
+	if b.contract != nil {
+		findings = append(findings, checkMissingFields(b.contract, "")...)
+	}
+
+	if len(findings) > 0 {
+		return ValidationReport{Findings: findings}
+	}
+
+	return Validate(b.contract, b.Options)
+}
+
+// Contract returns the finished contract, or an error describing the first
+// problem found by Validate.
+func (b *Builder) Contract() (Contract, error) {
+	report := b.Validate()
+	for _, f := range report.Findings {
+		if f.Severity == SeverityError {
+			return nil, errors.New(f.Message)
+		}
+	}
+	return b.contract, nil
+}
+
+// A WhenBuilder assembles a When one case at a time, e.g.
+//
+//	NewWhen().Case(deposit, pay).Case(choice, Close).Timeout(t).Then(Close).Build()
+type WhenBuilder struct {
+	when When
+}
+
+// NewWhen returns a WhenBuilder for a When without cases.
+func NewWhen() *WhenBuilder {
+	return &WhenBuilder{}
+}
+
+// Case appends a case taking the action and continuing with the contract.
+func (b *WhenBuilder) Case(action Action, then Contract) *WhenBuilder {
+	b.when.Cases = append(b.when.Cases, Case{Action: action, Then: then})
+	return b
+}
+
+// Timeout sets the timeout of the When.
+func (b *WhenBuilder) Timeout(t Timeout) *WhenBuilder {
+	b.when.Timeout = t
+	return b
+}
+
+// Then sets the continuation taken once the When times out.
+func (b *WhenBuilder) Then(c Contract) *WhenBuilder {
+	b.when.Then = c
+	return b
+}
+
+// Build returns the When, or an error if a case lacks its action or
+// continuation, or the When lacks its timeout or timeout continuation.
+func (b *WhenBuilder) Build() (When, error) {
+	for i, cs := range b.when.Cases {
+		if cs.Action == nil {
+			return When{}, fmt.Errorf("case %d has no action", i)
+		}
+		if cs.Then == nil {
+			return When{}, fmt.Errorf("case %d has no continuation", i)
+		}
+	}
+	if b.when.Timeout == nil {
+		return When{}, errors.New("When has no timeout")
+	}
+	if b.when.Then == nil {
+		return When{}, errors.New("When has no timeout continuation")
+	}
+
+	when := b.when
+	when.Cases = append([]Case(nil), b.when.Cases...)
+	return when, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func openContinuations(c Contract, path string) []string {
+	var open []string
+	child := func(c Contract, key string) {
+		if c == nil {
+			open = append(open, joinPath(path, key))
+		} else {
+			open = append(open, openContinuations(c, joinPath(path, key))...)
+		}
+	}
+
+	switch c := c.(type) {
+	case Pay:
+		child(c.Then, "then")
+	case If:
+		child(c.Then, "then")
+		child(c.Else, "else")
+	case When:
+		for i, cs := range c.Cases {
+			if cs.MerkleizedThen == "" {
+				child(cs.Then, fmt.Sprintf("when[%d].then", i))
+			}
+		}
+		child(c.Then, "timeout_continuation")
+	case Let:
+		child(c.Then, "then")
+	case Assert:
+		child(c.Then, "then")
+	}
+
+	return open
+}
+
+// Return a copy of the contract with its first open continuation set to
+// with, and whether there was one to set.
+func fillContinuation(c Contract, with Contract) (Contract, bool) {
+	fill := func(child Contract) (Contract, bool) {
+		if child == nil {
+			return with, true
+		}
+		return fillContinuation(child, with)
+	}
+
+	var ok bool
+	switch c := c.(type) {
+	case Pay:
+		c.Then, ok = fill(c.Then)
+		return c, ok
+	case If:
+		if c.Then, ok = fill(c.Then); ok {
+			return c, true
+		}
+		c.Else, ok = fill(c.Else)
+		return c, ok
+	case When:
+		cases := make([]Case, len(c.Cases))
+		copy(cases, c.Cases)
+		c.Cases = cases
+		for i := range cases {
+			if cases[i].MerkleizedThen != "" {
+				continue
+			}
+			if cases[i].Then, ok = fill(cases[i].Then); ok {
+				return c, true
+			}
+		}
+		c.Then, ok = fill(c.Then)
+		return c, ok
+	case Let:
+		c.Then, ok = fill(c.Then)
+		return c, ok
+	case Assert:
+		c.Then, ok = fill(c.Then)
+		return c, ok
+	}
+
+	return c, false
+}
+
+func checkMissingFields(c Contract, path string) []Finding {
+	var findings []Finding
+	missing := func(construct, field string) {
+		at := path
+		if at == "" {
+			at = "$"
+		}
+		findings = append(findings, Finding{
+			Check:    CheckComplete,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%v at %v has no %v", construct, at, field),
+		})
+	}
+	child := func(c Contract, key string) {
+		if c != nil {
+			findings = append(findings, checkMissingFields(c, joinPath(path, key))...)
+		}
+	}
+
+	switch c := c.(type) {
+	case Pay:
+		if c.From == nil {
+			missing("Pay", "account")
+		}
+		if c.To.Party == nil && c.To.Account == nil {
+			missing("Pay", "payee")
+		}
+		if c.Pay == nil {
+			missing("Pay", "value")
+		}
+		child(c.Then, "then")
+	case If:
+		if c.Observe == nil {
+			missing("If", "observation")
+		}
+		child(c.Then, "then")
+		child(c.Else, "else")
+	case When:
+		if c.Timeout == nil {
+			missing("When", "timeout")
+		}
+		for i, cs := range c.Cases {
+			if cs.Action == nil {
+				missing("When", fmt.Sprintf("action for case %d", i))
+			}
+			child(cs.Then, fmt.Sprintf("when[%d].then", i))
+		}
+		child(c.Then, "timeout_continuation")
+	case Let:
+		if c.Value == nil {
+			missing("Let", "value")
+		}
+		child(c.Then, "then")
+	case Assert:
+		if c.Observe == nil {
+			missing("Assert", "observation")
+		}
+		child(c.Then, "then")
+	}
+
+	return findings
+}
@@ -0,0 +1,113 @@
+package language_test
+
+import (
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestBuilder_IncrementalWhen(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	b := m.NewBuilder()
+
+	err := b.Add(m.When{
+		Cases: []m.Case{{
+			Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")},
+		}},
+		Timeout: m.POSIXTime(1000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if open := b.Open(); len(open) != 2 || open[0] != "when[0].then" || open[1] != "timeout_continuation" {
+		t.Errorf("Expected the case and timeout continuations to be open, got: %v", open)
+	}
+
+	if err := b.Add(m.Close); err != nil {
+		t.Fatal(err)
+	}
+
+	report := b.Validate()
+	if !report.HasErrors() || len(report.Findings) != 1 ||
+		report.Findings[0].Check != m.CheckComplete ||
+		!strings.Contains(report.Findings[0].Message, "timeout_continuation") {
+		t.Errorf("Expected the timeout continuation to be reported, got: %v", report.Findings)
+	}
+	if _, err := b.Contract(); err == nil {
+		t.Error("Expected the incomplete contract to be refused")
+	}
+
+	if err := b.Add(m.Close); err != nil {
+		t.Fatal(err)
+	}
+
+	if report := b.Validate(); report.HasErrors() {
+		t.Errorf("Expected the contract to be complete, got: %v", report.Findings)
+	}
+	contract, err := b.Contract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if when, ok := contract.(m.When); !ok || when.Cases[0].Then != m.Close || when.Then != m.Close {
+		t.Errorf("Expected both continuations to be set, got: %v", contract)
+	}
+
+	if err := b.Add(m.Close); err == nil {
+		t.Error("Expected adding to a complete contract to fail")
+	}
+}
+
+func TestBuilder_MissingFields(t *testing.T) {
+	b := m.NewBuilder()
+	if err := b.Add(m.If{Then: m.Close, Else: m.When{Then: m.Close}}); err != nil {
+		t.Fatal(err)
+	}
+
+	report := b.Validate()
+	if len(report.Findings) != 2 {
+		t.Fatalf("Expected the observation and timeout to be reported, got: %v", report.Findings)
+	}
+	if !strings.Contains(report.Findings[1].Message, "When at else has no timeout") {
+		t.Errorf("Expected the path of the When, got: %v", report.Findings[1])
+	}
+}
+
+func TestWhenBuilder(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	deposit := m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")}
+	notify := m.Notify{If: m.TrueObs}
+
+	got, err := m.NewWhen().Case(deposit, m.Close).Case(notify, m.Close).Timeout(m.POSIXTime(100)).Then(m.Close).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := m.When{
+		Cases:   []m.Case{{Action: deposit, Then: m.Close}, {Action: notify, Then: m.Close}},
+		Timeout: m.POSIXTime(100),
+		Then:    m.Close,
+	}
+	if !m.Equal(got, expected) {
+		t.Errorf("Expected %v, got: %v", expected, got)
+	}
+}
+
+func TestWhenBuilder_Invalid(t *testing.T) {
+	notify := m.Notify{If: m.TrueObs}
+
+	for _, tc := range []struct {
+		builder *m.WhenBuilder
+		message string
+	}{
+		{m.NewWhen().Case(nil, m.Close).Timeout(m.POSIXTime(1)).Then(m.Close), "case 0 has no action"},
+		{m.NewWhen().Case(notify, m.Close).Case(notify, nil).Timeout(m.POSIXTime(1)).Then(m.Close), "case 1 has no continuation"},
+		{m.NewWhen().Case(notify, m.Close).Then(m.Close), "no timeout"},
+		{m.NewWhen().Case(notify, m.Close).Timeout(m.POSIXTime(1)), "no timeout continuation"},
+	} {
+		if _, err := tc.builder.Build(); err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("Expected an error containing %q, got: %v", tc.message, err)
+		}
+	}
+}
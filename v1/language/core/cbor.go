@@ -0,0 +1,333 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+)
+
+// Plutus data is the untyped representation of on-chain values, and takes one
+// of four forms: a constructor application, an integer, a byte string or a
+// list. (Plutus maps are not needed for Marlowe contracts.)
+//
+//	data Data = Constr Integer [Data] | Map [(Data, Data)] | List [Data]
+//		| I Integer | B ByteString
+type plutusData interface{}
+
+// A constructor application: the index of the constructor within its type,
+// as assigned by makeIsDataIndexed, and its fields.
+type plutusConstr struct {
+	index  uint64
+	fields []plutusData
+}
+
+type plutusList []plutusData
+
+func constrData(index uint64, fields ...plutusData) plutusData {
+	return plutusConstr{index: index, fields: fields}
+}
+
+// CBOR major types.
+const (
+	cborUnsigned byte = 0
+	cborNegative byte = 1
+	cborBytes    byte = 2
+	cborArray    byte = 4
+	cborTag      byte = 6
+)
+
+const (
+	cborIndefinite byte = 31
+	cborBreak      byte = 0xff
+)
+
+// Plutus splits byte strings longer than this into chunks of this size.
+const cborChunkSize = 64
+
+// Write the Plutus data as CBOR in the form used by the Plutus serialiser, so
+// that the bytes, and hence the datum hash, agree with marlowe-cardano.
+func writeData(buf *bytes.Buffer, d plutusData) {
+	switch d := d.(type) {
+	case plutusConstr:
+		switch {
+		case d.index < 7:
+			writeHead(buf, cborTag, 121+d.index)
+		case d.index < 128:
+			writeHead(buf, cborTag, 1280+d.index-7)
+		default:
+			writeHead(buf, cborTag, 102)
+			writeHead(buf, cborArray, 2)
+			writeHead(buf, cborUnsigned, d.index)
+		}
+		writeData(buf, plutusList(d.fields))
+	case *big.Int:
+		writeInteger(buf, d)
+	case []byte:
+		writeBytes(buf, d)
+	case plutusList:
+		if len(d) == 0 {
+			writeHead(buf, cborArray, 0)
+			return
+		}
+		buf.WriteByte(cborArray<<5 | cborIndefinite)
+		for _, item := range d {
+			writeData(buf, item)
+		}
+		buf.WriteByte(cborBreak)
+	}
+}
+
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= 0xff:
+		buf.Write([]byte{major<<5 | 24, byte(n)})
+	case n <= 0xffff:
+		buf.Write([]byte{major<<5 | 25, byte(n >> 8), byte(n)})
+	case n <= 0xffffffff:
+		buf.Write([]byte{major<<5 | 26, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+	default:
+		buf.WriteByte(major<<5 | 27)
+		for shift := 56; shift >= 0; shift -= 8 {
+			buf.WriteByte(byte(n >> shift))
+		}
+	}
+}
+
+// Write the integer as a CBOR integer if it fits in 64 bits, and otherwise as
+// a bignum: tag 2 or 3 on the big-endian bytes of n or of -1-n respectively.
+func writeInteger(buf *bytes.Buffer, n *big.Int) {
+	if n.Sign() >= 0 {
+		if n.IsUint64() {
+			writeHead(buf, cborUnsigned, n.Uint64())
+			return
+		}
+		writeHead(buf, cborTag, 2)
+		writeBytes(buf, n.Bytes())
+		return
+	}
+
+	m := new(big.Int).Neg(n)
+	m.Sub(m, big.NewInt(1))
+	if m.IsUint64() {
+		writeHead(buf, cborNegative, m.Uint64())
+		return
+	}
+	writeHead(buf, cborTag, 3)
+	writeBytes(buf, m.Bytes())
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	if len(b) <= cborChunkSize {
+		writeHead(buf, cborBytes, uint64(len(b)))
+		buf.Write(b)
+		return
+	}
+
+	buf.WriteByte(cborBytes<<5 | cborIndefinite)
+	for len(b) > 0 {
+		n := len(b)
+		if n > cborChunkSize {
+			n = cborChunkSize
+		}
+		writeHead(buf, cborBytes, uint64(n))
+		buf.Write(b[:n])
+		b = b[n:]
+	}
+	buf.WriteByte(cborBreak)
+}
+
+// A cborReader decodes the subset of CBOR that encodes Plutus data, accepting
+// both definite and indefinite lengths for arrays and byte strings.
+type cborReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *cborReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("cbor: unexpected end of data")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// Read the head of an item: its major type, and either its argument or, for
+// an indefinite length, indefinite set.
+func (r *cborReader) head() (major byte, n uint64, indefinite bool, err error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	major, info := b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if r.pos+size > len(r.data) {
+			return 0, 0, false, fmt.Errorf("cbor: unexpected end of data")
+		}
+		for _, b := range r.data[r.pos : r.pos+size] {
+			n = n<<8 | uint64(b)
+		}
+		r.pos += size
+		return major, n, false, nil
+	case info == cborIndefinite:
+		return major, 0, true, nil
+	}
+
+	return 0, 0, false, fmt.Errorf("cbor: invalid additional information %d at offset %d", info, r.pos-1)
+}
+
+// Report whether the next byte is a break, consuming it if so.
+func (r *cborReader) atBreak() bool {
+	if r.pos < len(r.data) && r.data[r.pos] == cborBreak {
+		r.pos++
+		return true
+	}
+	return false
+}
+
+func (r *cborReader) read() (plutusData, error) {
+	start := r.pos
+	major, n, indefinite, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && major != cborBytes && major != cborArray {
+		return nil, fmt.Errorf("cbor: unexpected indefinite length at offset %d", start)
+	}
+
+	switch major {
+	case cborUnsigned:
+		return new(big.Int).SetUint64(n), nil
+	case cborNegative:
+		m := new(big.Int).SetUint64(n)
+		return m.Neg(m.Add(m, big.NewInt(1))), nil
+	case cborBytes:
+		if indefinite {
+			return r.chunks()
+		}
+		return r.bytes(n)
+	case cborArray:
+		var list plutusList
+		for i := uint64(0); indefinite || i < n; i++ {
+			if indefinite && r.atBreak() {
+				break
+			}
+			if !indefinite && uint64(len(r.data)-r.pos) < n-i {
+				return nil, fmt.Errorf("cbor: unexpected end of data")
+			}
+			item, err := r.read()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case cborTag:
+		return r.tagged(n, start)
+	}
+
+	return nil, fmt.Errorf("cbor: unsupported major type %d at offset %d", major, start)
+}
+
+func (r *cborReader) bytes(n uint64) ([]byte, error) {
+	if uint64(len(r.data)-r.pos) < n {
+		return nil, fmt.Errorf("cbor: unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// Read the definite length chunks of an indefinite length byte string.
+func (r *cborReader) chunks() ([]byte, error) {
+	var b []byte
+	for !r.atBreak() {
+		start := r.pos
+		major, n, indefinite, err := r.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborBytes || indefinite {
+			return nil, fmt.Errorf("cbor: invalid byte string chunk at offset %d", start)
+		}
+		chunk, err := r.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+	return b, nil
+}
+
+func (r *cborReader) tagged(tag uint64, start int) (plutusData, error) {
+	switch {
+	case tag == 2 || tag == 3:
+		item, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		b, ok := item.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("cbor: bignum at offset %d is not a byte string", start)
+		}
+		n := new(big.Int).SetBytes(b)
+		if tag == 3 {
+			n.Neg(n.Add(n, big.NewInt(1)))
+		}
+		return n, nil
+	case tag >= 121 && tag <= 127:
+		return r.constr(tag-121, start)
+	case tag >= 1280 && tag <= 1400:
+		return r.constr(tag-1280+7, start)
+	case tag == 102:
+		item, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		pair, ok := item.(plutusList)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("cbor: constructor at offset %d is not an index and fields", start)
+		}
+		index, ok := pair[0].(*big.Int)
+		fields, isList := pair[1].(plutusList)
+		if !ok || !index.IsUint64() || !isList {
+			return nil, fmt.Errorf("cbor: constructor at offset %d is not an index and fields", start)
+		}
+		return plutusConstr{index: index.Uint64(), fields: fields}, nil
+	}
+
+	return nil, fmt.Errorf("cbor: unsupported tag %d at offset %d", tag, start)
+}
+
+func (r *cborReader) constr(index uint64, start int) (plutusData, error) {
+	item, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := item.(plutusList)
+	if !ok {
+		return nil, fmt.Errorf("cbor: fields of constructor at offset %d are not a list", start)
+	}
+	return plutusConstr{index: index, fields: fields}, nil
+}
@@ -0,0 +1,201 @@
+package language_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	assert "github.com/menabrealabs/marlowe/assertion"
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestMarshalCBOR_Encoding(t *testing.T) {
+	tests := []struct {
+		contract m.Contract
+		expected string
+	}{
+		// Constr 0 []
+		{m.Close, "d87980"},
+		// Constr 3 [[], 100, Close], with the non-empty field list of
+		// indefinite length as Plutus encodes it.
+		{m.When{Timeout: m.POSIXTime(100), Then: m.Close}, "d87c9f801864d87980ff"},
+		// Constr 4 ["x", Constant 5, Close]
+		{m.Let{Name: "x", Value: m.SetConstant("5"), Then: m.Close}, "d87d9f4178d87a9f05ffd87980ff"},
+		// Constr 5 [FalseObs, Close]: FalseObs is constructor 10, encoded
+		// with the tag for constructors 7 and above.
+		{m.Assert{Observe: m.FalseObs, Then: m.Close}, "d87e9fd9050380d87980ff"},
+		// Constr 4 ["x", SubValue (Constant 7) (Constant 2), Close]: the
+		// ToData instance of marlowe-cardano puts the minuend of SubValue
+		// lhs rhs first, so From precedes Subtract.
+		{m.Let{Name: "x", Value: m.SubValue{From: m.SetConstant("7"), Subtract: m.SetConstant("2")}, Then: m.Close}, "d87d9f4178d87d9fd87a9f07ffd87a9f02ffffd87980ff"},
+	}
+
+	for _, test := range tests {
+		data, err := m.MarshalCBOR(test.contract)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(data); got != test.expected {
+			t.Errorf("%v: expected %v, got: %v", test.contract, test.expected, got)
+		}
+	}
+}
+
+func TestMarshalCBOR_RoundTrip(t *testing.T) {
+	alice := m.Role{Name: "alice"}
+	dollar := m.Token{Symbol: "85bb65085bb65085bb65085bb65085bb65085bb65085bb65085bb650", Name: "dollar"}
+	huge, negative := m.SetConstant("123456789012345678901234567890"), m.SetConstant("-123456789012345678901234567890")
+
+	contracts := map[string]m.Contract{
+		"escrow": setupEscrowContract(),
+		"values": m.Let{
+			Name: m.ValueId(strings.Repeat("v", 100)), // chunked into byte strings of 64
+			Value: m.Cond{
+				Observation: m.AndObs{
+					Both: m.OrObs{Either: m.ChoseSomething{Choice: m.ChoiceId{Name: "c", Owner: alice}}, Or: m.NotObs{Not: m.TrueObs}},
+					And: m.AndObs{
+						Both: m.ValueGE{Value: m.TimeIntervalStart, Ge: m.TimeIntervalEnd},
+						And: m.OrObs{
+							Either: m.ValueGT{Value: huge, Gt: negative},
+							Or: m.AndObs{
+								Both: m.ValueLT{Value: m.SetConstant("-1"), Lt: m.SetConstant("0")},
+								And:  m.OrObs{Either: m.ValueLE{Value: m.SetConstant("1"), Le: m.SetConstant("2")}, Or: m.ValueEQ{Value: m.SetConstant("3"), Eq: m.SetConstant("3")}},
+							},
+						},
+					},
+				},
+				IfTrue:  m.AddValue{Add: m.NegValue{Neg: huge}, To: m.SubValue{Subtract: m.AvailableMoney{Amount: dollar, Account: alice}, From: m.ChoiceValue{Value: m.ChoiceId{Name: "c", Owner: alice}}}},
+				IfFalse: m.MulValue{Multiply: m.DivValue{Divide: m.UseValue{Value: "x"}, By: negative}, By: m.SetConstant("18446744073709551616")},
+			},
+			Then: m.Assert{Observe: m.FalseObs, Then: m.If{
+				Observe: m.TrueObs,
+				Then:    m.Pay{From: alice, To: m.Payee{Account: alice}, Token: dollar, Pay: m.SetConstant("1"), Then: m.Close},
+				Else:    m.Close,
+			}},
+		},
+		"merkleized": m.When{
+			Cases: []m.Case{
+				m.MerkleizedCase(m.Notify{If: m.TrueObs}, "3c2a0b6d8e4d4b7f8dbd4c5b1fa09e37e1f3a2bc0b9c2f3e7c4b0f8a9d6e5c4b"),
+				{Action: m.Choice{ChoiceId: m.ChoiceId{Name: "c", Owner: alice}, Bounds: []m.Bound{{Lower: 0, Upper: 1 << 63}}}, Then: m.Close},
+			},
+			Timeout: m.POSIXTime(1666078977926),
+			Then:    m.Close,
+		},
+	}
+
+	for name, contract := range contracts {
+		data, err := m.MarshalCBOR(contract)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		decoded, err := m.UnmarshalCBOR(data)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if !m.Equal(contract, decoded) {
+			t.Errorf("%v: expected %v, got: %v", name, contract, decoded)
+		}
+	}
+}
+
+// Helper function returning a swap: alice deposits 100 ADA, then bob deposits
+// 10 dollars, and the two deposits are exchanged.
+func setupSwapContract() m.Contract {
+	alice, bob := m.Role{Name: "alice"}, m.Role{Name: "bob"}
+	dollar := m.Token{Symbol: "85bb65085bb65085bb65085bb65085bb65085bb65085bb65085bb650", Name: "dollar"}
+	ada, dollars := m.SetConstant("100000000"), m.SetConstant("10")
+
+	return m.When{
+		Cases: []m.Case{{
+			Action: m.Deposit{IntoAccount: alice, Party: alice, Token: m.Ada, Deposits: ada},
+			Then: m.When{
+				Cases: []m.Case{{
+					Action: m.Deposit{IntoAccount: bob, Party: bob, Token: dollar, Deposits: dollars},
+					Then: m.Pay{
+						From: alice, To: m.Payee{Party: bob}, Token: m.Ada, Pay: ada,
+						Then: m.Pay{From: bob, To: m.Payee{Party: alice}, Token: dollar, Pay: dollars, Then: m.Close},
+					},
+				}},
+				Timeout: m.POSIXTime(1666078987926),
+				Then:    m.Close,
+			},
+		}},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+}
+
+func TestFormatsAgree(t *testing.T) {
+	assert.AssertFormatsAgree(t, setupEscrowContract())
+	assert.AssertFormatsAgree(t, setupSwapContract())
+}
+
+func TestMarshalCBOR_AddressParties(t *testing.T) {
+	addresses := []m.Address{
+		"addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x",
+		"addr1z8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gten0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgs9yc0hh",
+		"addr1gx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer5pnz75xxcrzqf96k",
+		"addr1w8phkx6acpnf78fuvxn0mkew3l0fd058hzquvz7w36x4gtcyjy7wx",
+		"addr_test1vz3ppzmmzuz0nlsjeyrqjm4pvdxl3cyfe8x06eg6htj2gwgv02qjt",
+	}
+
+	for _, address := range addresses {
+		contract := m.Pay{From: address, To: m.Payee{Party: address}, Token: m.Ada, Pay: m.SetConstant("1"), Then: m.Close}
+		data, err := m.MarshalCBOR(contract)
+		if err != nil {
+			t.Fatalf("%v: %v", address, err)
+		}
+		decoded, err := m.UnmarshalCBOR(data)
+		if err != nil {
+			t.Fatalf("%v: %v", address, err)
+		}
+		if pay := decoded.(m.Pay); pay.From != address || pay.To.Party != address {
+			t.Errorf("Expected %v, got: %v", address, decoded)
+		}
+	}
+}
+
+func TestMarshalCBOR_Errors(t *testing.T) {
+	if _, err := m.MarshalCBOR(m.When{Timeout: nil, Then: m.Close}); err == nil {
+		t.Error("Expected a When without a POSIXTime timeout to be rejected")
+	}
+	if _, err := m.MarshalCBOR(m.Pay{From: m.Address("addr1_buyer"), To: m.Payee{Party: m.Role{Name: "seller"}}, Token: m.Ada, Pay: m.SetConstant("1"), Then: m.Close}); err == nil {
+		t.Error("Expected an invalid address to be rejected")
+	}
+
+	for _, data := range []string{
+		"",             // no data
+		"d87a80",       // Pay with no fields
+		"d87980d87980", // trailing data
+		"d8799f",       // unterminated fields
+		"d87b9fd87a9f", // truncated
+		"a0",           // a map
+	} {
+		b, _ := hex.DecodeString(data)
+		if c, err := m.UnmarshalCBOR(b); err == nil {
+			t.Errorf("%q: expected an error, got: %v", data, c)
+		}
+	}
+}
+
+func TestMarshalCBOR_Bignum(t *testing.T) {
+	// Constant 2^64 is a tag 2 bignum, and -2^64-1 a tag 3 bignum on 2^64.
+	for value, expected := range map[string]string{
+		"18446744073709551616":  "d87a9fc249010000000000000000ff",
+		"-18446744073709551617": "d87a9fc349010000000000000000ff",
+	} {
+		data, err := m.MarshalCBOR(m.Let{Name: "x", Value: m.SetConstant(value), Then: m.Close})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(data); !strings.Contains(got, expected) {
+			t.Errorf("%v: expected %v within %v", value, expected, got)
+		}
+
+		decoded, _ := m.UnmarshalCBOR(data)
+		n := decoded.(m.Let).Value.(m.Constant).Int()
+		if n.String() != value {
+			t.Errorf("Expected %v, got: %v", value, n.String())
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "math/big"
+
+// Clone returns a deep copy of the contract that shares no memory with it:
+// Case and Bound slices are copied, as are the digits of every Constant, so
+// that a transformation mutating the copy in place cannot corrupt the
+// original.
+func Clone(c Contract) Contract {
+	switch c := c.(type) {
+	case Pay:
+		c.Pay = CloneValue(c.Pay)
+		c.Then = Clone(c.Then)
+		return c
+	case If:
+		c.Observe = cloneObservation(c.Observe)
+		c.Then = Clone(c.Then)
+		c.Else = Clone(c.Else)
+		return c
+	case When:
+		if c.Cases != nil {
+			cases := make([]Case, len(c.Cases))
+			for i, cs := range c.Cases {
+				cases[i] = Case{Action: cloneAction(cs.Action), Then: Clone(cs.Then), MerkleizedThen: cs.MerkleizedThen}
+			}
+			c.Cases = cases
+		}
+		c.Then = Clone(c.Then)
+		return c
+	case Let:
+		c.Value = CloneValue(c.Value)
+		c.Then = Clone(c.Then)
+		return c
+	case Assert:
+		c.Observe = cloneObservation(c.Observe)
+		c.Then = Clone(c.Then)
+		return c
+	}
+
+	return c
+}
+
+// CloneValue returns a deep copy of the value, or observation, that shares no
+// memory with it.
+func CloneValue(v Value) Value {
+	switch v := v.(type) {
+	case Constant:
+		return cloneConstant(v)
+	case NegValue:
+		v.Neg = CloneValue(v.Neg)
+		return v
+	case AddValue:
+		v.Add = CloneValue(v.Add)
+		v.To = CloneValue(v.To)
+		return v
+	case SubValue:
+		v.Subtract = CloneValue(v.Subtract)
+		v.From = CloneValue(v.From)
+		return v
+	case MulValue:
+		v.Multiply = CloneValue(v.Multiply)
+		v.By = CloneValue(v.By)
+		return v
+	case DivValue:
+		v.Divide = CloneValue(v.Divide)
+		v.By = CloneValue(v.By)
+		return v
+	case Cond:
+		v.Observation = cloneObservation(v.Observation)
+		v.IfTrue = CloneValue(v.IfTrue)
+		v.IfFalse = CloneValue(v.IfFalse)
+		return v
+	case AndObs:
+		v.Both = cloneObservation(v.Both)
+		v.And = cloneObservation(v.And)
+		return v
+	case OrObs:
+		v.Either = cloneObservation(v.Either)
+		v.Or = cloneObservation(v.Or)
+		return v
+	case NotObs:
+		v.Not = cloneObservation(v.Not)
+		return v
+	case ValueGE:
+		v.Value = CloneValue(v.Value)
+		v.Ge = CloneValue(v.Ge)
+		return v
+	case ValueGT:
+		v.Value = CloneValue(v.Value)
+		v.Gt = CloneValue(v.Gt)
+		return v
+	case ValueLT:
+		v.Value = CloneValue(v.Value)
+		v.Lt = CloneValue(v.Lt)
+		return v
+	case ValueLE:
+		v.Value = CloneValue(v.Value)
+		v.Le = CloneValue(v.Le)
+		return v
+	case ValueEQ:
+		v.Value = CloneValue(v.Value)
+		v.Eq = CloneValue(v.Eq)
+		return v
+	}
+
+	return v
+}
+
+// CloneState returns a deep copy of the state: its Accounts, Choices and
+// BoundValues maps are copied along with the numbers they hold. A nil map
+// stays nil.
+func CloneState(s State) State {
+	clone := State{MinTime: s.MinTime}
+
+	if s.Accounts != nil {
+		clone.Accounts = make(Accounts, len(s.Accounts))
+		for account, amount := range s.Accounts {
+			clone.Accounts[account] = amount
+		}
+	}
+	if s.Choices != nil {
+		clone.Choices = make(map[ChoiceId]ChosenNum, len(s.Choices))
+		for id, num := range s.Choices {
+			clone.Choices[id] = NewChosenNum(new(big.Int).Set(num.Int()))
+		}
+	}
+	if s.BoundValues != nil {
+		clone.BoundValues = make(map[ValueId]*big.Int, len(s.BoundValues))
+		for id, value := range s.BoundValues {
+			if value != nil {
+				value = new(big.Int).Set(value)
+			}
+			clone.BoundValues[id] = value
+		}
+	}
+
+	return clone
+}
+
+func cloneAction(a Action) Action {
+	switch a := a.(type) {
+	case Deposit:
+		a.Deposits = CloneValue(a.Deposits)
+		return a
+	case Choice:
+		if a.Bounds != nil {
+			a.Bounds = append([]Bound(nil), a.Bounds...)
+		}
+		return a
+	case Notify:
+		a.If = cloneObservation(a.If)
+		return a
+	}
+
+	return a
+}
+
+func cloneObservation(o Observation) Observation {
+	if o == nil {
+		return nil
+	}
+	return CloneValue(o).(Observation)
+}
+
+func cloneConstant(c Constant) Constant {
+	return NewConstant(new(big.Int).Set(c.Int()))
+}
@@ -0,0 +1,77 @@
+package language_test
+
+import (
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestClone_Independent(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	source := m.When{
+		Cases: []m.Case{
+			{
+				Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("123456789012345678901234567890")},
+				Then: m.If{
+					Observe: m.ValueGT{Value: m.UseValue{Value: "x"}, Gt: m.SetConstant("98765432109876543210")},
+					Then: m.Let{
+						Name:  "x",
+						Value: m.Cond{Observation: m.TrueObs, IfTrue: m.NegValue{Neg: m.SetConstant("1")}, IfFalse: m.SetConstant("2")},
+						Then:  m.Pay{From: buyer, To: m.Payee{Party: seller}, Token: m.Ada, Pay: m.SetConstant("3"), Then: m.Close},
+					},
+					Else: m.Assert{Observe: m.NotObs{Not: m.FalseObs}, Then: m.Close},
+				},
+			},
+			{
+				Action: m.Choice{ChoiceId: m.ChoiceId{Name: "c", Owner: seller}, Bounds: []m.Bound{{Lower: 1, Upper: 2}}},
+				Then:   m.Close,
+			},
+		},
+		Timeout: m.POSIXTime(100),
+		Then:    m.Close,
+	}
+	before := m.FingerprintHex(source)
+
+	clone := m.Clone(source).(m.When)
+	if !m.Equal(clone, source) {
+		t.Fatalf("Expected the clone to equal the source, got: %v", clone)
+	}
+
+	// Overwrite the integers held by Constants in place.
+	clone.Cases[0].Action.(m.Deposit).Deposits.(m.Constant).Int().SetInt64(7)
+	clone.Cases[0].Then.(m.If).Observe.(m.ValueGT).Gt.(m.Constant).Int().SetInt64(7)
+	clone.Cases[1].Action.(m.Choice).Bounds[0] = m.Bound{Lower: 5, Upper: 6}
+	clone.Cases[1] = m.Case{Action: m.Notify{If: m.TrueObs}, Then: m.Close}
+
+	if m.FingerprintHex(source) != before {
+		t.Errorf("Expected mutating the clone to leave the source unchanged, got: %v", source)
+	}
+}
+
+func TestCloneState_Independent(t *testing.T) {
+	account := m.Account{AccountId: m.Role{Name: "buyer"}, Token: m.Ada}
+	choice := m.ChoiceId{Name: "c", Owner: m.Role{Name: "buyer"}}
+	source := m.State{
+		Accounts:    m.Accounts{account: 10},
+		Choices:     map[m.ChoiceId]m.ChosenNum{choice: m.NewChosenNum(big.NewInt(1 << 62))},
+		BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(1 << 62)},
+		MinTime:     5,
+	}
+
+	clone := m.CloneState(source)
+	clone.Accounts[account] = 20
+	clone.Choices[choice].Int().SetInt64(3)
+	clone.BoundValues["x"].SetInt64(3)
+	clone.BoundValues["y"] = big.NewInt(1)
+
+	expected := m.State{
+		Accounts:    m.Accounts{account: 10},
+		Choices:     map[m.ChoiceId]m.ChosenNum{choice: m.NewChosenNum(big.NewInt(1 << 62))},
+		BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(1 << 62)},
+		MinTime:     5,
+	}
+	if !m.EqualStates(source, expected) {
+		t.Errorf("Expected mutating the clone to leave the source unchanged, got: %v", source)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ClosingInputs computes the fewest transactions, each carrying a single
+// choice or notification, that drive the contract to Close within the time
+// interval of the environment. Deposits are never made, since winding a
+// contract down should not require anyone to pay in. Each choice is tried
+// with both ends of each of its bounds, since a later If may depend on the
+// number chosen.
+//
+// If the contract cannot be closed without a deposit, an error names the
+// timeout after which it closes by itself.
+func ClosingInputs(env Environment, state State, c Contract) ([]TransactionInput, error) {
+	type node struct {
+		state    State
+		contract Contract
+		inputs   []Input
+	}
+
+	start := reduceContractUntilQuiescent(env, state, c, nil)
+	if start.err != nil {
+		return nil, fmt.Errorf("the contract cannot be reduced: %v", transactionErrorName(start.err))
+	}
+
+	// Different orders of the same inputs often lead to the same contract and
+	// state, which need only be explored once.
+	var visited []node
+	seen := func(n node) bool {
+		for _, v := range visited {
+			if Equal(v.contract, n.contract) && EqualStates(v.state, n.state) {
+				return true
+			}
+		}
+		visited = append(visited, n)
+		return false
+	}
+
+	queue := []node{{state: start.state, contract: start.contract}}
+	seen(queue[0])
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		when, ok := n.contract.(When)
+		if !ok {
+			return closingTransactions(env, n.inputs, start.reduced), nil
+		}
+
+		for _, cs := range when.Cases {
+			for _, input := range closingInputs(env, n.state, cs.Action) {
+				result := applyAllInputs(env, n.state, n.contract, []Input{input}, nil)
+				if result.err != nil {
+					continue
+				}
+
+				next := node{state: result.state, contract: result.contract}
+				if seen(next) {
+					continue
+				}
+				next.inputs = append(append([]Input{}, n.inputs...), input)
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	timeout, err := closingTimeout(start.state, start.contract)
+	if err != nil {
+		return nil, fmt.Errorf("the contract cannot be closed without a deposit: %w", err)
+	}
+	return nil, fmt.Errorf("the contract cannot be closed without a deposit before its timeout %v", timeout)
+}
+
+// Choose the inputs other than deposits that the action accepts.
+func closingInputs(env Environment, state State, action Action) []Input {
+	switch a := action.(type) {
+	case Choice:
+		var inputs []Input
+		tried := make(map[uint64]bool)
+		for _, b := range a.Bounds {
+			for _, n := range []uint64{b.Lower, b.Upper} {
+				if tried[n] {
+					continue
+				}
+				tried[n] = true
+				inputs = append(inputs, IChoice{ChoiceId: a.ChoiceId, ChosenNum: NewChosenNum(new(big.Int).SetUint64(n))})
+			}
+		}
+		return inputs
+	case Notify:
+		if EvalObservation(env, state, a.If) {
+			return []Input{INotify{}}
+		}
+	}
+
+	return nil
+}
+
+// Find the timeout after which the contract closes without any input, by
+// following the timeout continuations of each When it reduces to.
+func closingTimeout(state State, c Contract) (POSIXTime, error) {
+	var timeout POSIXTime
+	for {
+		when, ok := c.(When)
+		if !ok {
+			return timeout, nil
+		}
+		t, ok := when.Timeout.(POSIXTime)
+		if !ok {
+			return 0, fmt.Errorf("unsupported timeout %v", when.Timeout)
+		}
+		if t > timeout {
+			timeout = t
+		}
+
+		// Reduce at the later of the timeout and the minimum time of the
+		// state, when the When has timed out.
+		at := timeout
+		if state.MinTime > at {
+			at = state.MinTime
+		}
+		result := reduceContractUntilQuiescent(Environment{TimeInterval: NewTimeInterval(at, at)}, state, when, nil)
+		if result.err != nil {
+			return 0, fmt.Errorf("reducing after timeout %v: %v", t, transactionErrorName(result.err))
+		}
+		state, c = result.state, result.contract
+	}
+}
+
+// Put each input in a transaction of its own. A contract that closes without
+// input still needs a transaction to reduce it, unless it is already closed.
+func closingTransactions(env Environment, inputs []Input, reduced bool) []TransactionInput {
+	if len(inputs) == 0 {
+		if reduced {
+			return []TransactionInput{{Interval: env.TimeInterval}}
+		}
+		return nil
+	}
+
+	txs := make([]TransactionInput, len(inputs))
+	for i, input := range inputs {
+		txs[i] = TransactionInput{Interval: env.TimeInterval, Inputs: []Input{input}}
+	}
+	return txs
+}
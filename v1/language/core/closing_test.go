@@ -0,0 +1,96 @@
+package language_test
+
+import (
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestClosingInputs_Notify(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	env := m.Environment{TimeInterval: m.NewTimeInterval(0, 10)}
+	contract := m.When{
+		Cases: []m.Case{
+			{
+				Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")},
+				Then:   m.Close,
+			},
+			{Action: m.Notify{If: m.TrueObs}, Then: m.Close},
+		},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	txs, err := m.ClosingInputs(env, m.State{}, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || len(txs[0].Inputs) != 1 || txs[0].Inputs[0] != m.Input(m.INotify{}) {
+		t.Fatalf("Expected a single notify, got: %v", txs)
+	}
+
+	result, terr := m.Simulate(contract, m.State{}, txs)
+	if terr != nil || !result.IsClosed() {
+		t.Errorf("Expected the inputs to close the contract, got: %v %v", result.RemainingContract(), terr)
+	}
+}
+
+func TestClosingInputs_ChoiceBounds(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	env := m.Environment{TimeInterval: m.NewTimeInterval(0, 10)}
+	choiceId := m.ChoiceId{Name: "agree", Owner: buyer}
+	deposit := m.When{
+		Cases: []m.Case{{
+			Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")},
+			Then:   m.Close,
+		}},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	// Only choosing the upper end of the bound avoids the deposit.
+	contract := m.When{
+		Cases: []m.Case{{
+			Action: m.Choice{ChoiceId: choiceId, Bounds: []m.Bound{{Lower: 0, Upper: 1}}},
+			Then:   m.If{Observe: m.ValueEQ{Value: m.ChoiceValue{Value: choiceId}, Eq: m.SetConstant("1")}, Then: m.Close, Else: deposit},
+		}},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	txs, err := m.ClosingInputs(env, m.State{}, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, terr := m.Simulate(contract, m.State{}, txs)
+	if terr != nil || !result.IsClosed() {
+		t.Errorf("Expected the inputs to close the contract, got: %v %v", txs, terr)
+	}
+}
+
+func TestClosingInputs_TimeoutOnly(t *testing.T) {
+	env := m.Environment{TimeInterval: m.NewTimeInterval(0, 10)}
+
+	_, err := m.ClosingInputs(env, m.State{}, setupEscrowContract())
+	if err == nil || !strings.Contains(err.Error(), "1666078977926") {
+		t.Errorf("Expected the escrow to close only via its timeout, got: %v", err)
+	}
+
+	// The first timeout leads to another deposit, which closes later.
+	buyer := m.Role{Name: "buyer"}
+	deposit := func(timeout m.POSIXTime, then m.Contract) m.Contract {
+		return m.When{
+			Cases: []m.Case{{
+				Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")},
+				Then:   m.Close,
+			}},
+			Timeout: timeout,
+			Then:    then,
+		}
+	}
+	_, err = m.ClosingInputs(env, m.State{}, deposit(m.POSIXTime(100), deposit(m.POSIXTime(200), m.Close)))
+	if err == nil || !strings.Contains(err.Error(), "timeout 200") {
+		t.Errorf("Expected the error to name the timeout 200, got: %v", err)
+	}
+}
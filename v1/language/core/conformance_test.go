@@ -0,0 +1,44 @@
+package language_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	assert "github.com/menabrealabs/marlowe/assertion"
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// Reference vectors in testdata/conformance are hand-written contracts in the
+// JSON shape of marlowe-cardano's serialization. To add a vector, drop its
+// JSON into the directory and register the equivalent Go contract here under
+// the file's base name.
+var conformanceContracts = map[string]func() m.Contract{
+	"escrow": setupEscrowContract,
+}
+
+func TestConformance(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "conformance", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+
+		t.Run(name, func(t *testing.T) {
+			setup, ok := conformanceContracts[name]
+			if !ok {
+				t.Fatalf("No Go contract registered for reference vector %v", file)
+			}
+
+			reference, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.JsonConforms(t, setup(), reference)
+		})
+	}
+}
@@ -17,6 +17,8 @@
 // See: https://github.com/input-output-hk/marlowe-cardano/blob/main/marlowe/src/Language/Marlowe/Core/V1/Semantics/Types.hs
 package language
 
+import "encoding/json"
+
 // "2.1.7 Contracts
 //
 // Marlowe is a continuation-based language, this means that a Contract can
@@ -37,15 +39,48 @@ type Contract interface {
 }
 
 type Case struct {
-	Action Action   `json:"case"`
-	Then   Contract `json:"then"`
+	Action Action
+	Then   Contract
+	// MerkleizedThen, when set, stands in place of Then for the hash of the
+	// continuation, which is then supplied with the input that takes the case.
+	MerkleizedThen Hash
+}
+
+// A Hash is the hex-encoded hash of a merkleized continuation.
+type Hash string
+
+// MerkleizedCase returns a Case whose continuation is known only by its hash.
+//
+//	data Case = Case Action Contract | MerkleizedCase Action BuiltinByteString
+func MerkleizedCase(action Action, hash Hash) Case {
+	return Case{Action: action, MerkleizedThen: hash}
 }
 
 func (c Case) isCase() {}
 
+// Marshal the Case with its action under "case" and its continuation under
+// "then", or its continuation hash under "merkleized_then".
+func (c Case) MarshalJSON() ([]byte, error) {
+	if c.MerkleizedThen != "" {
+		return json.Marshal(struct {
+			Action Action `json:"case"`
+			Hash   Hash   `json:"merkleized_then"`
+		}{c.Action, c.MerkleizedThen})
+	}
+
+	return json.Marshal(struct {
+		Action Action   `json:"case"`
+		Then   Contract `json:"then"`
+	}{c.Action, c.Then})
+}
+
 // "Close is the simplest contract, when we evaluate it, the execution is completed
 // and we generate Payments §?? for the assets in the internal accounts to their
 // default owners." (§2.1.6)
+//
+// Close is reduced one refund at a time, paying out the accounts in the order
+// of the Haskell implementation's Map: by owner, with addresses before roles,
+// and then by currency symbol and token name.
 type CloseContract string
 
 const Close CloseContract = "close"
@@ -97,6 +132,24 @@ type When struct {
 func (c When) isContract() {}
 func (c When) isCase()     {}
 
+// Marshal the When with its cases under "when", which is an empty array rather
+// than null when there are no cases. The timeout marshals itself, so a
+// POSIXTime is emitted as a number and an Extended timeout parameter in its
+// own form. A missing timeout is emitted as null, as a missing continuation
+// is, so that any contract can be marshalled.
+func (c When) MarshalJSON() ([]byte, error) {
+	cases := c.Cases
+	if cases == nil {
+		cases = []Case{}
+	}
+
+	return json.Marshal(struct {
+		Cases   []Case   `json:"when"`
+		Timeout Timeout  `json:"timeout"`
+		Then    Contract `json:"timeout_continuation"`
+	}{cases, c.Timeout, c.Then})
+}
+
 // "A Let contract Let i v c allows a contract to record a value using an identifier
 // i. In this case, the expression v is evaluated, and the result is stored with
 // the name i. The contract then continues as c. As well as allowing us to
@@ -1,6 +1,7 @@
 package language_test
 
 import (
+	"encoding/json"
 	"math/big"
 	"testing"
 
@@ -57,13 +58,13 @@ func TestTypes_PayContract(t *testing.T) {
 
 	contract := m.Pay{
 		From:  m.Role{"debtor"},
-		To:    m.Payee{m.Role{"creditor"}},
+		To:    m.Payee{Party: m.Role{"creditor"}},
 		Token: m.Ada,
-		Pay:   m.Constant(*big.NewInt(5_000_000)),
+		Pay:   m.NewConstant(big.NewInt(5_000_000)),
 		Then:  m.Close,
 	}
 
-	assert.Json(t, contract, `{"from_account":{"role_token":"debtor"},"to":{"Party":{"role_token":"creditor"}},"token":{"currency_symbol":"","token_name":""},"pay":5000000,"then":"close"}`)
+	assert.Json(t, contract, `{"from_account":{"role_token":"debtor"},"to":{"party":{"role_token":"creditor"}},"token":{"currency_symbol":"","token_name":""},"pay":5000000,"then":"close"}`)
 }
 
 func TestTypes_WhenContract(t *testing.T) {
@@ -92,5 +93,70 @@ func TestTypes_WhenContract(t *testing.T) {
 		Then:    m.Close,
 	}
 
-	assert.Json(t, contract, `{"when":[{"case":{"for_choice":{"choice_name":"option","choice_owner":{"role_token":"creditor"}},"choose_between":[{"from":3,"to":2}]},"then":"close"}],"timeout":1666078977926,"timeout_continuation":"close"}`)
+	assert.Json(t, contract, `{"when":[{"case":{"for_choice":{"choice_name":"option","choice_owner":{"role_token":"creditor"}},"choose_between":[{"from":2,"to":3}]},"then":"close"}],"timeout":1666078977926,"timeout_continuation":"close"}`)
+}
+
+func TestTypes_WhenContract_NoCases(t *testing.T) {
+	contract := m.When{
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	assert.Json(t, contract, `{"when":[],"timeout":1666078977926,"timeout_continuation":"close"}`)
+}
+
+func TestTypes_Case(t *testing.T) {
+	action := m.Notify{If: m.TrueObs}
+
+	assert.Json(t, m.Case{Action: action, Then: m.Close}, `{"case":{"notify_if":true},"then":"close"}`)
+
+	hash := m.Hash("0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9")
+	assert.Json(t, m.MerkleizedCase(action, hash), `{"case":{"notify_if":true},"merkleized_then":"`+string(hash)+`"}`)
+}
+
+func TestTypes_PayContract_Account(t *testing.T) {
+	contract := m.Pay{
+		From:  m.Role{Name: "debtor"},
+		To:    m.Payee{Account: m.Role{Name: "creditor"}},
+		Token: m.Ada,
+		Pay:   m.SetConstant("5000000"),
+		Then:  m.Close,
+	}
+
+	assert.Json(t, contract, `{"from_account":{"role_token":"debtor"},"to":{"account":{"role_token":"creditor"}},"token":{"currency_symbol":"","token_name":""},"pay":5000000,"then":"close"}`)
+}
+
+func TestTypes_AddressParty(t *testing.T) {
+	contract := m.Pay{
+		From:  m.Address("addr1_buyer"),
+		To:    m.Payee{Party: m.Address("addr1_seller")},
+		Token: m.Ada,
+		Pay:   m.SetConstant("5"),
+		Then:  m.Close,
+	}
+
+	assert.Json(t, contract, `{"from_account":{"address":"addr1_buyer"},"to":{"party":{"address":"addr1_seller"}},"token":{"currency_symbol":"","token_name":""},"pay":5,"then":"close"}`)
+}
+
+func TestTypes_AddressRoundTrip(t *testing.T) {
+	data, err := json.Marshal(m.Address("addr1_buyer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var address m.Address
+	if err := json.Unmarshal(data, &address); err != nil {
+		t.Fatal(err)
+	}
+	if address != "addr1_buyer" {
+		t.Errorf("got %q, want %q", address, "addr1_buyer")
+	}
+
+	var role m.Role
+	if err := json.Unmarshal(data, &role); err == nil {
+		t.Errorf("address %s decoded as role %v", data, role)
+	}
+	if err := json.Unmarshal([]byte(`{"role_token":"buyer"}`), &address); err == nil {
+		t.Error("role decoded as address")
+	}
 }
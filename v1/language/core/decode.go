@@ -0,0 +1,166 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// UnmarshalOptions configures the decoding of Marlowe JSON.
+type UnmarshalOptions struct {
+	// Strict rejects any key that is not part of the construct it appears in,
+	// e.g. a Deposit with "to_account" in place of "into_account". By default,
+	// as with encoding/json, unknown keys are ignored.
+	Strict bool
+}
+
+// Unmarshal decodes the JSON into v, first checking its keys if the options
+// are Strict.
+func (o UnmarshalOptions) Unmarshal(data []byte, v any) error {
+	if o.Strict {
+		if err := checkKnownKeys(data); err != nil {
+			return err
+		}
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// The keys of each construct that marshals to a JSON object, in the order in
+// which ties between constructs sharing keys are resolved.
+var constructKeys = []struct {
+	name string
+	keys []string
+}{
+	{"When", []string{"when", "timeout", "timeout_continuation"}},
+	{"Pay", []string{"from_account", "to", "token", "pay", "then"}},
+	{"If", []string{"if", "then", "else"}},
+	{"Let", []string{"let", "be", "then"}},
+	{"Assert", []string{"assert", "then"}},
+	{"Case", []string{"case", "then"}},
+	{"MerkleizedCase", []string{"case", "merkleized_then"}},
+	{"TransactionInput", []string{"tx_interval", "tx_inputs"}},
+	{"IDeposit", []string{"input_from_party", "that_deposits", "of_token", "into_account"}},
+	{"IChoice", []string{"for_choice_id", "input_that_chooses_num"}},
+	{"MerkleizedInput", []string{"input_from_party", "that_deposits", "of_token", "into_account", "continuation_hash", "merkleized_continuation"}},
+	{"MerkleizedInput", []string{"for_choice_id", "input_that_chooses_num", "continuation_hash", "merkleized_continuation"}},
+	{"MerkleizedInput", []string{"continuation_hash", "merkleized_continuation"}},
+	{"Payment", []string{"payment_from", "to", "token", "amount"}},
+	{"Deposit", []string{"into_account", "party", "of_token", "deposits"}},
+	{"Choice", []string{"for_choice", "choose_between"}},
+	{"Notify", []string{"notify_if"}},
+	{"Bound", []string{"from", "to"}},
+	{"ChoiceId", []string{"choice_name", "choice_owner"}},
+	{"Token", []string{"currency_symbol", "token_name"}},
+	{"Role", []string{"role_token"}},
+	{"Address", []string{"address"}},
+	{"Payee", []string{"party"}},
+	{"Payee", []string{"account"}},
+	{"AvailableMoney", []string{"amount_of_token", "in_account"}},
+	{"ChoiceValue", []string{"value_of_choice"}},
+	{"UseValue", []string{"use_value"}},
+	{"NegValue", []string{"negate"}},
+	{"AddValue", []string{"add", "and"}},
+	{"SubValue", []string{"minus", "value"}},
+	{"MulValue", []string{"multiply", "times"}},
+	{"DivValue", []string{"divide", "by"}},
+	{"AndObs", []string{"both", "and"}},
+	{"OrObs", []string{"either", "or"}},
+	{"NotObs", []string{"not"}},
+	{"ChoseSomething", []string{"chose_something_for"}},
+	{"ValueGE", []string{"value", "ge_than"}},
+	{"ValueGT", []string{"value", "gt"}},
+	{"ValueLT", []string{"value", "lt"}},
+	{"ValueLE", []string{"value", "le_than"}},
+	{"ValueEQ", []string{"value", "equal_to"}},
+}
+
+// Check that every JSON object is made of the keys of a single construct. An
+// object is taken to be the construct it shares the most keys with; objects
+// that share no keys with any construct are left for the decoder to reject.
+func checkKnownKeys(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+
+	var check func(v any) error
+	check = func(v any) error {
+		switch v := v.(type) {
+		case []any:
+			for _, elem := range v {
+				if err := check(elem); err != nil {
+					return err
+				}
+			}
+		case map[string]any:
+			if err := checkObjectKeys(v); err != nil {
+				return err
+			}
+			for _, elem := range v {
+				if err := check(elem); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	return check(v)
+}
+
+func checkObjectKeys(object map[string]any) error {
+	best, bestCount := -1, 0
+	for i, construct := range constructKeys {
+		count := 0
+		for _, key := range construct.keys {
+			if _, ok := object[key]; ok {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+
+	if best < 0 {
+		return nil
+	}
+
+	construct := constructKeys[best]
+	var unknown []string
+	for key := range object {
+		known := false
+		for _, k := range construct.keys {
+			known = known || k == key
+		}
+		if !known {
+			unknown = append(unknown, key)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown key %q in %v", unknown[0], construct.name)
+	}
+
+	return nil
+}
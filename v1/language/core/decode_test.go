@@ -0,0 +1,82 @@
+package language_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestUnmarshalOptions_Strict(t *testing.T) {
+	deposit := []byte(`{"to_account":{"role_token":"seller"},"party":{"role_token":"buyer"},"of_token":{"currency_symbol":"","token_name":""},"deposits":50000000}`)
+
+	var v map[string]json.RawMessage
+	if err := (m.UnmarshalOptions{}).Unmarshal(deposit, &v); err != nil {
+		t.Fatalf("Expected unknown keys to be ignored by default, got: %v", err)
+	}
+
+	err := m.UnmarshalOptions{Strict: true}.Unmarshal(deposit, &v)
+	if err == nil || !strings.Contains(err.Error(), `"to_account"`) || !strings.Contains(err.Error(), "Deposit") {
+		t.Errorf("Expected an error naming the misspelled key, got: %v", err)
+	}
+}
+
+func TestUnmarshalOptions_StrictAcceptsMarshalled(t *testing.T) {
+	data, err := json.Marshal(setupEscrowContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v any
+	if err := (m.UnmarshalOptions{Strict: true}).Unmarshal(data, &v); err != nil {
+		t.Errorf("Expected the marshalled contract to be accepted, got: %v", err)
+	}
+}
+
+func TestUnmarshalOptions_StrictTransactionInput(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	deposit := m.NewIDeposit(buyer, buyer, m.Ada, big.NewInt(50000000))
+	merkleized := m.MerkleizedInput{Input: deposit, Hash: closeHash, Continuation: m.Close}
+	tx := m.TransactionInput{
+		Interval: m.NewTimeInterval(1666078977926, 1666078987926),
+		Inputs: []m.Input{
+			deposit,
+			m.IChoice{ChoiceId: m.ChoiceId{Name: "approve", Owner: buyer}, ChosenNum: m.SetChosenNum("1")},
+			m.INotify{},
+			merkleized,
+			m.MerkleizedInput{Input: m.INotify{}, Hash: closeHash, Continuation: m.Close},
+		},
+	}
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded m.TransactionInput
+	if err := (m.UnmarshalOptions{Strict: true}).Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected the marshalled transaction to be accepted, got: %v", err)
+	}
+	if again, err := json.Marshal(decoded); err != nil || string(again) != string(data) {
+		t.Errorf("Expected %s after the round trip, got: %s %v", data, again, err)
+	}
+
+	// The merkleized input and a payment on their own.
+	payment := m.Payment{PaymentFromAccount: buyer, To: m.Payee{Party: buyer}, Token: m.Ada, Amount: big.NewInt(1)}
+	for _, v := range []any{merkleized, payment} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded any
+		if err := (m.UnmarshalOptions{Strict: true}).Unmarshal(data, &decoded); err != nil {
+			t.Errorf("Expected %s to be accepted, got: %v", data, err)
+		}
+	}
+	var input m.TransactionInput
+	data = []byte(`{"tx_interval":{"from":0,"to":1},"tx_inputs":[{"continuation_hash":"00","merkleized_continuation":"close","extra":1}]}`)
+	if err := (m.UnmarshalOptions{Strict: true}).Unmarshal(data, &input); err == nil {
+		t.Error("Expected an unknown key in a merkleized input to be rejected")
+	}
+}
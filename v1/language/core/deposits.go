@@ -0,0 +1,52 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+// NoOpDeposits reports every Deposit whose funds are never paid out by the
+// contract: no Pay on any path following the deposit draws on the account and
+// token it deposits into, so the funds can only be refunded when the contract
+// closes. Deposits are reported in the order they appear in the contract.
+func NoOpDeposits(c Contract) []Deposit {
+	var deposits []Deposit
+
+	inspect(c, func(node any) {
+		when, ok := node.(When)
+		if !ok {
+			return
+		}
+
+		for _, cs := range when.Cases {
+			deposit, ok := cs.Action.(Deposit)
+			if ok && !paysFrom(cs.Then, Account{AccountId: deposit.IntoAccount, Token: deposit.Token}) {
+				deposits = append(deposits, deposit)
+			}
+		}
+	})
+
+	return deposits
+}
+
+// Report whether any Pay in the contract draws on the account.
+func paysFrom(c Contract, account Account) bool {
+	found := false
+
+	inspect(c, func(node any) {
+		if pay, ok := node.(Pay); ok && pay.From == account.AccountId && pay.Token == account.Token {
+			found = true
+		}
+	})
+
+	return found
+}
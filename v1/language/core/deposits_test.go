@@ -0,0 +1,28 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestNoOpDeposits_Refunded(t *testing.T) {
+	deposit := m.Deposit{
+		IntoAccount: m.Role{Name: "buyer"},
+		Party:       m.Role{Name: "buyer"},
+		Token:       m.Ada,
+		Deposits:    m.SetConstant("50000000"),
+	}
+	contract := setupWhenContract(deposit)
+
+	deposits := m.NoOpDeposits(contract)
+	if len(deposits) != 1 || deposits[0].IntoAccount != deposit.IntoAccount {
+		t.Errorf("Expected the deposit to be flagged, got: %v", deposits)
+	}
+}
+
+func TestNoOpDeposits_PaidOut(t *testing.T) {
+	if deposits := m.NoOpDeposits(setupEscrowContract()); len(deposits) != 0 {
+		t.Errorf("Expected no flagged deposits, got: %v", deposits)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChangeKind classifies the differences Diff reports.
+type ChangeKind int
+
+const (
+	// NodeAdded marks a node present only in the second tree, such as an
+	// extra case of a When.
+	NodeAdded ChangeKind = iota
+	// NodeRemoved marks a node present only in the first tree.
+	NodeRemoved
+	// FieldChanged marks a field that holds a different value, or a node of a
+	// different kind, in the two trees.
+	FieldChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case NodeAdded:
+		return "added"
+	case NodeRemoved:
+		return "removed"
+	case FieldChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A Change is a difference between two contracts at Path. Old is the node or
+// field value in the first contract and New the one in the second; Old is nil
+// for an added node and New for a removed one. Fields are named by their JSON
+// keys, so the Path of a changed deposit amount reads, for example,
+// "when[0].case.deposits".
+type Change struct {
+	Path Path
+	Kind ChangeKind
+	Old  any
+	New  any
+}
+
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "$"
+	}
+
+	switch c.Kind {
+	case NodeAdded:
+		return fmt.Sprintf("%v: added %v", path, describe(c.New))
+	case NodeRemoved:
+		return fmt.Sprintf("%v: removed %v", path, describe(c.Old))
+	}
+	return fmt.Sprintf("%v: changed from %v to %v", path, describe(c.Old), describe(c.New))
+}
+
+// Diff compares two contracts structurally and reports their differences in
+// the order of the trees, as Equal compares them: numeric constants compare
+// by value, and fields are compared by name, whatever their order in the
+// JSON the contracts were read from. Cases are matched by position, so a case
+// inserted in the middle of a When is reported as changes to the cases after
+// it and an added last case. Diff reports no changes exactly when Equal holds.
+func Diff(a, b Contract) []Change {
+	var d differ
+	d.diff("", a, b)
+	return d.changes
+}
+
+// DiffStates compares two states as Diff compares contracts, matching the
+// entries of their maps by key regardless of order. Chosen numbers and bound
+// values compare by value, and a nil map equals an empty one.
+func DiffStates(a, b State) []Change {
+	var d differ
+	d.diff("", a, b)
+	return d.changes
+}
+
+// differ walks two trees in lockstep, collecting the changes between them.
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(path Path, kind ChangeKind, old, new any) {
+	d.changes = append(d.changes, Change{Path: path, Kind: kind, Old: old, New: new})
+}
+
+func (d *differ) diff(path Path, a, b any) {
+	switch {
+	case isNil(a) && isNil(b):
+		return
+	case isNil(a):
+		d.add(path, NodeAdded, nil, b)
+		return
+	case isNil(b):
+		d.add(path, NodeRemoved, a, nil)
+		return
+	case reflect.TypeOf(a) != reflect.TypeOf(b):
+		d.add(path, FieldChanged, a, b)
+		return
+	}
+
+	switch a := a.(type) {
+	case Constant:
+		if a.Int().Cmp(b.(Constant).Int()) != 0 {
+			d.add(path, FieldChanged, a, b)
+		}
+		return
+	case ChosenNum:
+		if a.Int().Cmp(b.(ChosenNum).Int()) != 0 {
+			d.add(path, FieldChanged, a, b)
+		}
+		return
+	case *big.Int:
+		if a.Cmp(b.(*big.Int)) != 0 {
+			d.add(path, FieldChanged, a, b)
+		}
+		return
+	case Case:
+		b := b.(Case)
+		d.diff(joinPath(path, "case"), a.Action, b.Action)
+		d.diff(joinPath(path, "then"), a.Then, b.Then)
+		d.diff(joinPath(path, "merkleized_then"), a.MerkleizedThen, b.MerkleizedThen)
+		return
+	}
+
+	x, y := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch x.Kind() {
+	case reflect.Struct:
+		if !exported(x.Type()) {
+			break
+		}
+		for i := 0; i < x.NumField(); i++ {
+			key := fieldKey(x.Type().Field(i))
+			d.diff(joinPath(path, key), x.Field(i).Interface(), y.Field(i).Interface())
+		}
+		return
+	case reflect.Slice:
+		// Slices are keyed by their field, as in "when[0]", rather than
+		// nested under it.
+		for i := 0; i < x.Len() || i < y.Len(); i++ {
+			elem := fmt.Sprintf("%v[%d]", path, i)
+			switch {
+			case i >= y.Len():
+				d.add(elem, NodeRemoved, x.Index(i).Interface(), nil)
+			case i >= x.Len():
+				d.add(elem, NodeAdded, nil, y.Index(i).Interface())
+			default:
+				d.diff(elem, x.Index(i).Interface(), y.Index(i).Interface())
+			}
+		}
+		return
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, k := range append(x.MapKeys(), y.MapKeys()...) {
+			keys[describe(k.Interface())] = k
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			elem := fmt.Sprintf("%v[%v]", path, name)
+			u, v := x.MapIndex(keys[name]), y.MapIndex(keys[name])
+			switch {
+			case !v.IsValid():
+				d.add(elem, NodeRemoved, u.Interface(), nil)
+			case !u.IsValid():
+				d.add(elem, NodeAdded, nil, v.Interface())
+			default:
+				d.diff(elem, u.Interface(), v.Interface())
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		d.add(path, FieldChanged, a, b)
+	}
+}
+
+// fieldKey names a struct field in a Path by its JSON key, or by its Go name
+// in lower camel case when it has none.
+func fieldKey(f reflect.StructField) string {
+	if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		return tag
+	}
+	r, size := utf8.DecodeRuneInString(f.Name)
+	return string(unicode.ToLower(r)) + f.Name[size:]
+}
+
+// exported reports whether all the fields of a struct type are exported, so
+// that Diff can descend into them.
+func exported(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return r.IsNil()
+	}
+	return false
+}
+
+// describe renders a node or field value for a Change, as JSON where it has a
+// JSON form.
+func describe(v any) string {
+	if v == nil {
+		return "nothing"
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,98 @@
+package language_test
+
+import (
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestDiff(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	deposit := func(amount m.Constant) m.Case {
+		return m.Case{
+			Action: m.Deposit{IntoAccount: seller, Party: buyer, Token: m.Ada, Deposits: amount},
+			Then:   m.Close,
+		}
+	}
+	notify := m.Case{Action: m.Notify{If: m.TrueObs}, Then: m.Close}
+
+	original := m.When{
+		Cases:   []m.Case{deposit(m.SetConstant("100")), notify},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	if changes := m.Diff(original, m.When{
+		Cases:   []m.Case{deposit(m.NewConstant(big.NewInt(100))), notify},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}); len(changes) != 0 {
+		t.Errorf("Expected equal constants to compare by value, got: %v", changes)
+	}
+
+	changed := m.When{
+		Cases:   []m.Case{deposit(m.SetConstant("200"))},
+		Timeout: m.POSIXTime(1666078977926),
+		Then: m.Pay{
+			From: seller, To: m.Payee{Party: buyer}, Token: m.Ada, Pay: m.SetConstant("1"), Then: m.Close,
+		},
+	}
+
+	want := []struct {
+		path string
+		kind m.ChangeKind
+	}{
+		{"when[0].case.deposits", m.FieldChanged},
+		{"when[1]", m.NodeRemoved},
+		{"timeout_continuation", m.FieldChanged},
+	}
+
+	changes := m.Diff(original, changed)
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %v changes, got: %v", len(want), changes)
+	}
+	for i, change := range changes {
+		if change.Path != want[i].path || change.Kind != want[i].kind {
+			t.Errorf("Expected %v at %q, got: %v", want[i].kind, want[i].path, change)
+		}
+	}
+	if s := changes[0].String(); s != "when[0].case.deposits: changed from 100 to 200" {
+		t.Errorf("Unexpected description of the change: %v", s)
+	}
+}
+
+func TestDiffStates(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	choice := m.ChoiceId{Name: "price", Owner: buyer}
+
+	a := m.State{
+		Accounts:    m.Accounts{{AccountId: buyer, Token: m.Ada}: 10},
+		Choices:     map[m.ChoiceId]m.ChosenNum{choice: m.NewChosenNum(big.NewInt(5))},
+		BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(1), "y": big.NewInt(2)},
+	}
+	b := m.State{
+		Accounts:    m.Accounts{{AccountId: buyer, Token: m.Ada}: 10},
+		Choices:     map[m.ChoiceId]m.ChosenNum{choice: m.NewChosenNum(big.NewInt(5))},
+		BoundValues: map[m.ValueId]*big.Int{"y": big.NewInt(2), "x": big.NewInt(1)},
+	}
+	if changes := m.DiffStates(a, b); len(changes) != 0 {
+		t.Errorf("Expected equal states to have no changes, got: %v", changes)
+	}
+
+	b.BoundValues = map[m.ValueId]*big.Int{"y": big.NewInt(3), "z": big.NewInt(1)}
+	changes := m.DiffStates(a, b)
+	want := []string{
+		`boundValues["x"]: removed 1`,
+		`boundValues["y"]: changed from 2 to 3`,
+		`boundValues["z"]: added 1`,
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %v changes, got: %v", len(want), changes)
+	}
+	for i, change := range changes {
+		if change.String() != want[i] {
+			t.Errorf("Expected %v, got: %v", want[i], change)
+		}
+	}
+}
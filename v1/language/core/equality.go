@@ -0,0 +1,185 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "reflect"
+
+// Equal compares two contracts structurally. Constants are compared by their
+// numeric value, so SetConstant("1") equals a Constant built from big.NewInt(1)
+// whatever their internal representation. Tokens are compared by value, so Ada
+// equals an explicit Token{Symbol: "", Name: ""}, but not a token with only
+// one of the two empty.
+func Equal(a, b Contract) bool {
+	return comparer{}.contract(a, b)
+}
+
+// EqualValues compares two values structurally as Equal does.
+func EqualValues(a, b Value) bool {
+	return comparer{}.value(a, b)
+}
+
+// EqualObservations compares two observations structurally as Equal does.
+func EqualObservations(a, b Observation) bool {
+	return comparer{}.value(a, b)
+}
+
+// EqualStates compares two states, regardless of the order of their maps.
+// Chosen numbers and bound values are compared by numeric value, and a nil
+// map equals an empty one.
+func EqualStates(a, b State) bool {
+	if a.MinTime != b.MinTime || len(a.Accounts) != len(b.Accounts) ||
+		len(a.Choices) != len(b.Choices) || len(a.BoundValues) != len(b.BoundValues) {
+		return false
+	}
+	for account, balance := range a.Accounts {
+		if other, ok := b.Accounts[account]; !ok || other != balance {
+			return false
+		}
+	}
+	for id, num := range a.Choices {
+		other, ok := b.Choices[id]
+		if !ok || num.Int().Cmp(other.Int()) != 0 {
+			return false
+		}
+	}
+	for id, value := range a.BoundValues {
+		other, ok := b.BoundValues[id]
+		if !ok || (value == nil) != (other == nil) || (value != nil && value.Cmp(other) != 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualIgnoringTimeouts compares two contracts structurally while treating
+// all When timeouts as equal. An Extended template and its instantiation only
+// differ in their resolved timeouts, so this confirms that instantiation did
+// not alter the structure of the contract.
+func EqualIgnoringTimeouts(a, b Contract) bool {
+	return comparer{ignoreTimeouts: true}.contract(a, b)
+}
+
+// comparer walks two contract trees in lockstep, comparing numeric constants
+// by value rather than by their big.Int representation.
+type comparer struct {
+	ignoreTimeouts bool
+}
+
+func (cmp comparer) contract(a, b Contract) bool {
+	switch a := a.(type) {
+	case Pay:
+		b, ok := b.(Pay)
+		return ok && a.From == b.From && a.To == b.To && a.Token == b.Token &&
+			cmp.value(a.Pay, b.Pay) && cmp.contract(a.Then, b.Then)
+	case If:
+		b, ok := b.(If)
+		return ok && cmp.value(a.Observe, b.Observe) &&
+			cmp.contract(a.Then, b.Then) && cmp.contract(a.Else, b.Else)
+	case When:
+		b, ok := b.(When)
+		if !ok || len(a.Cases) != len(b.Cases) {
+			return false
+		}
+		for i := range a.Cases {
+			if !cmp.action(a.Cases[i].Action, b.Cases[i].Action) ||
+				a.Cases[i].MerkleizedThen != b.Cases[i].MerkleizedThen ||
+				!cmp.contract(a.Cases[i].Then, b.Cases[i].Then) {
+				return false
+			}
+		}
+		if !cmp.ignoreTimeouts && !reflect.DeepEqual(a.Timeout, b.Timeout) {
+			return false
+		}
+		return cmp.contract(a.Then, b.Then)
+	case Let:
+		b, ok := b.(Let)
+		return ok && a.Name == b.Name && cmp.value(a.Value, b.Value) && cmp.contract(a.Then, b.Then)
+	case Assert:
+		b, ok := b.(Assert)
+		return ok && cmp.value(a.Observe, b.Observe) && cmp.contract(a.Then, b.Then)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func (cmp comparer) action(a, b Action) bool {
+	switch a := a.(type) {
+	case Deposit:
+		b, ok := b.(Deposit)
+		return ok && a.IntoAccount == b.IntoAccount && a.Party == b.Party &&
+			a.Token == b.Token && cmp.value(a.Deposits, b.Deposits)
+	case Choice:
+		b, ok := b.(Choice)
+		return ok && a.ChoiceId == b.ChoiceId && reflect.DeepEqual(a.Bounds, b.Bounds)
+	case Notify:
+		b, ok := b.(Notify)
+		return ok && cmp.value(a.If, b.If)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func (cmp comparer) value(a, b Value) bool {
+	switch a := a.(type) {
+	case Constant:
+		b, ok := b.(Constant)
+		return ok && a.Int().Cmp(b.Int()) == 0
+	case NegValue:
+		b, ok := b.(NegValue)
+		return ok && cmp.value(a.Neg, b.Neg)
+	case AddValue:
+		b, ok := b.(AddValue)
+		return ok && cmp.value(a.Add, b.Add) && cmp.value(a.To, b.To)
+	case SubValue:
+		b, ok := b.(SubValue)
+		return ok && cmp.value(a.Subtract, b.Subtract) && cmp.value(a.From, b.From)
+	case MulValue:
+		b, ok := b.(MulValue)
+		return ok && cmp.value(a.Multiply, b.Multiply) && cmp.value(a.By, b.By)
+	case DivValue:
+		b, ok := b.(DivValue)
+		return ok && cmp.value(a.Divide, b.Divide) && cmp.value(a.By, b.By)
+	case Cond:
+		b, ok := b.(Cond)
+		return ok && cmp.value(a.Observation, b.Observation) &&
+			cmp.value(a.IfTrue, b.IfTrue) && cmp.value(a.IfFalse, b.IfFalse)
+	case AndObs:
+		b, ok := b.(AndObs)
+		return ok && cmp.value(a.Both, b.Both) && cmp.value(a.And, b.And)
+	case OrObs:
+		b, ok := b.(OrObs)
+		return ok && cmp.value(a.Either, b.Either) && cmp.value(a.Or, b.Or)
+	case NotObs:
+		b, ok := b.(NotObs)
+		return ok && cmp.value(a.Not, b.Not)
+	case ValueGE:
+		b, ok := b.(ValueGE)
+		return ok && cmp.value(a.Value, b.Value) && cmp.value(a.Ge, b.Ge)
+	case ValueGT:
+		b, ok := b.(ValueGT)
+		return ok && cmp.value(a.Value, b.Value) && cmp.value(a.Gt, b.Gt)
+	case ValueLT:
+		b, ok := b.(ValueLT)
+		return ok && cmp.value(a.Value, b.Value) && cmp.value(a.Lt, b.Lt)
+	case ValueLE:
+		b, ok := b.(ValueLE)
+		return ok && cmp.value(a.Value, b.Value) && cmp.value(a.Le, b.Le)
+	case ValueEQ:
+		b, ok := b.(ValueEQ)
+		return ok && cmp.value(a.Value, b.Value) && cmp.value(a.Eq, b.Eq)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,121 @@
+package language_test
+
+import (
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestEqualIgnoringTimeouts(t *testing.T) {
+	a := m.When{
+		Cases:   []m.Case{{Action: m.Notify{If: m.TrueObs}, Then: m.Close}},
+		Timeout: m.POSIXTime(1000),
+		Then:    m.Close,
+	}
+	b := a
+	b.Timeout = m.POSIXTime(2000)
+
+	if !m.EqualIgnoringTimeouts(a, b) {
+		t.Error("Contracts differing only in timeout should be equal")
+	}
+}
+
+func TestEqualIgnoringTimeouts_StructuralDifference(t *testing.T) {
+	a := setupLetContract(m.SetConstant("1"))
+	b := setupLetContract(m.SetConstant("2"))
+
+	if m.EqualIgnoringTimeouts(a, b) {
+		t.Error("Contracts with different constants should not be equal")
+	}
+
+	if !m.EqualIgnoringTimeouts(a, setupLetContract(m.SetConstant("1"))) {
+		t.Error("Contracts with equal constants should be equal")
+	}
+}
+
+func TestEqualIgnoringTimeouts_AdaNormalization(t *testing.T) {
+	pay := func(token m.Token) m.Contract {
+		return m.Pay{
+			From:  m.Role{Name: "buyer"},
+			To:    m.Payee{Party: m.Role{Name: "seller"}},
+			Token: token,
+			Pay:   m.AvailableMoney{Amount: token, Account: m.Role{Name: "buyer"}},
+			Then:  m.Close,
+		}
+	}
+
+	literal := m.Token{Symbol: "", Name: ""}
+	if !m.EqualIgnoringTimeouts(pay(m.Ada), pay(literal)) {
+		t.Error("Contracts differing only in how ADA was constructed should be equal")
+	}
+	if literal.String() != "ADA" {
+		t.Errorf("Expected a literal empty token to be ADA, got: %v", literal)
+	}
+
+	evaluator := m.NewEvaluator(m.Environment{}, m.State{
+		Accounts: m.Accounts{{AccountId: m.Role{Name: "buyer"}, Token: m.Ada}: 10},
+	})
+	value := m.AvailableMoney{Amount: literal, Account: m.Role{Name: "buyer"}}
+	if got := evaluator.EvalValue(value); got.Int64() != 10 {
+		t.Errorf("Expected the ADA balance for a literal empty token, got: %v", got)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := setupEscrowContract()
+	if !m.Equal(a, setupEscrowContract()) {
+		t.Error("Expected identical contracts to be equal")
+	}
+
+	b := a.(m.When)
+	b.Timeout = m.POSIXTime(1)
+	if m.Equal(a, b) {
+		t.Error("Expected contracts with different timeouts to differ")
+	}
+
+	// The same number, built so that the big.Int representations differ.
+	zero := m.NewConstant(new(big.Int).Sub(big.NewInt(1), big.NewInt(1)))
+	if !m.EqualValues(zero, m.SetConstant("0")) {
+		t.Error("Expected constants to be compared by value")
+	}
+	if m.EqualValues(m.SetConstant("1"), m.SetConstant("2")) {
+		t.Error("Expected different constants to differ")
+	}
+
+	gt := m.ValueGT{Value: m.SetConstant("1"), Gt: zero}
+	if !m.EqualObservations(gt, m.ValueGT{Value: m.SetConstant("1"), Gt: m.SetConstant("0")}) {
+		t.Error("Expected equal observations to be equal")
+	}
+	if m.EqualObservations(gt, m.ValueGE{Value: m.SetConstant("1"), Ge: zero}) {
+		t.Error("Expected different observations to differ")
+	}
+}
+
+func TestEqualStates(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	id := m.ChoiceId{Name: "approve", Owner: buyer}
+
+	a := m.State{
+		Accounts:    m.Accounts{{AccountId: buyer, Token: m.Ada}: 10, {AccountId: seller, Token: m.Ada}: 5},
+		Choices:     map[m.ChoiceId]m.ChosenNum{id: m.NewChosenNum(new(big.Int).Sub(big.NewInt(1), big.NewInt(1)))},
+		BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(3)},
+	}
+	b := m.State{
+		Accounts:    m.Accounts{{AccountId: seller, Token: m.Ada}: 5, {AccountId: buyer, Token: m.Ada}: 10},
+		Choices:     map[m.ChoiceId]m.ChosenNum{id: m.SetChosenNum("0")},
+		BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(3)},
+	}
+	if !m.EqualStates(a, b) {
+		t.Error("Expected states with the same entries to be equal")
+	}
+
+	b.BoundValues = map[m.ValueId]*big.Int{"x": big.NewInt(4)}
+	if m.EqualStates(a, b) {
+		t.Error("Expected states with different bound values to differ")
+	}
+
+	if !m.EqualStates(m.State{}, m.State{Accounts: m.Accounts{}}) {
+		t.Error("Expected a nil map to equal an empty one")
+	}
+}
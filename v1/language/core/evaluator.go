@@ -0,0 +1,367 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// An Evaluator evaluates Values and Observations against a fixed Environment
+// and State, memoizing the result of every sub-value and sub-observation it
+// evaluates. Repeatedly evaluating terms that share structure, such as the
+// observations guarding the actions a UI offers, then reuses earlier work.
+//
+// Results are looked up by a 128-bit key built from the structure of each
+// term, so that equal terms share a result however they were constructed.
+// Each node is hashed once per evaluation, its key being built from the keys
+// of its operands, and a result found under a key is used without comparing
+// the terms. The Evaluator assumes the State is not modified behind its back:
+// call SetState or SetEnvironment to change either, which discards the
+// memoized results.
+type Evaluator struct {
+	env          Environment
+	state        State
+	values       map[key]*big.Int
+	observations map[key]bool
+}
+
+// NewEvaluator returns an Evaluator for the environment and state.
+func NewEvaluator(env Environment, state State) *Evaluator {
+	e := &Evaluator{env: env, state: state}
+	e.reset()
+	return e
+}
+
+// SetEnvironment replaces the environment and invalidates memoized results.
+func (e *Evaluator) SetEnvironment(env Environment) {
+	e.env = env
+	e.reset()
+}
+
+// SetState replaces the state and invalidates memoized results.
+func (e *Evaluator) SetState(state State) {
+	e.state = state
+	e.reset()
+}
+
+func (e *Evaluator) reset() {
+	e.values = make(map[key]*big.Int)
+	e.observations = make(map[key]bool)
+}
+
+// EvalValue evaluates the value as EvalValue would. A new big.Int is returned
+// on every call, so the result may be modified freely.
+func (e *Evaluator) EvalValue(v Value) *big.Int {
+	return new(big.Int).Set(e.value(v))
+}
+
+// EvalObservation evaluates the observation as EvalObservation would.
+func (e *Evaluator) EvalObservation(o Observation) bool {
+	return e.observation(o)
+}
+
+// A term that was evaluated before is found by hashing it alone; otherwise it
+// is evaluated, and its key built, in a single pass.
+func (e *Evaluator) value(v Value) *big.Int {
+	if result, ok := e.values[hashValue(v)]; ok {
+		return result
+	}
+	_, result := e.keyedValue(v)
+	return result
+}
+
+func (e *Evaluator) observation(o Observation) bool {
+	if result, ok := e.observations[hashValue(o)]; ok {
+		return result
+	}
+	_, result := e.keyedObservation(o)
+	return result
+}
+
+// A node that cannot be evaluated is zero, or false, as in EvalValue.
+func (e *Evaluator) unsupported(node any) {}
+
+// Evaluate the value, memoizing the result of every sub-term it evaluates,
+// and return its key along with its result. Operands are evaluated as in
+// evalValue, and the branch a Cond does not take is only hashed.
+func (e *Evaluator) keyedValue(v Value) (key, *big.Int) {
+	switch v := v.(type) {
+	case NegValue:
+		k, n := e.keyedValue(v.Neg)
+		return e.memoValue(nodeKey(tagNegValue, k), func() *big.Int {
+			return new(big.Int).Neg(n)
+		})
+	case AddValue:
+		return e.arithmetic(tagAddValue, v.Add, v.To, func(x, y *big.Int) *big.Int {
+			return new(big.Int).Add(x, y)
+		})
+	case SubValue:
+		return e.arithmetic(tagSubValue, v.Subtract, v.From, func(x, y *big.Int) *big.Int {
+			return new(big.Int).Sub(y, x)
+		})
+	case MulValue:
+		return e.arithmetic(tagMulValue, v.Multiply, v.By, func(x, y *big.Int) *big.Int {
+			return new(big.Int).Mul(x, y)
+		})
+	case DivValue:
+		return e.arithmetic(tagDivValue, v.Divide, v.By, func(x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Quo(x, y)
+		})
+	case Cond:
+		ko, b := e.keyedObservation(v.Observation)
+		var kt, kf key
+		var n *big.Int
+		if b {
+			kt, n = e.keyedValue(v.IfTrue)
+			kf = hashValue(v.IfFalse)
+		} else {
+			kt = hashValue(v.IfTrue)
+			kf, n = e.keyedValue(v.IfFalse)
+		}
+		return e.memoValue(nodeKey(tagCond, ko, kt, kf), func() *big.Int { return n })
+	}
+
+	// The remaining values have no operands.
+	return e.memoValue(hashValue(v), func() *big.Int {
+		return evalValue(e, e.env, e.state, v)
+	})
+}
+
+// Evaluate the observation as keyedValue evaluates a value. AndObs and OrObs
+// short-circuit as in evalObservation, only hashing the operand they skip.
+func (e *Evaluator) keyedObservation(o Observation) (key, bool) {
+	switch o := o.(type) {
+	case AndObs:
+		k1, b := e.keyedObservation(o.Both)
+		var k2 key
+		if b {
+			k2, b = e.keyedObservation(o.And)
+		} else {
+			k2 = hashValue(o.And)
+		}
+		return e.memoObservation(nodeKey(tagAndObs, k1, k2), b)
+	case OrObs:
+		k1, b := e.keyedObservation(o.Either)
+		var k2 key
+		if !b {
+			k2, b = e.keyedObservation(o.Or)
+		} else {
+			k2 = hashValue(o.Or)
+		}
+		return e.memoObservation(nodeKey(tagOrObs, k1, k2), b)
+	case NotObs:
+		k, b := e.keyedObservation(o.Not)
+		return e.memoObservation(nodeKey(tagNotObs, k), !b)
+	case ValueGE:
+		return e.comparison(tagValueGE, o.Value, o.Ge, func(c int) bool { return c >= 0 })
+	case ValueGT:
+		return e.comparison(tagValueGT, o.Value, o.Gt, func(c int) bool { return c > 0 })
+	case ValueLT:
+		return e.comparison(tagValueLT, o.Value, o.Lt, func(c int) bool { return c < 0 })
+	case ValueLE:
+		return e.comparison(tagValueLE, o.Value, o.Le, func(c int) bool { return c <= 0 })
+	case ValueEQ:
+		return e.comparison(tagValueEQ, o.Value, o.Eq, func(c int) bool { return c == 0 })
+	}
+
+	// The remaining observations have no operands.
+	k := hashValue(o)
+	if result, ok := e.observations[k]; ok {
+		return k, result
+	}
+	return e.memoObservation(k, evalObservation(e, e.env, e.state, o))
+}
+
+func (e *Evaluator) arithmetic(tag byte, x, y Value, op func(x, y *big.Int) *big.Int) (key, *big.Int) {
+	kx, nx := e.keyedValue(x)
+	ky, ny := e.keyedValue(y)
+	return e.memoValue(nodeKey(tag, kx, ky), func() *big.Int { return op(nx, ny) })
+}
+
+func (e *Evaluator) comparison(tag byte, x, y Value, cmp func(c int) bool) (key, bool) {
+	kx, nx := e.keyedValue(x)
+	ky, ny := e.keyedValue(y)
+	return e.memoObservation(nodeKey(tag, kx, ky), cmp(nx.Cmp(ny)))
+}
+
+// Return the result memoized under the key, or memoize the one computed.
+func (e *Evaluator) memoValue(k key, compute func() *big.Int) (key, *big.Int) {
+	if result, ok := e.values[k]; ok {
+		return k, result
+	}
+	result := compute()
+	e.values[k] = result
+	return k, result
+}
+
+func (e *Evaluator) memoObservation(k key, result bool) (key, bool) {
+	e.observations[k] = result
+	return k, result
+}
+
+// Node tags for hashValue; each term hashes its tag followed by its fields.
+const (
+	tagAvailableMoney byte = iota + 1
+	tagConstant
+	tagNegValue
+	tagAddValue
+	tagSubValue
+	tagMulValue
+	tagDivValue
+	tagChoiceValue
+	tagTimeIntervalValue
+	tagUseValue
+	tagCond
+	tagAndObs
+	tagOrObs
+	tagNotObs
+	tagChoseSomething
+	tagValueGE
+	tagValueGT
+	tagValueLT
+	tagValueLE
+	tagValueEQ
+	tagBoolObs
+	tagAddress
+	tagRole
+)
+
+// A key identifies a Value or Observation by its structure. It is made of two
+// independently seeded 64-bit lanes, each absorbing a word at a time with a
+// multiply and xorshift, so that distinct terms do not share a key in
+// practice.
+type key struct{ a, b uint64 }
+
+const (
+	seedA uint64 = 0x243f6a8885a308d3
+	seedB uint64 = 0x13198a2e03707344
+	mulA  uint64 = 0x9e3779b97f4a7c15
+	mulB  uint64 = 0xbf58476d1ce4e5b9
+)
+
+func (k key) word(w uint64) key {
+	a := (k.a ^ w) * mulA
+	b := (k.b ^ w) * mulB
+	return key{a ^ a>>29, b ^ b>>32}
+}
+
+func (k key) key(operand key) key {
+	return k.word(operand.a).word(operand.b)
+}
+
+func (k key) string(s string) key {
+	k = k.word(uint64(len(s)))
+	for i := 0; i < len(s); i += 8 {
+		var w uint64
+		for j := i; j < len(s) && j < i+8; j++ {
+			w |= uint64(s[j]) << (8 * (j - i))
+		}
+		k = k.word(w)
+	}
+	return k
+}
+
+func (k key) party(p Party) key {
+	switch p := p.(type) {
+	case Address:
+		return k.word(uint64(tagAddress)).string(string(p))
+	case Role:
+		return k.word(uint64(tagRole)).string(p.Name)
+	}
+	return k.string(fmt.Sprintf("%T%#v", p, p))
+}
+
+func (k key) token(t Token) key {
+	return k.string(t.Symbol).string(t.Name)
+}
+
+func (k key) choiceId(c ChoiceId) key {
+	return k.string(c.Name).party(c.Owner)
+}
+
+// The key of a node with the tag, built from the keys of its operands.
+func nodeKey(tag byte, operands ...key) key {
+	k := key{seedA, seedB}.word(uint64(tag))
+	for _, operand := range operands {
+		k = k.key(operand)
+	}
+	return k
+}
+
+// Hash the structure of a Value or Observation.
+func hashValue(v Value) key {
+	switch v := v.(type) {
+	case AvailableMoney:
+		return nodeKey(tagAvailableMoney).party(v.Account).token(v.Amount)
+	case Constant:
+		num := v.Int()
+		words := num.Bits()
+		k := nodeKey(tagConstant).word(uint64(num.Sign() + 1)).word(uint64(len(words)))
+		for _, w := range words {
+			k = k.word(uint64(w))
+		}
+		return k
+	case NegValue:
+		return nodeKey(tagNegValue, hashValue(v.Neg))
+	case AddValue:
+		return nodeKey(tagAddValue, hashValue(v.Add), hashValue(v.To))
+	case SubValue:
+		return nodeKey(tagSubValue, hashValue(v.Subtract), hashValue(v.From))
+	case MulValue:
+		return nodeKey(tagMulValue, hashValue(v.Multiply), hashValue(v.By))
+	case DivValue:
+		return nodeKey(tagDivValue, hashValue(v.Divide), hashValue(v.By))
+	case ChoiceValue:
+		return nodeKey(tagChoiceValue).choiceId(v.Value)
+	case TimeIntervalValue:
+		return nodeKey(tagTimeIntervalValue).string(string(v))
+	case UseValue:
+		return nodeKey(tagUseValue).string(string(v.Value))
+	case Cond:
+		return nodeKey(tagCond, hashValue(v.Observation), hashValue(v.IfTrue), hashValue(v.IfFalse))
+	case AndObs:
+		return nodeKey(tagAndObs, hashValue(v.Both), hashValue(v.And))
+	case OrObs:
+		return nodeKey(tagOrObs, hashValue(v.Either), hashValue(v.Or))
+	case NotObs:
+		return nodeKey(tagNotObs, hashValue(v.Not))
+	case ChoseSomething:
+		return nodeKey(tagChoseSomething).choiceId(v.Choice)
+	case ValueGE:
+		return nodeKey(tagValueGE, hashValue(v.Value), hashValue(v.Ge))
+	case ValueGT:
+		return nodeKey(tagValueGT, hashValue(v.Value), hashValue(v.Gt))
+	case ValueLT:
+		return nodeKey(tagValueLT, hashValue(v.Value), hashValue(v.Lt))
+	case ValueLE:
+		return nodeKey(tagValueLE, hashValue(v.Value), hashValue(v.Le))
+	case ValueEQ:
+		return nodeKey(tagValueEQ, hashValue(v.Value), hashValue(v.Eq))
+	case BoolObs:
+		b := uint64(0)
+		if v {
+			b = 1
+		}
+		return nodeKey(tagBoolObs).word(b)
+	}
+
+	// Other terms cannot be evaluated, and are zero or false whatever their
+	// fields, so they hash by their type alone.
+	return key{seedA, seedB}.string(fmt.Sprintf("%T", v))
+}
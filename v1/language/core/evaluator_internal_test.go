@@ -0,0 +1,80 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "testing"
+
+func TestEvaluator_KeyedMatchesHash(t *testing.T) {
+	buyer := Role{Name: "buyer"}
+	price := ChoiceValue{Value: ChoiceId{Name: "price", Owner: buyer}}
+	observation := OrObs{
+		Either: ChoseSomething{Choice: ChoiceId{Name: "price", Owner: buyer}},
+		Or:     ValueGT{Value: AvailableMoney{Account: buyer, Amount: Ada}, Gt: SetConstant("100")},
+	}
+	values := []Value{
+		NegValue{Neg: price},
+		SubValue{Subtract: price, From: TimeIntervalEnd},
+		DivValue{Divide: MulValue{Multiply: price, By: SetConstant("3")}, By: UseValue{Value: "x"}},
+		Cond{Observation: observation, IfTrue: price, IfFalse: AddValue{Add: price, To: SetConstant("-1")}},
+	}
+	observations := []Observation{
+		observation,
+		AndObs{Both: BoolObs(false), And: observation},
+		NotObs{Not: ValueLE{Value: price, Le: SetConstant("7")}},
+	}
+
+	// The key built while evaluating a term is the one it is looked up by.
+	e := NewEvaluator(Environment{}, State{})
+	for _, v := range values {
+		if k, _ := e.keyedValue(v); k != hashValue(v) {
+			t.Errorf("%#v: expected key %v, got: %v", v, hashValue(v), k)
+		}
+	}
+	for _, o := range observations {
+		if k, _ := e.keyedObservation(o); k != hashValue(o) {
+			t.Errorf("%#v: expected key %v, got: %v", o, hashValue(o), k)
+		}
+	}
+}
+
+func TestEvaluator_DistinctKeys(t *testing.T) {
+	x, y := UseValue{Value: "x"}, UseValue{Value: "y"}
+	terms := []Value{
+		x,
+		y,
+		AddValue{Add: x, To: y},
+		AddValue{Add: y, To: x},
+		SubValue{Subtract: x, From: y},
+		MulValue{Multiply: x, By: y},
+		SetConstant("1"),
+		SetConstant("-1"),
+		SetConstant("18446744073709551617"),
+		AvailableMoney{Account: Role{Name: "a"}, Amount: Ada},
+		AvailableMoney{Account: Address("a"), Amount: Ada},
+		ChoiceValue{Value: ChoiceId{Name: "ab", Owner: Role{Name: "c"}}},
+		ChoiceValue{Value: ChoiceId{Name: "a", Owner: Role{Name: "bc"}}},
+		BoolObs(true),
+		BoolObs(false),
+	}
+
+	seen := make(map[key]Value)
+	for _, v := range terms {
+		k := hashValue(v)
+		if other, ok := seen[k]; ok {
+			t.Errorf("%#v and %#v share a key", other, v)
+		}
+		seen[k] = v
+	}
+}
@@ -0,0 +1,113 @@
+package language_test
+
+import (
+	"fmt"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func setupEvaluatorState() (m.Environment, m.State) {
+	buyer := m.Role{Name: "buyer"}
+	env := m.Environment{TimeInterval: m.NewTimeInterval(1000, 2000)}
+	state := m.State{
+		Accounts: m.Accounts{{AccountId: buyer, Token: m.Ada}: 50},
+		Choices:  map[m.ChoiceId]m.ChosenNum{{Name: "price", Owner: buyer}: m.SetChosenNum("7")},
+	}
+	return env, state
+}
+
+// Build observations that share a common price sub-value, as the guards of
+// the actions offered by a UI typically do.
+func setupEvaluatorObservations(n int) []m.Observation {
+	buyer := m.Role{Name: "buyer"}
+	price := m.Value(m.ChoiceValue{Value: m.ChoiceId{Name: "price", Owner: buyer}})
+	for i := 0; i < 20; i++ {
+		price = m.AddValue{Add: m.MulValue{Multiply: price, By: m.SetConstant("3")}, To: m.SetConstant("1")}
+	}
+
+	observations := make([]m.Observation, n)
+	for i := range observations {
+		observations[i] = m.AndObs{
+			Both: m.ValueGE{Value: m.AvailableMoney{Account: buyer, Amount: m.Ada}, Ge: price},
+			And:  m.NotObs{Not: m.ValueEQ{Value: price, Eq: m.SetConstant(fmt.Sprint(i))}},
+		}
+	}
+	return observations
+}
+
+func TestEvaluator_MatchesFreshEvaluation(t *testing.T) {
+	env, state := setupEvaluatorState()
+	buyer := m.Role{Name: "buyer"}
+	e := m.NewEvaluator(env, state)
+
+	values := []m.Value{
+		m.AvailableMoney{Account: buyer, Amount: m.Ada},
+		m.AddValue{Add: m.ChoiceValue{Value: m.ChoiceId{Name: "price", Owner: buyer}}, To: m.TimeIntervalStart},
+		m.DivValue{Divide: m.SetConstant("-7"), By: m.SetConstant("2")},
+		m.Cond{Observation: m.ChoseSomething{Choice: m.ChoiceId{Name: "price", Owner: buyer}}, IfTrue: m.SetConstant("1"), IfFalse: m.SetConstant("2")},
+	}
+
+	// Evaluate twice so that the second pass is served from the cache.
+	for pass := 0; pass < 2; pass++ {
+		for _, v := range values {
+			if got, expected := e.EvalValue(v), m.EvalValue(env, state, v); got.Cmp(expected) != 0 {
+				t.Errorf("%#v: expected %v, got: %v", v, expected, got)
+			}
+		}
+		for _, o := range setupEvaluatorObservations(10) {
+			if got, expected := e.EvalObservation(o), m.EvalObservation(env, state, o); got != expected {
+				t.Errorf("%#v: expected %v, got: %v", o, expected, got)
+			}
+		}
+	}
+
+	// Results are copies, so modifying one does not poison the cache.
+	e.EvalValue(values[0]).SetInt64(0)
+	if got := e.EvalValue(values[0]); got.Int64() != 50 {
+		t.Errorf("Expected the cached value to be unaffected, got: %v", got)
+	}
+}
+
+func TestEvaluator_SetStateInvalidates(t *testing.T) {
+	env, state := setupEvaluatorState()
+	buyer := m.Role{Name: "buyer"}
+	money := m.AvailableMoney{Account: buyer, Amount: m.Ada}
+	e := m.NewEvaluator(env, state)
+
+	if got := e.EvalValue(money); got.Int64() != 50 {
+		t.Fatalf("Expected 50, got: %v", got)
+	}
+
+	e.SetState(m.State{Accounts: m.Accounts{{AccountId: buyer, Token: m.Ada}: 10}})
+	if got := e.EvalValue(money); got.Int64() != 10 {
+		t.Errorf("Expected the new state to be used, got: %v", got)
+	}
+
+	e.SetEnvironment(m.Environment{TimeInterval: m.NewTimeInterval(5, 6)})
+	if got := e.EvalValue(m.TimeIntervalStart); got.Int64() != 5 {
+		t.Errorf("Expected the new environment to be used, got: %v", got)
+	}
+}
+
+func BenchmarkEvalObservation(b *testing.B) {
+	env, state := setupEvaluatorState()
+	observations := setupEvaluatorObservations(50)
+
+	b.Run("Fresh", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, o := range observations {
+				m.EvalObservation(env, state, o)
+			}
+		}
+	})
+
+	b.Run("Evaluator", func(b *testing.B) {
+		e := m.NewEvaluator(env, state)
+		for i := 0; i < b.N; i++ {
+			for _, o := range observations {
+				e.EvalObservation(o)
+			}
+		}
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// A FieldNameMap maps the canonical Marlowe JSON keys, such as "into_account",
+// to the spelling used by an alternate backend, such as "in_account". Keys are
+// renamed in every construct that has them; keys that are not in the map keep
+// their canonical spelling, so the zero value produces the standard Marlowe
+// JSON.
+type FieldNameMap map[string]string
+
+// Marshal marshals v to the standard Marlowe JSON and renames its keys.
+func (m FieldNameMap) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return renameKeys(data, func([]string) map[string]string { return m })
+}
+
+// Unmarshal renames the keys of the backend-specific JSON back to their
+// canonical spelling and unmarshals the result into v. Renames are undone
+// only within the construct they belong to: each object is taken to be the
+// construct whose renamed keys it shares the most, as in Strict decoding, so
+// an alternate spelling may equal a canonical key of another construct, e.g.
+// "in_account" for a Deposit leaves the "in_account" of an AvailableMoney
+// alone. An error is returned if two keys of a construct, or two keys of the
+// map, share a spelling, since the canonical key could not then be told apart.
+func (m FieldNameMap) Unmarshal(data []byte, v any) error {
+	canonical, err := m.inverse(nil)
+	if err != nil {
+		return err
+	}
+	constructs := make([]map[string]string, len(constructKeys))
+	for i, construct := range constructKeys {
+		if constructs[i], err = m.inverse(construct.keys); err != nil {
+			return fmt.Errorf("%v: %w", construct.name, err)
+		}
+	}
+
+	data, err = renameKeys(data, func(keys []string) map[string]string {
+		best, bestCount := -1, 0
+		for i := range constructKeys {
+			count := 0
+			for _, key := range keys {
+				if _, ok := constructs[i][key]; ok {
+					count++
+				}
+			}
+			if count > bestCount {
+				best, bestCount = i, count
+			}
+		}
+
+		if best < 0 {
+			return canonical
+		}
+		return constructs[best]
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Map the spelling of each key back to the canonical key: those of the map
+// when keys is nil, or else the given keys of a construct, renamed or not.
+func (m FieldNameMap) inverse(keys []string) (map[string]string, error) {
+	if keys == nil {
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	inverse := make(map[string]string, len(keys))
+	for _, key := range keys {
+		spelling := key
+		if alternate, ok := m[key]; ok {
+			spelling = alternate
+		}
+		if other, ok := inverse[spelling]; ok {
+			return nil, fmt.Errorf("keys %q and %q are both spelled %q", other, key, spelling)
+		}
+		inverse[spelling] = key
+	}
+
+	return inverse, nil
+}
+
+// An object decoded with its keys in order, so that renaming them preserves
+// the layout of the JSON.
+type orderedObject struct {
+	keys   []string
+	values []any
+}
+
+// Rewrite the JSON, renaming the keys of each object by the map that names
+// returns for its keys, while preserving their order.
+func renameKeys(data []byte, names func(keys []string) map[string]string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	v, err := decodeOrdered(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := encodeRenamed(&out, v, names); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func decodeOrdered(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		var object orderedObject
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.keys = append(object.keys, key.(string))
+			object.values = append(object.values, value)
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []any{}
+		for decoder.More() {
+			elem, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, elem)
+		}
+		_, err := decoder.Token()
+		return array, err
+	}
+
+	return token, nil
+}
+
+func encodeRenamed(out *bytes.Buffer, v any, names func(keys []string) map[string]string) error {
+	switch v := v.(type) {
+	case orderedObject:
+		renames := names(v.keys)
+		out.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if name, ok := renames[key]; ok {
+				key = name
+			}
+			encoded, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			out.Write(encoded)
+			out.WriteByte(':')
+			if err := encodeRenamed(out, v.values[i], names); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case []any:
+		out.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := encodeRenamed(out, elem, names); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case string:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+	case json.Number:
+		out.WriteString(v.String())
+	case bool:
+		fmt.Fprint(out, v)
+	case nil:
+		out.WriteString("null")
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package language_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestFieldNameMap_Deposit(t *testing.T) {
+	deposit := m.Deposit{
+		IntoAccount: m.Role{Name: "seller"},
+		Party:       m.Role{Name: "buyer"},
+		Token:       m.Ada,
+		Deposits:    m.SetConstant("50000000"),
+	}
+	names := m.FieldNameMap{"into_account": "in_account", "token_name": "name"}
+
+	got, err := names.Marshal(deposit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"in_account":{"role_token":"seller"},"party":{"role_token":"buyer"},"of_token":{"currency_symbol":"","name":""},"deposits":50000000}`
+	if string(got) != expected {
+		t.Errorf("%v [Expected]", expected)
+		t.Errorf("%v [Got]", string(got))
+	}
+
+	var fields map[string]json.RawMessage
+	if err := names.Unmarshal(got, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["into_account"]; !ok {
+		t.Errorf("Expected the canonical key to be restored, got: %v", fields)
+	}
+
+	var token m.Token
+	if err := names.Unmarshal(fields["of_token"], &token); err != nil || token != m.Ada {
+		t.Errorf("Expected the token to round-trip, got: %v (%v)", token, err)
+	}
+}
+
+func TestFieldNameMap_Default(t *testing.T) {
+	contract := setupEscrowContract()
+
+	canonical, err := json.Marshal(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.FieldNameMap(nil).Marshal(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != string(canonical) {
+		t.Errorf("Expected the standard JSON, got: %v", string(got))
+	}
+}
+
+func TestFieldNameMap_AvailableMoney(t *testing.T) {
+	// "in_account" spells the Deposit's "into_account", but is also the
+	// canonical key of AvailableMoney, which must be left alone.
+	seller := m.Role{Name: "seller"}
+	deposit := m.Deposit{
+		IntoAccount: seller,
+		Party:       m.Role{Name: "buyer"},
+		Token:       m.Ada,
+		Deposits:    m.AvailableMoney{Amount: m.Ada, Account: seller},
+	}
+	names := m.FieldNameMap{"into_account": "in_account"}
+
+	original := m.When{
+		Cases:   []m.Case{{Action: deposit, Then: m.Close}},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	data, err := names.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var canonical json.RawMessage
+	if err := names.Unmarshal(data, &canonical); err != nil {
+		t.Fatal(err)
+	}
+	contract, err := m.UnmarshalContract(canonical)
+	if err != nil {
+		t.Fatalf("%v: %s", err, canonical)
+	}
+	if !m.Equal(contract, original) {
+		t.Errorf("Expected the contract to round-trip, got: %s", canonical)
+	}
+}
+
+func TestFieldNameMap_Ambiguous(t *testing.T) {
+	// The Deposit's "party" could not be told from its renamed "deposits".
+	names := m.FieldNameMap{"deposits": "party"}
+
+	var v any
+	if err := names.Unmarshal([]byte(`{"party":1}`), &v); err == nil {
+		t.Error("Expected an error for a rename onto another key of the construct")
+	}
+
+	names = m.FieldNameMap{"into_account": "account", "in_account": "account"}
+	if err := names.Unmarshal([]byte(`{"account":1}`), &v); err == nil {
+		t.Error("Expected an error for two keys renamed alike")
+	}
+}
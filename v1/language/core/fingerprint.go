@@ -0,0 +1,45 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Fingerprint returns the SHA-256 hash of the JSON encoding of the contract.
+// The encoding is canonical: fields are written in a fixed order, constants
+// in decimal however their big.Int was built, and Extended parameters in
+// their own form, e.g. {"time_param":"deadline"}. Structurally equal
+// contracts, templates included, therefore have the same fingerprint, and
+// any change to the contract, including to a timeout, changes it.
+//
+// Every Core and Extended node can be marshalled, so Fingerprint only panics
+// for a Value of some other package whose MarshalJSON fails.
+func Fingerprint(c Contract) [32]byte {
+	data, err := json.Marshal(c)
+	if err != nil {
+		panic(fmt.Sprintf("fingerprint: %v", err))
+	}
+	return sha256.Sum256(data)
+}
+
+// FingerprintHex returns the Fingerprint of the contract, hex encoded.
+func FingerprintHex(c Contract) string {
+	fingerprint := Fingerprint(c)
+	return hex.EncodeToString(fingerprint[:])
+}
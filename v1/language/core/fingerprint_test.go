@@ -0,0 +1,53 @@
+package language_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// Helper function returning the hex fingerprint of a contract that can be
+// encoded.
+func TestFingerprint(t *testing.T) {
+	if m.FingerprintHex(setupEscrowContract()) != m.FingerprintHex(setupEscrowContract()) {
+		t.Error("Expected equal contracts to have equal fingerprints")
+	}
+
+	// The same constant, built so that the big.Int representations differ.
+	zero := m.NewConstant(new(big.Int).Sub(big.NewInt(1), big.NewInt(1)))
+	a := m.Let{Name: "x", Value: zero, Then: m.Close}
+	b := m.Let{Name: "x", Value: m.SetConstant("0"), Then: m.Close}
+	if m.FingerprintHex(a) != m.FingerprintHex(b) {
+		t.Error("Expected constants to be fingerprinted by value")
+	}
+	if m.FingerprintHex(m.Let{Name: "x", Value: m.Constant{}, Then: m.Close}) != m.FingerprintHex(b) {
+		t.Error("Expected the zero Constant to be fingerprinted as 0")
+	}
+
+	changed := setupEscrowContract().(m.When)
+	changed.Timeout = m.POSIXTime(1)
+	different := []m.Contract{
+		m.Close,
+		changed,
+		m.Let{Name: "x", Value: m.SetConstant("1"), Then: m.Close},
+		m.Let{Name: "y", Value: m.SetConstant("0"), Then: m.Close},
+		m.Assert{Observe: m.TrueObs, Then: m.Close},
+	}
+	seen := map[string]bool{m.FingerprintHex(setupEscrowContract()): true, m.FingerprintHex(a): true}
+	for _, c := range different {
+		hash := m.FingerprintHex(c)
+		if seen[hash] {
+			t.Errorf("Expected a distinct fingerprint for %v", c)
+		}
+		seen[hash] = true
+	}
+
+	// Close is encoded as "close".
+	close := sha256.Sum256([]byte(`"close"`))
+	if expected := hex.EncodeToString(close[:]); m.FingerprintHex(m.Close) != expected {
+		t.Errorf("Expected %v, got: %v", expected, m.FingerprintHex(m.Close))
+	}
+}
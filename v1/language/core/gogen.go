@@ -0,0 +1,212 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GenerateGo returns the Go expression that constructs the contract, as a
+// gofmt-clean composite literal written against this package imported as m,
+// e.g. m.When{Cases: []m.Case{...}, Timeout: m.POSIXTime(1), Then: m.Close}.
+//
+// Fields holding their zero value are omitted. Tokens are the exception, so
+// that Ada is spelled out rather than left implicit. Constants are built with
+// SetConstant. The named constants Close, Ada, TrueObs, FalseObs,
+// TimeIntervalStart and TimeIntervalEnd are used where they apply.
+func GenerateGo(c Contract) (string, error) {
+	var g goGenerator
+	if err := g.value(reflect.ValueOf(&c).Elem(), contractType, false); err != nil {
+		return "", err
+	}
+
+	src, err := format.Source([]byte(g.String()))
+	if err != nil {
+		return "", fmt.Errorf("formatting generated Go: %w", err)
+	}
+	return string(src), nil
+}
+
+var (
+	contractType = reflect.TypeOf((*Contract)(nil)).Elem()
+	packagePath  = contractType.PkgPath()
+)
+
+// The longest list of elements that GenerateGo writes on a single line.
+const maxInlineLiteral = 60
+
+type goGenerator struct {
+	strings.Builder
+}
+
+// Write the Go expression for v, whose declared type is static. The type of
+// a struct literal is elided when it is an element of a slice literal.
+func (g *goGenerator) value(v reflect.Value, static reflect.Type, elided bool) error {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			g.WriteString("nil")
+			return nil
+		}
+		return g.value(v.Elem(), static, false)
+	}
+
+	switch x := v.Interface().(type) {
+	case Constant:
+		fmt.Fprintf(g, "m.SetConstant(%q)", x.Int().String())
+		return nil
+	case CloseContract:
+		if x == Close {
+			g.WriteString("m.Close")
+			return nil
+		}
+	case BoolObs:
+		if x {
+			g.WriteString("m.TrueObs")
+		} else {
+			g.WriteString("m.FalseObs")
+		}
+		return nil
+	case TimeIntervalValue:
+		switch x {
+		case TimeIntervalStart:
+			g.WriteString("m.TimeIntervalStart")
+			return nil
+		case TimeIntervalEnd:
+			g.WriteString("m.TimeIntervalEnd")
+			return nil
+		}
+	case Token:
+		if x == Ada {
+			g.WriteString("m.Ada")
+			return nil
+		}
+	}
+
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Struct:
+		if !elided {
+			name, err := goTypeName(t)
+			if err != nil {
+				return err
+			}
+			g.WriteString(name)
+		}
+		var fields []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				return fmt.Errorf("cannot generate Go for %v: unexported field %v", t, field.Name)
+			}
+			// Spell out Ada rather than leave the token implicit.
+			if v.Field(i).IsZero() && field.Type != reflect.TypeOf(Ada) {
+				continue
+			}
+			var f goGenerator
+			if err := f.value(v.Field(i), field.Type, false); err != nil {
+				return err
+			}
+			fields = append(fields, field.Name+": "+f.String())
+		}
+		g.writeList(fields)
+	case reflect.Slice:
+		if v.IsNil() {
+			g.WriteString("nil")
+			return nil
+		}
+		name, err := goTypeName(t)
+		if err != nil {
+			return err
+		}
+		g.WriteString(name)
+		elems := make([]string, v.Len())
+		for i := range elems {
+			var e goGenerator
+			if err := e.value(v.Index(i), t.Elem(), t.Elem().Kind() == reflect.Struct); err != nil {
+				return err
+			}
+			elems[i] = e.String()
+		}
+		g.writeList(elems)
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var literal string
+		switch t.Kind() {
+		case reflect.String:
+			literal = strconv.Quote(v.String())
+		case reflect.Bool:
+			literal = strconv.FormatBool(v.Bool())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			literal = strconv.FormatUint(v.Uint(), 10)
+		default:
+			literal = strconv.FormatInt(v.Int(), 10)
+		}
+		// A literal held by an interface needs its type spelled out.
+		if t != static && t.Name() != "" && t.PkgPath() != "" {
+			name, err := goTypeName(t)
+			if err != nil {
+				return err
+			}
+			literal = name + "(" + literal + ")"
+		}
+		g.WriteString(literal)
+	default:
+		return fmt.Errorf("cannot generate Go for a value of type %v", t)
+	}
+
+	return nil
+}
+
+// Write the braced, comma-separated elements of a composite literal, on one
+// line if they are short and simple enough, and one per line otherwise.
+func (g *goGenerator) writeList(elems []string) {
+	inline := strings.Join(elems, ", ")
+	if len(inline) <= maxInlineLiteral && !strings.Contains(inline, "\n") {
+		g.WriteString("{" + inline + "}")
+		return
+	}
+
+	g.WriteString("{\n")
+	for _, e := range elems {
+		g.WriteString(e + ",\n")
+	}
+	g.WriteString("}")
+}
+
+// Return the name of the type as written in code importing this package as m.
+func goTypeName(t reflect.Type) (string, error) {
+	if t.Kind() == reflect.Slice && t.Name() == "" {
+		elem, err := goTypeName(t.Elem())
+		return "[]" + elem, err
+	}
+
+	switch t.PkgPath() {
+	case "":
+		if t.Name() == "" {
+			return "", fmt.Errorf("cannot generate Go for unnamed type %v", t)
+		}
+		return t.Name(), nil
+	case packagePath:
+		return "m." + t.Name(), nil
+	}
+
+	return "", errors.New("cannot generate Go for type " + t.String() + " outside package language")
+}
@@ -0,0 +1,79 @@
+package language_test
+
+import (
+	"go/parser"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestGenerateGo(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	contract := m.When{
+		Cases: []m.Case{
+			{
+				Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")},
+				Then: m.If{
+					Observe: m.ValueGE{Value: m.TimeIntervalStart, Ge: m.UseValue{Value: "x"}},
+					Then:    m.Pay{From: buyer, To: m.Payee{Party: m.Address("addr1")}, Token: m.Ada, Pay: m.SetConstant("-5"), Then: m.Close},
+					Else:    m.Close,
+				},
+			},
+			{Action: m.Choice{ChoiceId: m.ChoiceId{Name: "c", Owner: buyer}, Bounds: []m.Bound{{Lower: 0, Upper: 3}}}, Then: m.Close},
+			{Action: m.Notify{If: m.TrueObs}, Then: m.Close},
+		},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	expected := `m.When{
+	Cases: []m.Case{
+		{
+			Action: m.Deposit{
+				IntoAccount: m.Role{Name: "buyer"},
+				Party:       m.Role{Name: "buyer"},
+				Token:       m.Ada,
+				Deposits:    m.SetConstant("10"),
+			},
+			Then: m.If{
+				Observe: m.ValueGE{Value: m.TimeIntervalStart, Ge: m.UseValue{Value: "x"}},
+				Then: m.Pay{
+					From:  m.Role{Name: "buyer"},
+					To:    m.Payee{Party: m.Address("addr1")},
+					Token: m.Ada,
+					Pay:   m.SetConstant("-5"),
+					Then:  m.Close,
+				},
+				Else: m.Close,
+			},
+		},
+		{
+			Action: m.Choice{
+				ChoiceId: m.ChoiceId{Name: "c", Owner: m.Role{Name: "buyer"}},
+				Bounds:   []m.Bound{{Upper: 3}},
+			},
+			Then: m.Close,
+		},
+		{Action: m.Notify{If: m.TrueObs}, Then: m.Close},
+	},
+	Timeout: m.POSIXTime(1666078977926),
+	Then:    m.Close,
+}`
+
+	got, err := m.GenerateGo(contract)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("Expected:\n%v\ngot:\n%v", expected, got)
+	}
+	if _, err := parser.ParseExpr(got); err != nil {
+		t.Errorf("Expected a valid Go expression, got: %v", err)
+	}
+}
+
+func TestGenerateGo_Close(t *testing.T) {
+	if got, err := m.GenerateGo(m.Close); err != nil || got != "m.Close" {
+		t.Errorf("Expected m.Close, got: %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SuggestInterval suggests a validity interval for a transaction submitted at
+// time now that is at most slotLength long and will not be rejected with a
+// TEAmbiguousTimeIntervalError. The interval starts at now and ends before the
+// earliest timeout after now of a When that the contract can reach without
+// input, i.e. through Pay, If, Let and Assert constructs and timed out Whens.
+func SuggestInterval(c Contract, now POSIXTime, slotLength POSIXTime) (TimeInterval, error) {
+	if slotLength <= 0 {
+		return TimeInterval{}, fmt.Errorf("slot length must be positive, got %v", slotLength)
+	}
+
+	end := now + slotLength - 1
+
+	var visit func(c Contract) error
+	visit = func(c Contract) error {
+		switch c := c.(type) {
+		case Pay:
+			return visit(c.Then)
+		case If:
+			if err := visit(c.Then); err != nil {
+				return err
+			}
+			return visit(c.Else)
+		case Let:
+			return visit(c.Then)
+		case Assert:
+			return visit(c.Then)
+		case When:
+			timeout, ok := c.Timeout.(POSIXTime)
+			if !ok {
+				return errors.New("cannot suggest an interval for a contract with parameterized timeouts")
+			}
+			if timeout <= now {
+				return visit(c.Then)
+			}
+			if timeout-1 < end {
+				end = timeout - 1
+			}
+		}
+		return nil
+	}
+
+	if err := visit(c); err != nil {
+		return TimeInterval{}, err
+	}
+
+	return NewTimeInterval(now, end), nil
+}
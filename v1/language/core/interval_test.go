@@ -0,0 +1,45 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestSuggestInterval_NearTimeout(t *testing.T) {
+	contract := setupEscrowContract()
+	timeout := m.POSIXTime(1666078977926)
+	now := timeout - 1000
+
+	interval, err := m.SuggestInterval(contract, now, 60000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !interval.Before(timeout) {
+		t.Errorf("Expected the interval %v to end before the timeout %v", interval, timeout)
+	}
+	if !interval.Contains(now) {
+		t.Errorf("Expected the interval %v to contain now", interval)
+	}
+
+	tx := m.TransactionInput{Interval: interval}
+	if out := m.ComputeTransaction(tx, m.State{}, contract); out.Error == (m.TEAmbiguousTimeIntervalError{}) {
+		t.Errorf("Expected the suggested interval not to be ambiguous")
+	}
+}
+
+func TestSuggestInterval_AfterTimeout(t *testing.T) {
+	contract := setupEscrowContract()
+	now := m.POSIXTime(1666078977926)
+
+	interval, err := m.SuggestInterval(contract, now, 60000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := m.NewTimeInterval(now, now+59999)
+	if interval != expected {
+		t.Errorf("Expected %v, got: %v", expected, interval)
+	}
+}
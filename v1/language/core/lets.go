@@ -0,0 +1,267 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "fmt"
+
+// UnusedLets reports the name of every Let whose bound value is never read by
+// a UseValue in its continuation before it is rebound, in the order the Lets
+// appear in the contract.
+func UnusedLets(c Contract) []ValueId {
+	var unused []ValueId
+
+	var walk func(c Contract)
+	walk = func(c Contract) {
+		switch c := c.(type) {
+		case Pay:
+			walk(c.Then)
+		case If:
+			walk(c.Then)
+			walk(c.Else)
+		case When:
+			for _, cs := range c.Cases {
+				walk(cs.Then)
+			}
+			walk(c.Then)
+		case Let:
+			if !readsValue(c.Then, c.Name) {
+				unused = append(unused, c.Name)
+			}
+			walk(c.Then)
+		case Assert:
+			walk(c.Then)
+		}
+	}
+	walk(c)
+
+	return unused
+}
+
+// CoalesceLets tidies chains of Let bindings by dropping every Let whose
+// bound value is never used (see UnusedLets), while preserving the order of
+// the remaining bindings. It only drops bindings: independent Lets are
+// neither grouped nor reordered.
+//
+// Dropping an unused Let does not change the payments made by the contract,
+// but it does change what executing it reports: the dropped value no longer
+// appears in the State's BoundValues, and the ShadowedLet warning issued when
+// a Let rebinds a bound id would be lost. A Let that shadows an earlier
+// binding on its path, or is itself shadowed by a later one, is therefore
+// kept even if its value is unused, so that the warnings are unchanged.
+func CoalesceLets(c Contract) Contract {
+	return coalesceLets(c, nil)
+}
+
+// Coalesce the Lets of the contract, given the ids bound on the path to it.
+func coalesceLets(c Contract, bound map[ValueId]bool) Contract {
+	switch c := c.(type) {
+	case Pay:
+		c.Then = coalesceLets(c.Then, bound)
+		return c
+	case If:
+		c.Then = coalesceLets(c.Then, bound)
+		c.Else = coalesceLets(c.Else, bound)
+		return c
+	case When:
+		cases := make([]Case, len(c.Cases))
+		for i, cs := range c.Cases {
+			cs.Then = coalesceLets(cs.Then, bound)
+			cases[i] = cs
+		}
+		c.Cases = cases
+		c.Then = coalesceLets(c.Then, bound)
+		return c
+	case Let:
+		if !bound[c.Name] && !readsValue(c.Then, c.Name) && !bindsValue(c.Then, c.Name) {
+			return coalesceLets(c.Then, bound)
+		}
+		inner := make(map[ValueId]bool, len(bound)+1)
+		for id := range bound {
+			inner[id] = true
+		}
+		inner[c.Name] = true
+		c.Then = coalesceLets(c.Then, inner)
+		return c
+	case Assert:
+		c.Then = coalesceLets(c.Then, bound)
+		return c
+	}
+
+	return c
+}
+
+// Report whether a Let in the contract binds the value id.
+func bindsValue(c Contract, id ValueId) bool {
+	found := false
+	inspect(c, func(node any) {
+		if let, ok := node.(Let); ok && let.Name == id {
+			found = true
+		}
+	})
+	return found
+}
+
+// Report whether the contract reads the value id before a Let rebinds it.
+func readsValue(c Contract, id ValueId) bool {
+	reads := func(values ...Value) bool {
+		found := false
+		for _, v := range values {
+			inspectValue(v, func(node any) {
+				if use, ok := node.(UseValue); ok && use.Value == id {
+					found = true
+				}
+			})
+		}
+		return found
+	}
+
+	switch c := c.(type) {
+	case Pay:
+		return reads(c.Pay) || readsValue(c.Then, id)
+	case If:
+		return reads(c.Observe) || readsValue(c.Then, id) || readsValue(c.Else, id)
+	case When:
+		for _, cs := range c.Cases {
+			switch a := cs.Action.(type) {
+			case Deposit:
+				if reads(a.Deposits) {
+					return true
+				}
+			case Notify:
+				if reads(a.If) {
+					return true
+				}
+			}
+			if readsValue(cs.Then, id) {
+				return true
+			}
+		}
+		return readsValue(c.Then, id)
+	case Let:
+		if reads(c.Value) {
+			return true
+		}
+		// A Let of the same name shadows the binding for the rest of the path.
+		return c.Name != id && readsValue(c.Then, id)
+	case Assert:
+		return reads(c.Observe) || readsValue(c.Then, id)
+	}
+
+	return false
+}
+
+// BindingKind classifies the problems CheckBindings reports.
+type BindingKind int
+
+const (
+	// UnboundValue marks a UseValue that can be reached before any Let binds
+	// its id, so that it silently evaluates to zero.
+	UnboundValue BindingKind = iota
+	// ShadowedValue marks a Let that rebinds an id already bound on its path.
+	ShadowedValue
+)
+
+func (k BindingKind) String() string {
+	switch k {
+	case UnboundValue:
+		return "unbound"
+	case ShadowedValue:
+		return "shadowed"
+	}
+	return fmt.Sprintf("BindingKind(%d)", int(k))
+}
+
+// A BindingWarning reports a problem with a value id at a node of the
+// contract, identified by its path from the root: a UseValue read there
+// before it is bound, or a Let there that shadows an earlier binding.
+type BindingWarning struct {
+	Path  Path
+	Kind  BindingKind
+	Value ValueId
+}
+
+func (w BindingWarning) String() string {
+	switch w.Kind {
+	case UnboundValue:
+		return fmt.Sprintf("%v: UseValue %q is read before any Let binds it", w.Path, w.Value)
+	case ShadowedValue:
+		return fmt.Sprintf("%v: Let %q shadows an earlier binding", w.Path, w.Value)
+	}
+	return fmt.Sprintf("%v: %v value %q", w.Path, w.Kind, w.Value)
+}
+
+// CheckBindings tracks the value ids bound by Let along each path through the
+// contract and reports every UseValue that can be reached before its id is
+// bound, as well as every Let that shadows an earlier binding. The value of a
+// Let is evaluated before its id is bound, so a Let reading its own id is
+// reported unless an earlier Let bound it. Each node is reported once per id,
+// in the order the nodes appear in the contract.
+func CheckBindings(c Contract) []BindingWarning {
+	return checkBindings(c, "", map[ValueId]bool{})
+}
+
+func checkBindings(c Contract, path Path, bound map[ValueId]bool) []BindingWarning {
+	var warnings []BindingWarning
+
+	// Report the ids read by the values that no Let on the path has bound.
+	reads := func(path Path, values ...Value) {
+		seen := make(map[ValueId]bool)
+		for _, v := range values {
+			_, ids, _ := ValueFreeVariables(v)
+			for _, id := range ids {
+				if !bound[id] && !seen[id] {
+					seen[id] = true
+					warnings = append(warnings, BindingWarning{Path: path, Kind: UnboundValue, Value: id})
+				}
+			}
+		}
+	}
+
+	switch c := c.(type) {
+	case Pay:
+		reads(path, c.Pay)
+		warnings = append(warnings, checkBindings(c.Then, joinPath(path, "then"), bound)...)
+	case If:
+		reads(path, c.Observe)
+		warnings = append(warnings, checkBindings(c.Then, joinPath(path, "then"), bound)...)
+		warnings = append(warnings, checkBindings(c.Else, joinPath(path, "else"), bound)...)
+	case When:
+		for i, cs := range c.Cases {
+			casePath := joinPath(path, fmt.Sprintf("when[%d]", i))
+			switch a := cs.Action.(type) {
+			case Deposit:
+				reads(casePath, a.Deposits)
+			case Notify:
+				reads(casePath, a.If)
+			}
+			warnings = append(warnings, checkBindings(cs.Then, joinPath(casePath, "then"), bound)...)
+		}
+		warnings = append(warnings, checkBindings(c.Then, joinPath(path, "timeout_continuation"), bound)...)
+	case Let:
+		reads(path, c.Value)
+		if bound[c.Name] {
+			warnings = append(warnings, BindingWarning{Path: path, Kind: ShadowedValue, Value: c.Name})
+			return append(warnings, checkBindings(c.Then, joinPath(path, "then"), bound)...)
+		}
+		bound[c.Name] = true
+		warnings = append(warnings, checkBindings(c.Then, joinPath(path, "then"), bound)...)
+		delete(bound, c.Name)
+	case Assert:
+		reads(path, c.Observe)
+		warnings = append(warnings, checkBindings(c.Then, joinPath(path, "then"), bound)...)
+	}
+
+	return warnings
+}
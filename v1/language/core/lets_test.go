@@ -0,0 +1,136 @@
+package language_test
+
+import (
+	"reflect"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestCoalesceLets(t *testing.T) {
+	contract := m.Let{
+		Name:  "unused",
+		Value: m.SetConstant("1"),
+		Then: m.Let{
+			Name:  "price",
+			Value: m.SetConstant("10"),
+			Then: m.Pay{
+				From:  m.Role{Name: "buyer"},
+				To:    m.Payee{Party: m.Role{Name: "seller"}},
+				Token: m.Ada,
+				Pay:   m.UseValue{Value: "price"},
+				Then:  m.Close,
+			},
+		},
+	}
+
+	unused := m.UnusedLets(contract)
+	if len(unused) != 1 || unused[0] != "unused" {
+		t.Errorf("Expected [unused], got: %v", unused)
+	}
+
+	expected := contract.Then
+	if got := m.CoalesceLets(contract); !m.EqualIgnoringTimeouts(got, expected) {
+		t.Errorf("Expected the unused Let to be dropped, got: %v", got)
+	}
+}
+
+func TestCoalesceLets_Shadowed(t *testing.T) {
+	pay := func(then m.Contract) m.Contract {
+		return m.Pay{From: m.Role{Name: "buyer"}, To: m.Payee{Party: m.Role{Name: "seller"}}, Token: m.Ada, Pay: m.UseValue{Value: "x"}, Then: then}
+	}
+	contracts := []m.Contract{
+		// The first binding is shadowed before it is ever read.
+		m.Let{
+			Name:  "x",
+			Value: m.SetConstant("1"),
+			Then: m.Let{
+				Name:  "x",
+				Value: m.SetConstant("2"),
+				Then:  m.Assert{Observe: m.ValueEQ{Value: m.UseValue{Value: "x"}, Eq: m.SetConstant("2")}, Then: m.Close},
+			},
+		},
+		// The second binding shadows the first and is never read.
+		m.Let{Name: "x", Value: m.SetConstant("1"), Then: pay(m.Let{Name: "x", Value: m.SetConstant("2"), Then: m.Close})},
+	}
+
+	env := m.Environment{TimeInterval: m.NewTimeInterval(0, 0)}
+	state := m.State{Accounts: m.Accounts{{AccountId: m.Role{Name: "buyer"}, Token: m.Ada}: 10}}
+	for _, contract := range contracts {
+		unused := m.UnusedLets(contract)
+		if len(unused) != 1 || unused[0] != "x" {
+			t.Errorf("Expected one unused binding, got: %v", unused)
+		}
+
+		// Dropping either binding would lose the ShadowedLet warning.
+		got := m.CoalesceLets(contract)
+		if !m.EqualIgnoringTimeouts(got, contract) {
+			t.Errorf("Expected the shadowing bindings to be kept, got: %v", got)
+		}
+		_, _, _, before, err := m.ReduceContractUntilQuiescent(env, state, contract)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, after, err := m.ReduceContractUntilQuiescent(env, state, got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(before) != 1 {
+			t.Errorf("Expected a ShadowedLet warning, got: %v", before)
+		}
+		if !reflect.DeepEqual(before, after) {
+			t.Errorf("Expected warnings %v, got: %v", before, after)
+		}
+	}
+}
+
+func TestCheckBindings(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	pay := func(v m.Value, then m.Contract) m.Contract {
+		return m.Pay{From: buyer, To: m.Payee{Party: seller}, Token: m.Ada, Pay: v, Then: then}
+	}
+
+	contract := m.If{
+		Observe: m.TrueObs,
+		// The typo'd id is never bound.
+		Then: m.Let{Name: "price", Value: m.SetConstant("10"), Then: pay(m.UseValue{Value: "prise"}, m.Close)},
+		Else: m.When{
+			Cases: []m.Case{{
+				Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.UseValue{Value: "price"}},
+				Then: m.Let{
+					Name:  "price",
+					Value: m.SetConstant("1"),
+					Then: m.Let{
+						Name:  "price",
+						Value: m.AddValue{Add: m.UseValue{Value: "price"}, To: m.SetConstant("1")},
+						Then:  pay(m.UseValue{Value: "price"}, m.Close),
+					},
+				},
+			}},
+			Timeout: m.POSIXTime(1),
+			Then:    m.Close,
+		},
+	}
+
+	expected := []m.BindingWarning{
+		{Path: "then.then", Kind: m.UnboundValue, Value: "prise"},
+		{Path: "else.when[0]", Kind: m.UnboundValue, Value: "price"},
+		{Path: "else.when[0].then.then", Kind: m.ShadowedValue, Value: "price"},
+	}
+	if got := m.CheckBindings(contract); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got: %v", expected, got)
+	}
+}
+
+func TestCheckBindings_SelfReference(t *testing.T) {
+	// A Let's value is evaluated before its id is bound.
+	contract := m.Let{Name: "x", Value: m.UseValue{Value: "x"}, Then: m.Close}
+
+	expected := []m.BindingWarning{{Path: "", Kind: m.UnboundValue, Value: "x"}}
+	if got := m.CheckBindings(contract); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got: %v", expected, got)
+	}
+	if got := m.CheckBindings(setupEscrowContract()); len(got) != 0 {
+		t.Errorf("Expected no warnings, got: %v", got)
+	}
+}
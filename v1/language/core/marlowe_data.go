@@ -0,0 +1,115 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package language contains types and methods that implement the Marlowe DSL in Go
+// See: https://github.com/input-output-hk/marlowe-cardano/tree/main/marlowe/specification
+// See: https://github.com/input-output-hk/marlowe-cardano/blob/main/marlowe/src/Language/Marlowe/Core/V1/Semantics.hs
+package language
+
+import "fmt"
+
+// MarloweParams holds the parameters of the Marlowe validator that are fixed
+// for the lifetime of a contract. All of the role tokens of a contract are
+// minted under the single RolesCurrency policy.
+//
+//	data MarloweParams = MarloweParams { rolesCurrency :: CurrencySymbol }
+type MarloweParams struct {
+	RolesCurrency string `json:"rolesCurrency"`
+}
+
+// MarloweData is the datum stored alongside a contract on-chain.
+//
+//	data MarloweData = MarloweData {
+//		marloweParams   :: MarloweParams,
+//		marloweState    :: State,
+//		marloweContract :: Contract }
+type MarloweData struct {
+	Params   MarloweParams
+	State    State
+	Contract Contract
+}
+
+// An Inconsistency describes a Token whose currency symbol conflicts with the
+// roles currency of the contract.
+type Inconsistency struct {
+	Token  Token
+	Reason string
+}
+
+// ValidateRoleCurrencyConsistency flags tokens that reuse the roles currency
+// symbol without being role tokens, which would mix payment assets with the
+// role tokens minted for the contract. A token under the roles currency is a
+// role token when its name is that of a Role in the contract or its State.
+// It also flags contracts that use roles but have no roles currency to mint
+// them under.
+func ValidateRoleCurrencyConsistency(data MarloweData) []Inconsistency {
+	type use struct {
+		token Token
+		where string
+	}
+	var uses []use
+	roles := make(map[string]bool)
+	addRoles := func(parties ...Party) {
+		for _, p := range parties {
+			if role, ok := p.(Role); ok {
+				roles[role.Name] = true
+			}
+		}
+	}
+
+	if data.Contract != nil {
+		inspect(data.Contract, func(node any) {
+			addRoles(nodeParties(node)...)
+
+			switch n := node.(type) {
+			case Pay:
+				uses = append(uses, use{n.Token, "Pay"})
+			case Deposit:
+				uses = append(uses, use{n.Token, "Deposit"})
+			case AvailableMoney:
+				uses = append(uses, use{n.Amount, "AvailableMoney"})
+			}
+		})
+	}
+
+	for account := range data.State.Accounts {
+		addRoles(account.AccountId)
+		uses = append(uses, use{account.Token, "State account"})
+	}
+	for id := range data.State.Choices {
+		addRoles(id.Owner)
+	}
+
+	var inconsistencies []Inconsistency
+	seen := make(map[Token]bool)
+	for _, u := range uses {
+		t := u.token
+		if t.Symbol == "" || t.Symbol != data.Params.RolesCurrency || roles[t.Name] || seen[t] {
+			continue
+		}
+		seen[t] = true
+		inconsistencies = append(inconsistencies, Inconsistency{
+			Token:  t,
+			Reason: fmt.Sprintf("%v uses the roles currency symbol %q for a non-role token", u.where, t.Symbol),
+		})
+	}
+
+	if len(roles) > 0 && data.Params.RolesCurrency == "" {
+		inconsistencies = append(inconsistencies, Inconsistency{
+			Reason: "contract uses roles but MarloweParams has no roles currency",
+		})
+	}
+
+	return inconsistencies
+}
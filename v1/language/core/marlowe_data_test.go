@@ -0,0 +1,75 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+const rolesCurrency = "8bb3b343d8e404472337966a722150048c768d0a92a9813596c5338d"
+
+func TestValidateRoleCurrencyConsistency(t *testing.T) {
+	rogue := m.Token{Symbol: rolesCurrency, Name: "coin"}
+	data := m.MarloweData{
+		Params: m.MarloweParams{RolesCurrency: rolesCurrency},
+		Contract: setupWhenContract(m.Deposit{
+			IntoAccount: m.Role{Name: "seller"},
+			Party:       m.Role{Name: "buyer"},
+			Token:       rogue,
+			Deposits:    m.SetConstant("10"),
+		}),
+	}
+
+	inconsistencies := m.ValidateRoleCurrencyConsistency(data)
+	if len(inconsistencies) != 1 || inconsistencies[0].Token != rogue {
+		t.Errorf("Expected one inconsistency for %v, got: %v", rogue, inconsistencies)
+	}
+}
+
+func TestValidateRoleCurrencyConsistency_Clean(t *testing.T) {
+	data := m.MarloweData{
+		Params: m.MarloweParams{RolesCurrency: rolesCurrency},
+		Contract: setupWhenContract(m.Deposit{
+			IntoAccount: m.Role{Name: "seller"},
+			Party:       m.Role{Name: "buyer"},
+			Token:       m.Ada,
+			Deposits:    m.SetConstant("10"),
+		}),
+	}
+
+	if inconsistencies := m.ValidateRoleCurrencyConsistency(data); len(inconsistencies) != 0 {
+		t.Errorf("Expected no inconsistencies, got: %v", inconsistencies)
+	}
+
+	data.Params.RolesCurrency = ""
+	if inconsistencies := m.ValidateRoleCurrencyConsistency(data); len(inconsistencies) != 1 {
+		t.Errorf("Expected a missing roles currency to be flagged, got: %v", inconsistencies)
+	}
+}
+
+func TestValidateRoleCurrencyConsistency_RoleTokens(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	buyerToken := m.Token{Symbol: rolesCurrency, Name: "buyer"}
+	sellerToken := m.Token{Symbol: rolesCurrency, Name: "seller"}
+	escrowToken := m.Token{Symbol: rolesCurrency, Name: "escrow"}
+
+	// Depositing and paying the role tokens of the contract's own roles, and
+	// holding that of a role known only to the State.
+	data := m.MarloweData{
+		Params: m.MarloweParams{RolesCurrency: rolesCurrency},
+		Contract: setupWhenContract(m.Deposit{
+			IntoAccount: seller,
+			Party:       buyer,
+			Token:       buyerToken,
+			Deposits:    m.SetConstant("1"),
+		}),
+		State: m.State{Accounts: m.Accounts{
+			{AccountId: m.Role{Name: "escrow"}, Token: escrowToken}: 1,
+		}},
+	}
+	data.Contract = m.Pay{From: seller, To: m.Payee{Party: buyer}, Token: sellerToken, Pay: m.SetConstant("1"), Then: data.Contract}
+
+	if inconsistencies := m.ValidateRoleCurrencyConsistency(data); len(inconsistencies) != 0 {
+		t.Errorf("Expected role tokens not to be flagged, got: %v", inconsistencies)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// A MerkleizedContract is a contract some of whose cases hold only the hash
+// of their continuation, which must be supplied with the input taking them.
+type MerkleizedContract = Contract
+
+// MerkleizeOptions configures which cases Merkleize replaces.
+type MerkleizeOptions struct {
+	// Threshold is the Depth a continuation must exceed to be merkleized,
+	// so that Close has depth 1. The default of 0 merkleizes every case, as
+	// marlowe-cardano does.
+	Threshold int
+}
+
+// Merkleize merkleizes every case of the contract, as MerkleizeOptions does
+// with the default options.
+func Merkleize(c Contract) (MerkleizedContract, map[Hash]Contract, error) {
+	return MerkleizeOptions{}.Merkleize(c)
+}
+
+// Merkleize replaces the continuation of each case deeper than the threshold
+// by its hash, and returns the merkleized contract together with the
+// continuations by hash. Continuations are merkleized bottom up, so those in
+// the map are themselves merkleized. The hash is the on-chain datum hash of
+// the continuation: the BLAKE2b-256 digest of the CBOR of its Plutus data, as
+// produced by MarshalCBOR. An error is returned if a continuation cannot be
+// encoded, because it holds Extended parameters or an invalid address.
+// Timeout continuations are never merkleized, since no input carries them.
+//
+// A merkleized case is taken by a MerkleizedInput carrying the continuation
+// from the map.
+func (o MerkleizeOptions) Merkleize(c Contract) (MerkleizedContract, map[Hash]Contract, error) {
+	continuations := make(map[Hash]Contract)
+	merkleized, err := o.merkleize(c, continuations)
+	if err != nil {
+		return nil, nil, err
+	}
+	return merkleized, continuations, nil
+}
+
+func (o MerkleizeOptions) merkleize(c Contract, continuations map[Hash]Contract) (Contract, error) {
+	var err error
+	switch c := c.(type) {
+	case Pay:
+		c.Then, err = o.merkleize(c.Then, continuations)
+		return c, err
+	case If:
+		if c.Then, err = o.merkleize(c.Then, continuations); err != nil {
+			return nil, err
+		}
+		c.Else, err = o.merkleize(c.Else, continuations)
+		return c, err
+	case When:
+		cases := make([]Case, len(c.Cases))
+		for i, cs := range c.Cases {
+			cases[i] = cs
+			if cs.MerkleizedThen != "" {
+				continue
+			}
+			if cases[i].Then, err = o.merkleize(cs.Then, continuations); err != nil {
+				return nil, err
+			}
+			if Depth(cs.Then) <= o.Threshold {
+				continue
+			}
+			hash, err := continuationHash(cases[i].Then)
+			if err != nil {
+				return nil, fmt.Errorf("case %d: %w", i, err)
+			}
+			continuations[hash] = cases[i].Then
+			cases[i] = MerkleizedCase(cs.Action, hash)
+		}
+		c.Cases = cases
+		c.Then, err = o.merkleize(c.Then, continuations)
+		return c, err
+	case Let:
+		c.Then, err = o.merkleize(c.Then, continuations)
+		return c, err
+	case Assert:
+		c.Then, err = o.merkleize(c.Then, continuations)
+		return c, err
+	}
+
+	return c, nil
+}
+
+// Hash the continuation as the ledger hashes a datum.
+func continuationHash(c Contract) (Hash, error) {
+	data, err := contractData(c)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writeData(&buf, data)
+	digest := blake2b.Sum256(buf.Bytes())
+	return Hash(hex.EncodeToString(digest[:])), nil
+}
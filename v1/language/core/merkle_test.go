@@ -0,0 +1,171 @@
+package language_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// The datum hash of Close, i.e. of Constr 0 [], as computed by the ledger.
+const closeHash = m.Hash("923918e403bf43c34b4ef6b48eb2ee04babed17320d8d1b9ff9ad086e86f44ec")
+
+func TestMerkleize(t *testing.T) {
+	original := setupEscrowContract()
+	contract, continuations, err := m.Merkleize(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := contract.(m.When)
+	if len(when.Cases) != 1 || when.Cases[0].MerkleizedThen == "" || when.Cases[0].Then != nil {
+		t.Fatalf("Expected the deposit case to be merkleized, got: %v", when.Cases)
+	}
+	if when.Then != m.Close {
+		t.Errorf("Expected the timeout continuation to be kept, got: %v", when.Then)
+	}
+
+	// The approval When, whose own case pays out and is merkleized in turn.
+	approval, ok := continuations[when.Cases[0].MerkleizedThen].(m.When)
+	if !ok || len(approval.Cases) != 1 || approval.Cases[0].MerkleizedThen == "" {
+		t.Fatalf("Expected the merkleized approval When, got: %v", continuations)
+	}
+	pay, ok := continuations[approval.Cases[0].MerkleizedThen].(m.Pay)
+	if !ok || pay.Then != m.Close {
+		t.Fatalf("Expected the payment, got: %v", continuations)
+	}
+	if len(continuations) != 2 {
+		t.Errorf("Expected two continuations, got: %v", continuations)
+	}
+
+	if !m.Equal(original, setupEscrowContract()) {
+		t.Error("Expected the original contract to be unchanged")
+	}
+}
+
+func TestMerkleize_Hash(t *testing.T) {
+	contract, continuations, err := m.Merkleize(setupWhenContract(m.Notify{If: m.TrueObs}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash := contract.(m.When).Cases[0].MerkleizedThen; hash != closeHash {
+		t.Errorf("Expected the hash of Close to be %v, got: %v", closeHash, hash)
+	}
+	if continuations[closeHash] != m.Close {
+		t.Errorf("Expected Close under its hash, got: %v", continuations)
+	}
+}
+
+func TestMerkleizeOptions_Threshold(t *testing.T) {
+	// Only the deposit case has a continuation deeper than 2.
+	contract, continuations, err := m.MerkleizeOptions{Threshold: 2}.Merkleize(setupEscrowContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := contract.(m.When)
+	if when.Cases[0].MerkleizedThen == "" {
+		t.Fatal("Expected the deposit case to be merkleized")
+	}
+	approval := continuations[when.Cases[0].MerkleizedThen].(m.When)
+	if approval.Cases[0].MerkleizedThen != "" {
+		t.Errorf("Expected the shallow payment case to stay inline, got: %v", approval.Cases[0])
+	}
+	if len(continuations) != 1 {
+		t.Errorf("Expected a single continuation, got: %v", continuations)
+	}
+}
+
+func TestMerkleize_Error(t *testing.T) {
+	// The continuation pays to an address that cannot be encoded.
+	contract := setupWhenContract(m.Notify{If: m.TrueObs}).(m.When)
+	contract.Cases[0].Then = m.Pay{
+		From:  m.Role{Name: "buyer"},
+		To:    m.Payee{Party: m.Address("addr1_seller")},
+		Token: m.Ada,
+		Pay:   m.SetConstant("1"),
+		Then:  m.Close,
+	}
+
+	if _, _, err := m.Merkleize(contract); err == nil {
+		t.Error("Expected an error for the address that cannot be encoded")
+	}
+}
+
+func TestMerkleizedInput_Apply(t *testing.T) {
+	contract, continuations, err := m.Merkleize(setupEscrowContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buyer := m.Role{Name: "buyer"}
+	hash := contract.(m.When).Cases[0].MerkleizedThen
+	deposit := m.NewIDeposit(buyer, buyer, m.Ada, big.NewInt(50000000))
+	interval := m.NewTimeInterval(1666078970000, 1666078977000)
+
+	tx := m.TransactionInput{Interval: interval, Inputs: []m.Input{
+		m.MerkleizedInput{Input: deposit, Hash: hash, Continuation: continuations[hash]},
+	}}
+	out := m.ComputeTransaction(tx, m.State{}, contract)
+	if out.Error != nil {
+		t.Fatalf("Expected the merkleized case to be taken, got: %v", out.Error)
+	}
+	if !m.Equal(out.Contract, continuations[hash]) {
+		t.Errorf("Expected the continuation, got: %v", out.Contract)
+	}
+
+	// Inputs that do not carry the continuation of the case are rejected.
+	for name, input := range map[string]m.Input{
+		"normal input":       deposit,
+		"wrong continuation": m.MerkleizedInput{Input: deposit, Hash: hash, Continuation: m.Close},
+		"wrong hash":         m.MerkleizedInput{Input: deposit, Hash: closeHash, Continuation: m.Close},
+	} {
+		tx := m.TransactionInput{Interval: interval, Inputs: []m.Input{input}}
+		if out := m.ComputeTransaction(tx, m.State{}, contract); out.Error != (m.TEHashMismatch{}) {
+			t.Errorf("%v: expected TEHashMismatch, got: %v", name, out.Error)
+		}
+	}
+
+	// As is a merkleized input for a case that is not merkleized.
+	input := m.MerkleizedInput{Input: deposit, Hash: closeHash, Continuation: m.Close}
+	tx = m.TransactionInput{Interval: interval, Inputs: []m.Input{input}}
+	if out := m.ComputeTransaction(tx, m.State{}, setupEscrowContract()); out.Error != (m.TEHashMismatch{}) {
+		t.Errorf("Expected TEHashMismatch, got: %v", out.Error)
+	}
+}
+
+func TestMerkleizedInput_JSON(t *testing.T) {
+	notify := m.MerkleizedInput{Input: m.INotify{}, Hash: closeHash, Continuation: m.Close}
+	choice := m.MerkleizedInput{
+		Input: m.IChoice{
+			ChoiceId:  m.ChoiceId{Name: "approve", Owner: m.Role{Name: "buyer"}},
+			ChosenNum: m.SetChosenNum("1"),
+		},
+		Hash:         closeHash,
+		Continuation: m.Close,
+	}
+
+	for expected, input := range map[string]m.Input{
+		`{"continuation_hash":"` + string(closeHash) + `","merkleized_continuation":"close"}`: notify,
+		`{"for_choice_id":{"choice_name":"approve","choice_owner":{"role_token":"buyer"}},"input_that_chooses_num":1,` +
+			`"continuation_hash":"` + string(closeHash) + `","merkleized_continuation":"close"}`: choice,
+	} {
+		data, err := json.Marshal(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("Expected %v, got: %s", expected, data)
+		}
+
+		decoded, err := m.UnmarshalInput(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, input) {
+			t.Errorf("Expected %v after the round trip, got: %v", input, decoded)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "fmt"
+
+// BranchingFactor scores the complexity of a contract for risk review. It
+// reports the number of cases of the widest When, and the total number of
+// branches out of decision points: two for every If, and one for every case
+// of every When. Timeout continuations are not counted as branches.
+func BranchingFactor(c Contract) (maxCases int, totalBranches int) {
+	inspect(c, func(node any) {
+		switch n := node.(type) {
+		case If:
+			totalBranches += 2
+		case When:
+			if len(n.Cases) > maxCases {
+				maxCases = len(n.Cases)
+			}
+			totalBranches += len(n.Cases)
+		}
+	})
+
+	return maxCases, totalBranches
+}
+
+// BranchCosts estimates the on-chain cost of each execution path of the
+// contract, keyed by the path to the Close that ends it, e.g. "$" for a bare
+// Close or "when[0].then.else" (the keys of the constructs, as Builder names
+// them). The cost counts one evaluation step for every construct reduced,
+// every action matched and every Value or Observation node evaluated along the
+// path, so the most expensive branch has the largest cost. Paths through
+// merkleized cases cannot be followed and are left out.
+func BranchCosts(c Contract) map[string]int {
+	costs := make(map[string]int)
+
+	var visit func(c Contract, path string, cost int)
+	visit = func(c Contract, path string, cost int) {
+		cost++
+		switch c := c.(type) {
+		case CloseContract:
+			if path == "" {
+				path = "$"
+			}
+			costs[path] = cost
+		case Pay:
+			visit(c.Then, joinPath(path, "then"), cost+valueSteps(c.Pay))
+		case If:
+			cost += valueSteps(c.Observe)
+			visit(c.Then, joinPath(path, "then"), cost)
+			visit(c.Else, joinPath(path, "else"), cost)
+		case When:
+			for i, cs := range c.Cases {
+				if cs.MerkleizedThen != "" {
+					continue
+				}
+				steps := 0
+				inspectAction(cs.Action, func(any) { steps++ })
+				visit(cs.Then, joinPath(path, fmt.Sprintf("when[%d].then", i)), cost+steps)
+			}
+			visit(c.Then, joinPath(path, "timeout_continuation"), cost)
+		case Let:
+			visit(c.Then, joinPath(path, "then"), cost+valueSteps(c.Value))
+		case Assert:
+			visit(c.Then, joinPath(path, "then"), cost+valueSteps(c.Observe))
+		}
+	}
+	visit(c, "", 0)
+
+	return costs
+}
+
+// The number of Value and Observation nodes evaluated to evaluate v.
+func valueSteps(v Value) int {
+	steps := 0
+	inspectValue(v, func(any) { steps++ })
+	return steps
+}
+
+// Depth reports the maximum nesting depth of the contract, counting every
+// Contract, Case, Action, Value and Observation on the way down, so a bare
+// Close has depth 1. The continuations of merkleized cases are not counted.
+func Depth(c Contract) int {
+	deepest := 0
+	inspectNested(c, 1, func(node any, depth int) {
+		if node != nil && depth > deepest {
+			deepest = depth
+		}
+	})
+
+	return deepest
+}
+
+// Size reports the total number of nodes in the contract: every Contract,
+// Case, Action, Value and Observation. The continuations of merkleized cases
+// are not counted.
+func Size(c Contract) int {
+	size := 0
+	inspect(c, func(node any) {
+		switch n := node.(type) {
+		case nil:
+		case When:
+			size += 1 + len(n.Cases)
+		default:
+			size++
+		}
+	})
+
+	return size
+}
@@ -0,0 +1,123 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestBranchingFactor(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	choice := func(name string) m.Case {
+		return m.Case{
+			Action: m.Choice{ChoiceId: m.ChoiceId{Name: name, Owner: buyer}, Bounds: []m.Bound{{Lower: 0, Upper: 1}}},
+			Then:   m.If{Observe: m.TrueObs, Then: m.Close, Else: m.Close},
+		}
+	}
+
+	contract := m.When{
+		Cases: []m.Case{
+			choice("a"),
+			choice("b"),
+			{Action: m.Notify{If: m.TrueObs}, Then: m.Close},
+			{Action: m.Notify{If: m.FalseObs}, Then: m.Close},
+		},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	maxCases, totalBranches := m.BranchingFactor(contract)
+	if maxCases != 4 {
+		t.Errorf("Expected the widest When to have 4 cases, got: %v", maxCases)
+	}
+	if totalBranches != 8 {
+		t.Errorf("Expected 4 cases and 2 Ifs of 2 branches each, got: %v", totalBranches)
+	}
+}
+
+func TestBranchCosts(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	price := m.UseValue{Value: "price"}
+
+	contract := m.If{
+		Observe: m.ValueGT{Value: price, Gt: m.SetConstant("100")},
+		Then: m.Pay{
+			From:  buyer,
+			To:    m.Payee{Party: seller},
+			Token: m.Ada,
+			Pay: m.DivValue{
+				Divide: m.MulValue{Multiply: price, By: m.AddValue{Add: m.SetConstant("3"), To: price}},
+				By:     m.SubValue{Subtract: price, From: m.SetConstant("7")},
+			},
+			Then: m.Close,
+		},
+		Else: m.Close,
+	}
+
+	costs := m.BranchCosts(contract)
+	if len(costs) != 2 {
+		t.Fatalf("Expected two paths, got: %v", costs)
+	}
+	if costs["then.then"] <= costs["else"] {
+		t.Errorf("Expected the arithmetic branch to cost more than the cheap one, got: %v", costs)
+	}
+	if costs["else"] != 5 {
+		t.Errorf("Expected the cheap branch to cost 5 steps (If, 3 observation nodes, Close), got: %v", costs["else"])
+	}
+}
+
+func TestDepthAndSize(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	price := m.SetConstant("100")
+
+	tests := []struct {
+		name     string
+		contract m.Contract
+		depth    int
+		size     int
+	}{
+		{"close", m.Close, 1, 1},
+		{
+			"pay",
+			m.Pay{From: buyer, To: m.Payee{Party: seller}, Token: m.Ada, Pay: m.AddValue{Add: price, To: price}, Then: m.Close},
+			3, 5,
+		},
+		{
+			"when",
+			m.When{
+				Cases: []m.Case{
+					{Action: m.Deposit{IntoAccount: seller, Party: buyer, Token: m.Ada, Deposits: price}, Then: m.Close},
+					{Action: m.Notify{If: m.ValueGT{Value: price, Gt: m.NegValue{Neg: price}}}, Then: m.Close},
+				},
+				Timeout: m.POSIXTime(1666078977926),
+				Then:    m.Close,
+			},
+			// When, Case, Notify, ValueGT, NegValue, Constant.
+			6,
+			// When, 2 Cases, Deposit, Constant, Close, Notify, ValueGT,
+			// 2 Constants, NegValue, Close, timeout Close.
+			13,
+		},
+		{
+			"merkleized",
+			m.When{
+				Cases:   []m.Case{m.MerkleizedCase(m.Notify{If: m.TrueObs}, closeHash)},
+				Timeout: m.POSIXTime(1666078977926),
+				Then:    m.Close,
+			},
+			// When, Case, Notify, TrueObs, with no continuation.
+			4,
+			// When, Case, Notify, TrueObs, timeout Close.
+			5,
+		},
+	}
+
+	for _, test := range tests {
+		if depth := m.Depth(test.contract); depth != test.depth {
+			t.Errorf("%v: expected depth %v, got: %v", test.name, test.depth, depth)
+		}
+		if size := m.Size(test.contract); size != test.size {
+			t.Errorf("%v: expected size %v, got: %v", test.name, test.size, size)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"bytes"
+	"math/big"
+)
+
+// ProtocolParams holds the ledger protocol parameters that determine the
+// minimum ADA of a transaction output.
+type ProtocolParams struct {
+	// CoinsPerUTxOByte is the lovelace required per byte of a serialized
+	// output (Babbage era), e.g. 4310 on mainnet.
+	CoinsPerUTxOByte uint64
+}
+
+// Sizes in bytes of the parts of a serialized output, taking the largest
+// encoding of each, so that estimates err on the high side.
+const (
+	// The per-output overhead the ledger adds to the serialized size.
+	utxoOverhead = 160
+	// The output map and a base address, with its byte string header.
+	outputBaseSize = 1 + 1 + 2 + 57
+	// A multi-asset value: an array holding the coin and the asset map.
+	valueBaseSize = 1 + 9 + 3
+	// A policy id and the header of the map of its assets.
+	policySize = 2 + 28 + 3
+	// The header of an asset name and the asset's quantity.
+	assetOverhead = 2 + 9
+	// The key of an inline datum, and the array, tag and byte string header
+	// that wrap it.
+	inlineDatumOverhead = 1 + 1 + 1 + 2 + 5
+	// The MarloweParams: a constructor holding the 28 byte roles currency.
+	paramsSize = 2 + 1 + 1 + 28 + 1
+	// The largest 64-bit integer, as an amount, a chosen number, a bound
+	// value or the minimum time of the State.
+	integerSize = 9
+)
+
+// MinAdaRequired estimates an upper bound on the lovelace that the output of
+// the Marlowe validator must hold to satisfy the minimum UTxO rule. As in
+// marlowe-cardano, all of the accounts live in that one output, so it is
+// priced as carrying ADA and every distinct token that a Deposit or a Pay
+// into an account may place there, with the MarloweData as its inline datum.
+// The datum is sized from the Plutus data encoding of the contract, as
+// produced by MarshalCBOR, and of the largest State the contract can reach:
+// an entry for every account and token, choice and Let it names, each
+// holding an integer that fits in 64 bits. Since the contract only shrinks as
+// it runs, the estimate holds for every output of the contract, although not
+// for merkleized continuations, whose size it cannot see.
+//
+// An error is returned if the contract cannot be encoded, such as a template
+// that still holds Extended parameters.
+func MinAdaRequired(c Contract, protocol ProtocolParams) (*big.Int, error) {
+	contract, err := MarshalCBOR(c)
+	if err != nil {
+		return nil, err
+	}
+
+	type accountToken struct {
+		account AccountId
+		token   Token
+	}
+	accounts := make(map[accountToken]bool)
+	tokens := make(map[Token]bool)
+	choices := make(map[ChoiceId]bool)
+	lets := make(map[ValueId]bool)
+	add := func(account AccountId, token Token) {
+		accounts[accountToken{account, token}] = true
+		tokens[token] = true
+	}
+
+	inspect(c, func(node any) {
+		switch n := node.(type) {
+		case Deposit:
+			add(n.IntoAccount, n.Token)
+		case Pay:
+			if n.To.Account != nil {
+				add(n.To.Account, n.Token)
+			}
+		case Choice:
+			choices[n.ChoiceId] = true
+		case Let:
+			lets[n.Name] = true
+		}
+	})
+
+	// The State, a constructor holding three maps and the minimum time.
+	state := 2 + 1 + mapHeaderSize(len(accounts)) + mapHeaderSize(len(choices)) +
+		mapHeaderSize(len(lets)) + integerSize + 1
+	for at := range accounts {
+		account, err := partyData(at.account)
+		if err != nil {
+			return nil, err
+		}
+		token, err := tokenData(at.token)
+		if err != nil {
+			return nil, err
+		}
+		state += dataSize(constrData(0, account, token)) + integerSize
+	}
+	for id := range choices {
+		choice, err := choiceIdData(id)
+		if err != nil {
+			return nil, err
+		}
+		state += dataSize(choice) + integerSize
+	}
+	for name := range lets {
+		state += dataSize([]byte(name)) + integerSize
+	}
+
+	// The MarloweData, a constructor holding the params, state and contract.
+	datum := 2 + 1 + paramsSize + state + len(contract) + 1
+
+	size := utxoOverhead + outputBaseSize + valueBaseSize + inlineDatumOverhead + datum
+	policies := make(map[string]bool)
+	for token := range tokens {
+		if token == Ada {
+			continue
+		}
+		if !policies[token.Symbol] {
+			policies[token.Symbol] = true
+			size += policySize
+		}
+		size += assetOverhead + len(token.Name)
+	}
+
+	return new(big.Int).Mul(big.NewInt(int64(size)), new(big.Int).SetUint64(protocol.CoinsPerUTxOByte)), nil
+}
+
+// The size of the Plutus data encoded as CBOR.
+func dataSize(d plutusData) int {
+	var buf bytes.Buffer
+	writeData(&buf, d)
+	return buf.Len()
+}
+
+// The size of the header of a Plutus map with n entries.
+func mapHeaderSize(n int) int {
+	var buf bytes.Buffer
+	writeHead(&buf, cborArray, uint64(n))
+	return buf.Len()
+}
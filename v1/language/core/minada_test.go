@@ -0,0 +1,97 @@
+package language_test
+
+import (
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+var mainnetParams = m.ProtocolParams{CoinsPerUTxOByte: 4310}
+
+// Helper function returning the estimate for a contract that can be encoded.
+func minAda(t *testing.T, c m.Contract) *big.Int {
+	t.Helper()
+	lovelace, err := m.MinAdaRequired(c, mainnetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lovelace
+}
+
+func TestMinAdaRequired_Close(t *testing.T) {
+	// The output overhead, base address, ADA value and inline datum header,
+	// and a datum of the params, the empty State and Close.
+	size := 160 + 61 + 13 + 10 + (2 + 1 + 33 + 16 + 3 + 1)
+	if got, expected := minAda(t, m.Close), big.NewInt(int64(size)*4310); got.Cmp(expected) != 0 {
+		t.Errorf("Expected %v for Close, got: %v", expected, got)
+	}
+}
+
+func TestMinAdaRequired_Datum(t *testing.T) {
+	// A larger contract costs its extra bytes and nothing more.
+	assert := m.Assert{Observe: m.TrueObs, Then: m.Close}
+	data, err := m.MarshalCBOR(assert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := big.NewInt(int64(len(data)-3) * 4310)
+	if got := new(big.Int).Sub(minAda(t, assert), minAda(t, m.Close)); got.Cmp(extra) != 0 {
+		t.Errorf("Expected the datum to add %v, got: %v", extra, got)
+	}
+
+	// The escrow only ever holds ADA, but its datum takes it above the
+	// ledger's ~1 ADA floor for a pure ADA output.
+	got := minAda(t, setupEscrowContract())
+	if got.Cmp(big.NewInt(2_000_000)) < 0 || got.Cmp(big.NewInt(3_000_000)) > 0 {
+		t.Errorf("Expected between 2 and 3 ADA, got: %v", got)
+	}
+}
+
+func TestMinAdaRequired_Tokens(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	token := m.Token{Symbol: "8bb3b343d8e404472337966a722150048c768d0a92a9813596c5338d", Name: "coin"}
+	deposit := func(account m.Party, token m.Token) m.Contract {
+		return m.When{
+			Cases: []m.Case{{
+				Action: m.Deposit{IntoAccount: account, Party: buyer, Token: token, Deposits: m.SetConstant("1")},
+				Then:   m.Close,
+			}},
+			Timeout: m.POSIXTime(1),
+			Then:    m.Close,
+		}
+	}
+
+	// The token is carried by the output, and its account entry by the State.
+	adaOnly := minAda(t, deposit(buyer, m.Ada))
+	withToken := minAda(t, deposit(buyer, token))
+	if withToken.Cmp(adaOnly) <= 0 {
+		t.Errorf("Expected a token to raise the estimate above %v, got: %v", adaOnly, withToken)
+	}
+
+	// Every account shares the one output, so another account costs only
+	// its entry in the State and not another output.
+	pay := m.Pay{From: buyer, To: m.Payee{Account: seller}, Token: token, Pay: m.SetConstant("1"), Then: m.Close}
+	paid := minAda(t, m.When{
+		Cases:   []m.Case{{Action: deposit(buyer, token).(m.When).Cases[0].Action, Then: pay}},
+		Timeout: m.POSIXTime(1),
+		Then:    m.Close,
+	})
+	if limit := new(big.Int).Add(withToken, minAda(t, m.Close)); paid.Cmp(withToken) <= 0 || paid.Cmp(limit) >= 0 {
+		t.Errorf("Expected between %v and %v, got: %v", withToken, limit, paid)
+	}
+}
+
+func TestMinAdaRequired_Error(t *testing.T) {
+	pay := m.Pay{
+		From:  m.Role{Name: "buyer"},
+		To:    m.Payee{Party: m.Address("addr1_seller")},
+		Token: m.Ada,
+		Pay:   m.SetConstant("1"),
+		Then:  m.Close,
+	}
+
+	if _, err := m.MinAdaRequired(pay, mainnetParams); err == nil {
+		t.Error("Expected an error for the address that cannot be encoded")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "sort"
+
+// SortParties returns a sorted copy of the parties in the canonical order of
+// analysis output: roles before addresses, each ordered lexicographically by
+// role name or address. Note that this differs from the order of the Haskell
+// implementation, which is kept for the JSON of State and for refunds.
+func SortParties(parties []Party) []Party {
+	sorted := make([]Party, len(parties))
+	copy(sorted, parties)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch a := a.(type) {
+		case Role:
+			b, ok := b.(Role)
+			return !ok || a.Name < b.Name
+		case Address:
+			b, ok := b.(Address)
+			return ok && a < b
+		}
+		return false
+	})
+
+	return sorted
+}
+
+// Parties returns every party referenced by the contract, without duplicates
+// and in the order of SortParties.
+func Parties(c Contract) []Party {
+	seen := make(map[Party]bool)
+	var parties []Party
+
+	inspect(c, func(node any) {
+		for _, p := range nodeParties(node) {
+			if !seen[p] {
+				seen[p] = true
+				parties = append(parties, p)
+			}
+		}
+	})
+
+	return SortParties(parties)
+}
+
+// Tokens returns every token referenced by a Deposit, Pay or AvailableMoney in
+// the contract, without duplicates and ordered by currency symbol and then
+// token name, so Ada comes first.
+func Tokens(c Contract) []Token {
+	seen := make(map[Token]bool)
+	var tokens []Token
+
+	inspect(c, func(node any) {
+		var t Token
+		switch n := node.(type) {
+		case Deposit:
+			t = n.Token
+		case Pay:
+			t = n.Token
+		case AvailableMoney:
+			t = n.Amount
+		default:
+			return
+		}
+		if !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	})
+
+	sort.Slice(tokens, func(i, j int) bool { return lessToken(tokens[i], tokens[j]) })
+	return tokens
+}
+
+// ChoiceIds returns every choice id referenced by a Choice, ChoiceValue or
+// ChoseSomething in the contract, without duplicates and ordered by choice
+// name and then owner, as in the Haskell implementation.
+func ChoiceIds(c Contract) []ChoiceId {
+	seen := make(map[ChoiceId]bool)
+	var ids []ChoiceId
+
+	inspect(c, func(node any) {
+		var id ChoiceId
+		switch n := node.(type) {
+		case Choice:
+			id = n.ChoiceId
+		case ChoiceValue:
+			id = n.Value
+		case ChoseSomething:
+			id = n.Choice
+		default:
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	})
+
+	sort.Slice(ids, func(i, j int) bool { return lessChoiceId(ids[i], ids[j]) })
+	return ids
+}
+
+// WithdrawableByParty returns the tokens the party could receive on any path
+// through the contract, ordered by currency symbol and then token name: those
+// paid to the party by a Pay, and those that may be refunded to it on Close,
+// i.e. deposited or paid into one of its accounts.
+func WithdrawableByParty(c Contract, p Party) []Token {
+	seen := make(map[Token]bool)
+	var tokens []Token
+	add := func(t Token) {
+		if !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+
+	inspect(c, func(node any) {
+		switch n := node.(type) {
+		case Pay:
+			if n.To.owner() == p {
+				add(n.Token)
+			}
+		case Deposit:
+			if n.IntoAccount == p {
+				add(n.Token)
+			}
+		}
+	})
+
+	sort.Slice(tokens, func(i, j int) bool { return lessToken(tokens[i], tokens[j]) })
+	return tokens
+}
+
+// RequiredSignatories returns the parties that must authorize a transaction
+// applying the inputs: the party of each deposit and the owner of each choice.
+// An address must sign the transaction, while a role is authorized by
+// spending its role token. Each is returned once, addresses and roles sorted
+// lexicographically. Notifications need no authorization.
+func RequiredSignatories(inputs []Input) ([]Address, []Role) {
+	seen := make(map[Party]bool)
+	var addresses []Address
+	var roles []Role
+	add := func(p Party) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		switch p := p.(type) {
+		case Address:
+			addresses = append(addresses, p)
+		case Role:
+			roles = append(roles, p)
+		}
+	}
+
+	for _, input := range inputs {
+		if merkleized, ok := input.(MerkleizedInput); ok {
+			input = merkleized.Input
+		}
+		switch i := input.(type) {
+		case IDeposit:
+			add(i.Party)
+		case IChoice:
+			add(i.ChoiceId.Owner)
+		}
+	}
+
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return addresses, roles
+}
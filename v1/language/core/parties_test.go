@@ -0,0 +1,145 @@
+package language_test
+
+import (
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestSortParties(t *testing.T) {
+	parties := []m.Party{
+		m.Address("addr_b"),
+		m.Role{Name: "seller"},
+		m.Address("addr_a"),
+		m.Role{Name: "buyer"},
+	}
+
+	expected := []m.Party{m.Role{Name: "buyer"}, m.Role{Name: "seller"}, m.Address("addr_a"), m.Address("addr_b")}
+	got := m.SortParties(parties)
+
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got: %v", expected, got)
+		}
+	}
+	if parties[0] != m.Address("addr_b") {
+		t.Errorf("Expected the input to be left unmodified, got: %v", parties)
+	}
+}
+
+func TestParties_Deterministic(t *testing.T) {
+	contract := setupEscrowContract()
+	first := m.Parties(contract)
+
+	if len(first) != 2 || first[0] != m.Party(m.Role{Name: "buyer"}) || first[1] != m.Party(m.Role{Name: "seller"}) {
+		t.Fatalf("Expected [buyer seller], got: %v", first)
+	}
+
+	for i := 0; i < 100; i++ {
+		got := m.Parties(contract)
+		for j := range first {
+			if got[j] != first[j] {
+				t.Fatalf("Expected %v on every call, got: %v", first, got)
+			}
+		}
+	}
+}
+
+func TestWithdrawableByParty(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	collateral := m.Token{Symbol: "abcd", Name: "collateral"}
+	deposit := func(token m.Token, then m.Contract) m.Contract {
+		return m.When{
+			Cases: []m.Case{{
+				Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: token, Deposits: m.SetConstant("10")},
+				Then:   then,
+			}},
+			Timeout: m.POSIXTime(1000),
+			Then:    m.Close,
+		}
+	}
+
+	contract := deposit(m.Ada, deposit(collateral, m.Pay{
+		From:  buyer,
+		To:    m.Payee{Party: seller},
+		Token: m.Ada,
+		Pay:   m.SetConstant("10"),
+		Then:  m.Close,
+	}))
+
+	if tokens := m.WithdrawableByParty(contract, seller); len(tokens) != 1 || tokens[0] != m.Ada {
+		t.Errorf("Expected the seller to withdraw ADA, got: %v", tokens)
+	}
+	if tokens := m.WithdrawableByParty(contract, buyer); len(tokens) != 2 || tokens[0] != m.Ada || tokens[1] != collateral {
+		t.Errorf("Expected the buyer to withdraw ADA and the refunded collateral, got: %v", tokens)
+	}
+	if tokens := m.WithdrawableByParty(contract, m.Address("addr1_other")); len(tokens) != 0 {
+		t.Errorf("Expected another party to withdraw nothing, got: %v", tokens)
+	}
+}
+
+func TestTokensAndChoiceIds(t *testing.T) {
+	alice, bob := m.Role{Name: "alice"}, m.Address("addr_test1vz3ppzmmzuz0nlsjeyrqjm4pvdxl3cyfe8x06eg6htj2gwgv02qjt")
+	dollar := m.Token{Symbol: "85bb65", Name: "dollar"}
+	approve, price := m.ChoiceId{Name: "approve", Owner: alice}, m.ChoiceId{Name: "price", Owner: bob}
+
+	contract := m.When{
+		Cases: []m.Case{
+			{Action: m.Choice{ChoiceId: price, Bounds: []m.Bound{{Lower: 0, Upper: 100}}}, Then: m.If{
+				Observe: m.ChoseSomething{Choice: approve},
+				Then: m.Pay{
+					From:  alice,
+					To:    m.Payee{Party: bob},
+					Token: dollar,
+					Pay:   m.AddValue{Add: m.ChoiceValue{Value: price}, To: m.AvailableMoney{Amount: m.Ada, Account: alice}},
+					Then:  m.Close,
+				},
+				Else: m.Close,
+			}},
+			{Action: m.Deposit{IntoAccount: alice, Party: bob, Token: dollar, Deposits: m.SetConstant("5")}, Then: m.Close},
+		},
+		Timeout: m.POSIXTime(100),
+		Then:    m.Close,
+	}
+
+	tokens := m.Tokens(contract)
+	if len(tokens) != 2 || tokens[0] != m.Ada || tokens[1] != dollar {
+		t.Errorf("Expected [ADA %v], got: %v", dollar, tokens)
+	}
+
+	ids := m.ChoiceIds(contract)
+	if len(ids) != 2 || ids[0] != approve || ids[1] != price {
+		t.Errorf("Expected [%v %v], got: %v", approve, price, ids)
+	}
+
+	parties := m.Parties(contract)
+	if len(parties) != 2 || parties[0] != m.Party(alice) || parties[1] != m.Party(bob) {
+		t.Errorf("Expected both the role and the address, got: %v", parties)
+	}
+}
+
+func TestRequiredSignatories(t *testing.T) {
+	alice, bob := m.Role{Name: "alice"}, m.Role{Name: "bob"}
+	address := m.Address("addr_test1vz3ppzmmzuz0nlsjeyrqjm4pvdxl3cyfe8x06eg6htj2gwgv02qjt")
+
+	inputs := []m.Input{
+		m.NewIDeposit(bob, bob, m.Ada, big.NewInt(10)),
+		m.NewIDeposit(bob, address, m.Ada, big.NewInt(10)),
+		m.IChoice{ChoiceId: m.ChoiceId{Name: "approve", Owner: alice}, ChosenNum: m.SetChosenNum("1")},
+		m.IChoice{ChoiceId: m.ChoiceId{Name: "price", Owner: bob}, ChosenNum: m.SetChosenNum("5")},
+		m.INotify{},
+	}
+
+	addresses, roles := m.RequiredSignatories(inputs)
+	if len(addresses) != 1 || addresses[0] != address {
+		t.Errorf("Expected [%v], got: %v", address, addresses)
+	}
+	if len(roles) != 2 || roles[0] != alice || roles[1] != bob {
+		t.Errorf("Expected [alice bob], got: %v", roles)
+	}
+
+	if addresses, roles := m.RequiredSignatories([]m.Input{m.INotify{}}); len(addresses) != 0 || len(roles) != 0 {
+		t.Errorf("Expected no signatories for a notification, got: %v %v", addresses, roles)
+	}
+}
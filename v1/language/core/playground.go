@@ -0,0 +1,79 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// The metadata the Marlowe Playground keeps alongside a contract. A contract
+// exported from Go has no descriptions, so every field is left empty apart
+// from its type.
+type playgroundMetadata struct {
+	ContractType              string         `json:"contractType"`
+	ContractName              string         `json:"contractName"`
+	ContractShortDescription  string         `json:"contractShortDescription"`
+	ContractLongDescription   string         `json:"contractLongDescription"`
+	RoleDescriptions          map[string]any `json:"roleDescriptions"`
+	TimeParameterDescriptions map[string]any `json:"timeParameterDescriptions"`
+	ValueParameterInfo        map[string]any `json:"valueParameterInfo"`
+	ChoiceInfo                map[string]any `json:"choiceInfo"`
+}
+
+// ExportPlayground wraps the contract and its state in the envelope the
+// Marlowe Playground simulator loads, with the state serialized as the
+// Haskell implementation does and empty metadata:
+//
+//	{"contract": ..., "state": {"accounts": ..., ...}, "metadata": {...}}
+func ExportPlayground(c Contract, s State) ([]byte, error) {
+	return json.Marshal(struct {
+		Contract Contract           `json:"contract"`
+		State    stateJSON          `json:"state"`
+		Metadata playgroundMetadata `json:"metadata"`
+	}{c, newStateJSON(s), playgroundMetadata{
+		ContractType:              "Other",
+		RoleDescriptions:          map[string]any{},
+		TimeParameterDescriptions: map[string]any{},
+		ValueParameterInfo:        map[string]any{},
+		ChoiceInfo:                map[string]any{},
+	}})
+}
+
+// ImportPlayground reads the contract and state from a Marlowe Playground
+// envelope, as written by ExportPlayground, with LoadContractWithState. The
+// metadata is ignored, and a missing state is read as the empty State.
+func ImportPlayground(data []byte) (Contract, State, error) {
+	var document struct {
+		Contract json.RawMessage `json:"contract,omitempty"`
+		State    json.RawMessage `json:"state"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, State{}, err
+	}
+	if document.State == nil || string(document.State) == "null" {
+		empty, err := json.Marshal(newStateJSON(State{}))
+		if err != nil {
+			return nil, State{}, err
+		}
+		document.State = empty
+	}
+
+	envelope, err := json.Marshal(document)
+	if err != nil {
+		return nil, State{}, err
+	}
+	return LoadContractWithState(bytes.NewReader(envelope))
+}
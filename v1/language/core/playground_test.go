@@ -0,0 +1,56 @@
+package language_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestExportPlayground_RoundTrip(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	state := m.State{
+		Accounts:    m.Accounts{{AccountId: buyer, Token: m.Ada}: 50000000},
+		Choices:     map[m.ChoiceId]m.ChosenNum{{Name: "approve", Owner: buyer}: m.SetChosenNum("1")},
+		BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(7)},
+		MinTime:     1666078977926,
+	}
+
+	data, err := m.ExportPlayground(setupEscrowContract(), state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"contract", "state", "metadata"} {
+		if _, ok := envelope[key]; !ok {
+			t.Errorf("Expected %q in the envelope, got: %s", key, data)
+		}
+	}
+
+	contract, imported, err := m.ImportPlayground(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Equal(contract, setupEscrowContract()) {
+		t.Errorf("Expected the escrow contract, got: %v", contract)
+	}
+	if !m.EqualStates(imported, state) {
+		t.Errorf("Expected %v, got: %v", state, imported)
+	}
+}
+
+func TestImportPlayground_WithoutState(t *testing.T) {
+	contract, state, err := m.ImportPlayground([]byte(`{"contract":"close","metadata":{}}`))
+	if err != nil || contract != m.Close || !m.EqualStates(state, m.State{}) {
+		t.Errorf("Expected Close with an empty state, got: %v %v %v", contract, state, err)
+	}
+
+	if _, _, err := m.ImportPlayground([]byte(`{"state":{}}`)); err == nil {
+		t.Error("Expected an error for a missing contract")
+	}
+}
@@ -0,0 +1,984 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// MarshalCBOR encodes the contract as the CBOR of its Plutus data, which is
+// how marlowe-cardano stores it in the datum of a contract UTxO. Every type is
+// encoded by the constructor index makeIsDataIndexed gives it, e.g. Close=0,
+// Pay=1, If=2, When=3, Let=4 and Assert=5; role names, token names, choice
+// names and value ids as their UTF-8 bytes; and currency symbols and
+// merkleized continuation hashes as the bytes of their hex encoding. Address
+// parties are encoded as a Plutus Address together with their network, which
+// marlowe-cardano represents as a Bool that is False on mainnet. A contract
+// that still holds Extended parameters cannot be encoded.
+func MarshalCBOR(c Contract) ([]byte, error) {
+	data, err := contractData(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeData(&buf, data)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCBOR decodes a contract from the CBOR of its Plutus data, as
+// encoded by MarshalCBOR.
+func UnmarshalCBOR(data []byte) (Contract, error) {
+	r := &cborReader{data: data}
+	d, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	if r.pos != len(data) {
+		return nil, fmt.Errorf("cbor: %d bytes remain after the contract", len(data)-r.pos)
+	}
+	return dataContract(d)
+}
+
+func contractData(c Contract) (plutusData, error) {
+	switch c := c.(type) {
+	case CloseContract:
+		return constrData(0), nil
+	case Pay:
+		from, err := partyData(c.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := payeeData(c.To)
+		if err != nil {
+			return nil, err
+		}
+		token, err := tokenData(c.Token)
+		if err != nil {
+			return nil, err
+		}
+		value, err := valueData(c.Pay)
+		if err != nil {
+			return nil, err
+		}
+		then, err := contractData(c.Then)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(1, from, to, token, value, then), nil
+	case If:
+		obs, err := observationData(c.Observe)
+		if err != nil {
+			return nil, err
+		}
+		then, err := contractData(c.Then)
+		if err != nil {
+			return nil, err
+		}
+		els, err := contractData(c.Else)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(2, obs, then, els), nil
+	case When:
+		cases := make(plutusList, len(c.Cases))
+		for i, cs := range c.Cases {
+			var err error
+			if cases[i], err = caseData(cs); err != nil {
+				return nil, err
+			}
+		}
+		timeout, ok := c.Timeout.(POSIXTime)
+		if !ok {
+			return nil, fmt.Errorf("When timeout %v is not a POSIXTime", c.Timeout)
+		}
+		then, err := contractData(c.Then)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(3, cases, new(big.Int).SetUint64(uint64(timeout)), then), nil
+	case Let:
+		value, err := valueData(c.Value)
+		if err != nil {
+			return nil, err
+		}
+		then, err := contractData(c.Then)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(4, []byte(c.Name), value, then), nil
+	case Assert:
+		obs, err := observationData(c.Observe)
+		if err != nil {
+			return nil, err
+		}
+		then, err := contractData(c.Then)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(5, obs, then), nil
+	}
+
+	return nil, fmt.Errorf("cannot encode contract %v as Plutus data", c)
+}
+
+func caseData(c Case) (plutusData, error) {
+	action, err := actionData(c.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.MerkleizedThen != "" {
+		hash, err := hex.DecodeString(string(c.MerkleizedThen))
+		if err != nil {
+			return nil, fmt.Errorf("merkleized continuation %q is not hex: %v", c.MerkleizedThen, err)
+		}
+		return constrData(1, action, hash), nil
+	}
+
+	then, err := contractData(c.Then)
+	if err != nil {
+		return nil, err
+	}
+	return constrData(0, action, then), nil
+}
+
+func actionData(a Action) (plutusData, error) {
+	switch a := a.(type) {
+	case Deposit:
+		account, err := partyData(a.IntoAccount)
+		if err != nil {
+			return nil, err
+		}
+		party, err := partyData(a.Party)
+		if err != nil {
+			return nil, err
+		}
+		token, err := tokenData(a.Token)
+		if err != nil {
+			return nil, err
+		}
+		value, err := valueData(a.Deposits)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(0, account, party, token, value), nil
+	case Choice:
+		id, err := choiceIdData(a.ChoiceId)
+		if err != nil {
+			return nil, err
+		}
+		bounds := make(plutusList, len(a.Bounds))
+		for i, b := range a.Bounds {
+			bounds[i] = constrData(0, new(big.Int).SetUint64(b.Lower), new(big.Int).SetUint64(b.Upper))
+		}
+		return constrData(1, id, bounds), nil
+	case Notify:
+		obs, err := observationData(a.If)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(2, obs), nil
+	}
+
+	return nil, fmt.Errorf("cannot encode action %v as Plutus data", a)
+}
+
+func valueData(v Value) (plutusData, error) {
+	// Each binary node encodes both of its operands in turn.
+	binary := func(index uint64, x, y Value) (plutusData, error) {
+		a, err := valueData(x)
+		if err != nil {
+			return nil, err
+		}
+		b, err := valueData(y)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(index, a, b), nil
+	}
+
+	switch v := v.(type) {
+	case AvailableMoney:
+		account, err := partyData(v.Account)
+		if err != nil {
+			return nil, err
+		}
+		token, err := tokenData(v.Amount)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(0, account, token), nil
+	case Constant:
+		return constrData(1, new(big.Int).Set(v.Int())), nil
+	case NegValue:
+		neg, err := valueData(v.Neg)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(2, neg), nil
+	case AddValue:
+		return binary(3, v.Add, v.To)
+	case SubValue:
+		return binary(4, v.From, v.Subtract)
+	case MulValue:
+		return binary(5, v.Multiply, v.By)
+	case DivValue:
+		return binary(6, v.Divide, v.By)
+	case ChoiceValue:
+		id, err := choiceIdData(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(7, id), nil
+	case TimeIntervalValue:
+		switch v {
+		case TimeIntervalStart:
+			return constrData(8), nil
+		case TimeIntervalEnd:
+			return constrData(9), nil
+		}
+	case UseValue:
+		return constrData(10, []byte(v.Value)), nil
+	case Cond:
+		obs, err := observationData(v.Observation)
+		if err != nil {
+			return nil, err
+		}
+		ifTrue, ifFalse, err := valuePairData(v.IfTrue, v.IfFalse)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(11, obs, ifTrue, ifFalse), nil
+	}
+
+	return nil, fmt.Errorf("cannot encode value %v as Plutus data", v)
+}
+
+func valuePairData(x, y Value) (plutusData, plutusData, error) {
+	a, err := valueData(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := valueData(y)
+	return a, b, err
+}
+
+func observationData(o Observation) (plutusData, error) {
+	// Each binary observation encodes both of its operands in turn.
+	logical := func(index uint64, x, y Observation) (plutusData, error) {
+		a, err := observationData(x)
+		if err != nil {
+			return nil, err
+		}
+		b, err := observationData(y)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(index, a, b), nil
+	}
+	comparison := func(index uint64, x, y Value) (plutusData, error) {
+		a, b, err := valuePairData(x, y)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(index, a, b), nil
+	}
+
+	switch o := o.(type) {
+	case AndObs:
+		return logical(0, o.Both, o.And)
+	case OrObs:
+		return logical(1, o.Either, o.Or)
+	case NotObs:
+		not, err := observationData(o.Not)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(2, not), nil
+	case ChoseSomething:
+		id, err := choiceIdData(o.Choice)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(3, id), nil
+	case ValueGE:
+		return comparison(4, o.Value, o.Ge)
+	case ValueGT:
+		return comparison(5, o.Value, o.Gt)
+	case ValueLT:
+		return comparison(6, o.Value, o.Lt)
+	case ValueLE:
+		return comparison(7, o.Value, o.Le)
+	case ValueEQ:
+		return comparison(8, o.Value, o.Eq)
+	case BoolObs:
+		if o {
+			return constrData(9), nil
+		}
+		return constrData(10), nil
+	}
+
+	return nil, fmt.Errorf("cannot encode observation %v as Plutus data", o)
+}
+
+func payeeData(p Payee) (plutusData, error) {
+	if p.Account != nil {
+		account, err := partyData(p.Account)
+		if err != nil {
+			return nil, err
+		}
+		return constrData(0, account), nil
+	}
+
+	party, err := partyData(p.Party)
+	if err != nil {
+		return nil, err
+	}
+	return constrData(1, party), nil
+}
+
+func choiceIdData(id ChoiceId) (plutusData, error) {
+	owner, err := partyData(id.Owner)
+	if err != nil {
+		return nil, err
+	}
+	return constrData(0, []byte(id.Name), owner), nil
+}
+
+func tokenData(t Token) (plutusData, error) {
+	symbol, err := hex.DecodeString(t.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("currency symbol %q is not hex: %v", t.Symbol, err)
+	}
+	return constrData(0, symbol, []byte(t.Name)), nil
+}
+
+func partyData(p Party) (plutusData, error) {
+	switch p := p.(type) {
+	case Address:
+		return addressData(p)
+	case Role:
+		return constrData(1, []byte(p.Name)), nil
+	}
+
+	return nil, fmt.Errorf("cannot encode party %v as Plutus data", p)
+}
+
+// Encode the address party as its network and Plutus Address:
+//
+//	Address Network Address
+//	data Address = Address Credential (Maybe StakingCredential)
+func addressData(a Address) (plutusData, error) {
+	net, err := a.Network()
+	if err != nil {
+		return nil, err
+	}
+	kind, payload, err := a.shelley()
+	if err != nil {
+		return nil, err
+	}
+
+	// The Maybe StakingCredential, where Just=0 and Nothing=1.
+	var stake plutusData
+	switch kind {
+	case baseAddress:
+		credential := credentialData(payload[0]&0b0010_0000 != 0, payload[1+credentialSize:])
+		stake = constrData(0, constrData(0, credential))
+	case pointerAddress:
+		ptr, err := readPointer(payload[1+credentialSize:])
+		if err != nil {
+			return nil, fmt.Errorf("address %q: %v", string(a), err)
+		}
+		stake = constrData(0, constrData(1, ptr...))
+	default:
+		stake = constrData(1)
+	}
+
+	payment := credentialData(payload[0]&0b0001_0000 != 0, payload[1:1+credentialSize])
+	return constrData(0, networkData(net), constrData(0, payment, stake)), nil
+}
+
+// A PubKeyCredential is constructor 0 and a ScriptCredential constructor 1.
+func credentialData(script bool, hash []byte) plutusData {
+	if script {
+		return constrData(1, hash)
+	}
+	return constrData(0, hash)
+}
+
+// marlowe-cardano represents the network as a Bool, where mainnet is False
+// (constructor 0) and testnet is True (constructor 1).
+func networkData(net Network) plutusData {
+	if net == Mainnet {
+		return constrData(0)
+	}
+	return constrData(1)
+}
+
+// Read the slot, transaction index and certificate index of a stake pointer,
+// each a natural number in big-endian groups of 7 bits whose high bit is set
+// on all but the last byte.
+func readPointer(b []byte) ([]plutusData, error) {
+	var ptr []plutusData
+	for len(ptr) < 3 {
+		var n uint64
+		for {
+			if len(b) == 0 {
+				return nil, fmt.Errorf("truncated stake pointer")
+			}
+			if n > (1<<57)-1 {
+				return nil, fmt.Errorf("stake pointer overflows 64 bits")
+			}
+			n = n<<7 | uint64(b[0]&0x7f)
+			more := b[0]&0x80 != 0
+			b = b[1:]
+			if !more {
+				break
+			}
+		}
+		ptr = append(ptr, new(big.Int).SetUint64(n))
+	}
+	if len(b) != 0 {
+		return nil, fmt.Errorf("%d bytes follow the stake pointer", len(b))
+	}
+	return ptr, nil
+}
+
+func writePointer(buf *bytes.Buffer, n uint64) {
+	var groups []byte
+	for {
+		groups = append([]byte{byte(n & 0x7f)}, groups...)
+		n >>= 7
+		if n == 0 {
+			break
+		}
+	}
+	for i := 0; i < len(groups)-1; i++ {
+		groups[i] |= 0x80
+	}
+	buf.Write(groups)
+}
+
+// The fields of the constructor, if d applies the expected constructor of
+// the type to the expected number of fields.
+func fieldsOf(d plutusData, what string, index uint64, arity int) ([]plutusData, error) {
+	c, ok := d.(plutusConstr)
+	if !ok || c.index != index || len(c.fields) != arity {
+		return nil, fmt.Errorf("invalid %v data: %v", what, d)
+	}
+	return c.fields, nil
+}
+
+func constrOf(d plutusData, what string) (plutusConstr, error) {
+	c, ok := d.(plutusConstr)
+	if !ok {
+		return plutusConstr{}, fmt.Errorf("invalid %v data: %v", what, d)
+	}
+	return c, nil
+}
+
+func bytesOf(d plutusData, what string) ([]byte, error) {
+	b, ok := d.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("invalid %v data: %v", what, d)
+	}
+	return b, nil
+}
+
+func integerOf(d plutusData, what string) (*big.Int, error) {
+	n, ok := d.(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("invalid %v data: %v", what, d)
+	}
+	return n, nil
+}
+
+func uint64Of(d plutusData, what string) (uint64, error) {
+	n, err := integerOf(d, what)
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsUint64() {
+		return 0, fmt.Errorf("%v %v is out of range", what, n)
+	}
+	return n.Uint64(), nil
+}
+
+// Check that the constructor has the arity of its constructor within the type.
+func arity(c plutusConstr, what string, arities []int) error {
+	if c.index >= uint64(len(arities)) || len(c.fields) != arities[c.index] {
+		return fmt.Errorf("invalid %v data: constructor %d with %d fields", what, c.index, len(c.fields))
+	}
+	return nil
+}
+
+func dataContract(d plutusData) (Contract, error) {
+	c, err := constrOf(d, "contract")
+	if err != nil {
+		return nil, err
+	}
+	if err := arity(c, "contract", []int{0, 5, 3, 3, 3, 2}); err != nil {
+		return nil, err
+	}
+	f := c.fields
+
+	switch c.index {
+	case 0:
+		return Close, nil
+	case 1:
+		var pay Pay
+		if pay.From, err = dataParty(f[0]); err != nil {
+			return nil, err
+		}
+		if pay.To, err = dataPayee(f[1]); err != nil {
+			return nil, err
+		}
+		if pay.Token, err = dataToken(f[2]); err != nil {
+			return nil, err
+		}
+		if pay.Pay, err = dataValue(f[3]); err != nil {
+			return nil, err
+		}
+		pay.Then, err = dataContract(f[4])
+		return pay, err
+	case 2:
+		var cond If
+		if cond.Observe, err = dataObservation(f[0]); err != nil {
+			return nil, err
+		}
+		if cond.Then, err = dataContract(f[1]); err != nil {
+			return nil, err
+		}
+		cond.Else, err = dataContract(f[2])
+		return cond, err
+	case 3:
+		list, ok := f[0].(plutusList)
+		if !ok {
+			return nil, fmt.Errorf("invalid cases data: %v", f[0])
+		}
+		when := When{Cases: make([]Case, len(list))}
+		for i, cs := range list {
+			if when.Cases[i], err = dataCase(cs); err != nil {
+				return nil, err
+			}
+		}
+		timeout, err := uint64Of(f[1], "timeout")
+		if err != nil {
+			return nil, err
+		}
+		when.Timeout = POSIXTime(timeout)
+		when.Then, err = dataContract(f[2])
+		return when, err
+	case 4:
+		name, err := bytesOf(f[0], "value id")
+		if err != nil {
+			return nil, err
+		}
+		let := Let{Name: ValueId(name)}
+		if let.Value, err = dataValue(f[1]); err != nil {
+			return nil, err
+		}
+		let.Then, err = dataContract(f[2])
+		return let, err
+	default:
+		var assert Assert
+		if assert.Observe, err = dataObservation(f[0]); err != nil {
+			return nil, err
+		}
+		assert.Then, err = dataContract(f[1])
+		return assert, err
+	}
+}
+
+func dataCase(d plutusData) (Case, error) {
+	c, err := constrOf(d, "case")
+	if err != nil {
+		return Case{}, err
+	}
+	if err := arity(c, "case", []int{2, 2}); err != nil {
+		return Case{}, err
+	}
+
+	action, err := dataAction(c.fields[0])
+	if err != nil {
+		return Case{}, err
+	}
+
+	if c.index == 1 {
+		hash, err := bytesOf(c.fields[1], "continuation hash")
+		if err != nil {
+			return Case{}, err
+		}
+		return MerkleizedCase(action, Hash(hex.EncodeToString(hash))), nil
+	}
+
+	then, err := dataContract(c.fields[1])
+	return Case{Action: action, Then: then}, err
+}
+
+func dataAction(d plutusData) (Action, error) {
+	c, err := constrOf(d, "action")
+	if err != nil {
+		return nil, err
+	}
+	if err := arity(c, "action", []int{4, 2, 1}); err != nil {
+		return nil, err
+	}
+	f := c.fields
+
+	switch c.index {
+	case 0:
+		var deposit Deposit
+		if deposit.IntoAccount, err = dataParty(f[0]); err != nil {
+			return nil, err
+		}
+		if deposit.Party, err = dataParty(f[1]); err != nil {
+			return nil, err
+		}
+		if deposit.Token, err = dataToken(f[2]); err != nil {
+			return nil, err
+		}
+		deposit.Deposits, err = dataValue(f[3])
+		return deposit, err
+	case 1:
+		var choice Choice
+		if choice.ChoiceId, err = dataChoiceId(f[0]); err != nil {
+			return nil, err
+		}
+		list, ok := f[1].(plutusList)
+		if !ok {
+			return nil, fmt.Errorf("invalid bounds data: %v", f[1])
+		}
+		choice.Bounds = make([]Bound, len(list))
+		for i, b := range list {
+			bound, err := fieldsOf(b, "bound", 0, 2)
+			if err != nil {
+				return nil, err
+			}
+			if choice.Bounds[i].Lower, err = uint64Of(bound[0], "bound"); err != nil {
+				return nil, err
+			}
+			if choice.Bounds[i].Upper, err = uint64Of(bound[1], "bound"); err != nil {
+				return nil, err
+			}
+		}
+		return choice, nil
+	default:
+		obs, err := dataObservation(f[0])
+		return Notify{If: obs}, err
+	}
+}
+
+func dataValue(d plutusData) (Value, error) {
+	c, err := constrOf(d, "value")
+	if err != nil {
+		return nil, err
+	}
+	if err := arity(c, "value", []int{2, 1, 1, 2, 2, 2, 2, 1, 0, 0, 1, 3}); err != nil {
+		return nil, err
+	}
+	f := c.fields
+
+	// Each binary node decodes both of its operands in turn.
+	pair := func() (Value, Value, error) {
+		x, err := dataValue(f[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err := dataValue(f[1])
+		return x, y, err
+	}
+
+	switch c.index {
+	case 0:
+		var money AvailableMoney
+		if money.Account, err = dataParty(f[0]); err != nil {
+			return nil, err
+		}
+		money.Amount, err = dataToken(f[1])
+		return money, err
+	case 1:
+		n, err := integerOf(f[0], "constant")
+		if err != nil {
+			return nil, err
+		}
+		return NewConstant(new(big.Int).Set(n)), nil
+	case 2:
+		neg, err := dataValue(f[0])
+		return NegValue{Neg: neg}, err
+	case 3:
+		x, y, err := pair()
+		return AddValue{Add: x, To: y}, err
+	case 4:
+		x, y, err := pair()
+		return SubValue{From: x, Subtract: y}, err
+	case 5:
+		x, y, err := pair()
+		return MulValue{Multiply: x, By: y}, err
+	case 6:
+		x, y, err := pair()
+		return DivValue{Divide: x, By: y}, err
+	case 7:
+		id, err := dataChoiceId(f[0])
+		return ChoiceValue{Value: id}, err
+	case 8:
+		return TimeIntervalStart, nil
+	case 9:
+		return TimeIntervalEnd, nil
+	case 10:
+		name, err := bytesOf(f[0], "value id")
+		return UseValue{Value: ValueId(name)}, err
+	default:
+		var cond Cond
+		if cond.Observation, err = dataObservation(f[0]); err != nil {
+			return nil, err
+		}
+		if cond.IfTrue, err = dataValue(f[1]); err != nil {
+			return nil, err
+		}
+		cond.IfFalse, err = dataValue(f[2])
+		return cond, err
+	}
+}
+
+func dataObservation(d plutusData) (Observation, error) {
+	c, err := constrOf(d, "observation")
+	if err != nil {
+		return nil, err
+	}
+	if err := arity(c, "observation", []int{2, 2, 1, 1, 2, 2, 2, 2, 2, 0, 0}); err != nil {
+		return nil, err
+	}
+	f := c.fields
+
+	// Each binary observation decodes both of its operands in turn.
+	logical := func() (Observation, Observation, error) {
+		x, err := dataObservation(f[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err := dataObservation(f[1])
+		return x, y, err
+	}
+	comparison := func() (Value, Value, error) {
+		x, err := dataValue(f[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err := dataValue(f[1])
+		return x, y, err
+	}
+
+	switch c.index {
+	case 0:
+		x, y, err := logical()
+		return AndObs{Both: x, And: y}, err
+	case 1:
+		x, y, err := logical()
+		return OrObs{Either: x, Or: y}, err
+	case 2:
+		not, err := dataObservation(f[0])
+		return NotObs{Not: not}, err
+	case 3:
+		id, err := dataChoiceId(f[0])
+		return ChoseSomething{Choice: id}, err
+	case 4:
+		x, y, err := comparison()
+		return ValueGE{Value: x, Ge: y}, err
+	case 5:
+		x, y, err := comparison()
+		return ValueGT{Value: x, Gt: y}, err
+	case 6:
+		x, y, err := comparison()
+		return ValueLT{Value: x, Lt: y}, err
+	case 7:
+		x, y, err := comparison()
+		return ValueLE{Value: x, Le: y}, err
+	case 8:
+		x, y, err := comparison()
+		return ValueEQ{Value: x, Eq: y}, err
+	case 9:
+		return TrueObs, nil
+	default:
+		return FalseObs, nil
+	}
+}
+
+func dataPayee(d plutusData) (Payee, error) {
+	c, err := constrOf(d, "payee")
+	if err != nil {
+		return Payee{}, err
+	}
+	if err := arity(c, "payee", []int{1, 1}); err != nil {
+		return Payee{}, err
+	}
+
+	party, err := dataParty(c.fields[0])
+	if err != nil {
+		return Payee{}, err
+	}
+	if c.index == 0 {
+		return Payee{Account: party}, nil
+	}
+	return Payee{Party: party}, nil
+}
+
+func dataChoiceId(d plutusData) (ChoiceId, error) {
+	f, err := fieldsOf(d, "choice id", 0, 2)
+	if err != nil {
+		return ChoiceId{}, err
+	}
+	name, err := bytesOf(f[0], "choice name")
+	if err != nil {
+		return ChoiceId{}, err
+	}
+	owner, err := dataParty(f[1])
+	return ChoiceId{Name: string(name), Owner: owner}, err
+}
+
+func dataToken(d plutusData) (Token, error) {
+	f, err := fieldsOf(d, "token", 0, 2)
+	if err != nil {
+		return Token{}, err
+	}
+	symbol, err := bytesOf(f[0], "currency symbol")
+	if err != nil {
+		return Token{}, err
+	}
+	name, err := bytesOf(f[1], "token name")
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{Symbol: hex.EncodeToString(symbol), Name: string(name)}, nil
+}
+
+func dataParty(d plutusData) (Party, error) {
+	c, err := constrOf(d, "party")
+	if err != nil {
+		return nil, err
+	}
+	if err := arity(c, "party", []int{2, 1}); err != nil {
+		return nil, err
+	}
+
+	if c.index == 1 {
+		name, err := bytesOf(c.fields[0], "role")
+		return Role{Name: string(name)}, err
+	}
+	return dataAddress(c.fields[0], c.fields[1])
+}
+
+// Rebuild the Bech32 encoding of an address party from its network and
+// Plutus Address.
+func dataAddress(network, address plutusData) (Address, error) {
+	net, err := constrOf(network, "network")
+	if err != nil {
+		return "", err
+	}
+	if err := arity(net, "network", []int{0, 0}); err != nil {
+		return "", err
+	}
+	prefix := Mainnet.addressPrefix()
+	header := byte(Mainnet)
+	if net.index == 1 {
+		prefix, header = Testnet.addressPrefix(), byte(Testnet)
+	}
+
+	f, err := fieldsOf(address, "address", 0, 2)
+	if err != nil {
+		return "", err
+	}
+	script, payment, err := dataCredential(f[0])
+	if err != nil {
+		return "", err
+	}
+	if script {
+		header |= 0b0001_0000
+	}
+
+	var buf bytes.Buffer
+	buf.Write(payment)
+
+	stake, err := constrOf(f[1], "stake credential")
+	if err != nil {
+		return "", err
+	}
+	if err := arity(stake, "stake credential", []int{1, 0}); err != nil {
+		return "", err
+	}
+	if stake.index == 0 {
+		staking, err := constrOf(stake.fields[0], "staking credential")
+		if err != nil {
+			return "", err
+		}
+		if err := arity(staking, "staking credential", []int{1, 3}); err != nil {
+			return "", err
+		}
+		if staking.index == 0 {
+			script, hash, err := dataCredential(staking.fields[0])
+			if err != nil {
+				return "", err
+			}
+			if script {
+				header |= 0b0010_0000
+			}
+			header |= baseAddress << 4
+			buf.Write(hash)
+		} else {
+			header |= pointerAddress << 4
+			for _, field := range staking.fields {
+				n, err := uint64Of(field, "stake pointer")
+				if err != nil {
+					return "", err
+				}
+				writePointer(&buf, n)
+			}
+		}
+	} else {
+		header |= enterpriseAddress << 4
+	}
+
+	data, err := bech32.ConvertBits(append([]byte{header}, buf.Bytes()...), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := bech32.Encode(prefix, data)
+	return Address(encoded), err
+}
+
+// Decode a credential, reporting whether it is a script credential.
+func dataCredential(d plutusData) (bool, []byte, error) {
+	c, err := constrOf(d, "credential")
+	if err != nil {
+		return false, nil, err
+	}
+	if err := arity(c, "credential", []int{1, 1}); err != nil {
+		return false, nil, err
+	}
+	hash, err := bytesOf(c.fields[0], "credential")
+	if err != nil {
+		return false, nil, err
+	}
+	if len(hash) != credentialSize {
+		return false, nil, fmt.Errorf("credential hash has %d bytes, expected %d", len(hash), credentialSize)
+	}
+	return c.index == 1, hash, nil
+}
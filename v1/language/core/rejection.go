@@ -0,0 +1,94 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "math/big"
+
+// FindRejectingInput synthesizes an Input that the cases of a When contract
+// should refuse, so that contract authors can exercise their rejection paths in
+// negative tests. For a Choice it returns a ChosenNum that lies outside every
+// Bound offered for that ChoiceId; for a Deposit it returns an IDeposit of a
+// token that no matching Deposit case accepts.
+//
+// The second return value is false when c is not a When or when none of its
+// cases can be turned into a rejected input (e.g. it only offers Notify).
+// The Environment and State are accepted so that callers can pass the context
+// the input would be applied in.
+func FindRejectingInput(env Environment, state State, c Contract) (Input, bool) {
+	when, ok := c.(When)
+	if !ok {
+		return nil, false
+	}
+
+	for _, cs := range when.Cases {
+		switch action := cs.Action.(type) {
+		case Choice:
+			if num, ok := rejectedChoice(action.ChoiceId, when.Cases); ok {
+				return IChoice{ChoiceId: action.ChoiceId, ChosenNum: num}, true
+			}
+		case Deposit:
+			return IDeposit{
+				AccountId: action.IntoAccount,
+				Party:     action.Party,
+				Token:     rejectedToken(action, when.Cases),
+				Value:     new(big.Int),
+			}, true
+		}
+	}
+
+	return nil, false
+}
+
+// Find a number outside of every bound offered for the choice id across all
+// of the cases, since the first matching case would otherwise accept it.
+func rejectedChoice(id ChoiceId, cases []Case) (ChosenNum, bool) {
+	var bounds []Bound
+	for _, cs := range cases {
+		if choice, ok := cs.Action.(Choice); ok && choice.ChoiceId == id {
+			bounds = append(bounds, choice.Bounds...)
+		}
+	}
+
+	var highest uint64
+	for _, b := range bounds {
+		if b.Upper > highest {
+			highest = b.Upper
+		}
+	}
+
+	// A number above the highest bound is outside all of them.
+	candidate := new(big.Int).SetUint64(highest)
+	candidate.Add(candidate, big.NewInt(1))
+
+	return NewChosenNum(candidate), true
+}
+
+// Derive a token that no Deposit case for the same account and party accepts.
+func rejectedToken(deposit Deposit, cases []Case) Token {
+	token := Token{Symbol: deposit.Token.Symbol, Name: deposit.Token.Name + "_rejected"}
+
+	for accepted := true; accepted; {
+		accepted = false
+		for _, cs := range cases {
+			d, ok := cs.Action.(Deposit)
+			if ok && d.IntoAccount == deposit.IntoAccount && d.Party == deposit.Party && d.Token == token {
+				token.Name += "_"
+				accepted = true
+			}
+		}
+	}
+
+	return token
+}
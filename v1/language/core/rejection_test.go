@@ -0,0 +1,93 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// Helper function asserting that the contract, a When, rejects the input.
+func assertRejected(t *testing.T, contract m.Contract, input m.Input) {
+	t.Helper()
+	env := m.Environment{TimeInterval: m.NewTimeInterval(0, 10)}
+	if _, _, err := m.ApplyInput(env, m.State{}, input, contract.(m.When).Cases); err != (m.ApplyAllNoMatchError{}) {
+		t.Errorf("Expected %v to be rejected, got: %v", input, err)
+	}
+}
+
+func TestFindRejectingInput_Choice(t *testing.T) {
+	choiceId := m.ChoiceId{Name: "option", Owner: m.Role{Name: "creditor"}}
+	bounds := []m.Bound{{Lower: 0, Upper: 0}, {Lower: 3, Upper: 5}}
+	contract := setupWhenContract(m.Choice{ChoiceId: choiceId, Bounds: bounds})
+
+	input, ok := m.FindRejectingInput(m.Environment{}, m.State{}, contract)
+	if !ok {
+		t.Fatal("Expected a rejecting input for a Choice When")
+	}
+
+	choice, ok := input.(m.IChoice)
+	if !ok {
+		t.Fatalf("Expected an IChoice input, got: %T", input)
+	}
+
+	if choice.ChoiceId != choiceId {
+		t.Errorf("Expected choice id %v, got: %v", choiceId, choice.ChoiceId)
+	}
+
+	for _, b := range bounds {
+		if m.AcceptedNumbers([]m.Bound{b})(choice.ChosenNum.Int()) {
+			t.Errorf("Chosen number %v falls within bound %v", choice.ChosenNum, b)
+		}
+	}
+	assertRejected(t, contract, input)
+}
+
+func TestFindRejectingInput_SeveralCases(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	choiceId := m.ChoiceId{Name: "option", Owner: buyer}
+	contract := m.When{
+		Cases: []m.Case{
+			{Action: m.Choice{ChoiceId: choiceId, Bounds: []m.Bound{{Lower: 0, Upper: 2}}}, Then: m.Close},
+			{Action: m.Choice{ChoiceId: choiceId, Bounds: []m.Bound{{Lower: 3, Upper: 9}}}, Then: m.Close},
+			{Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("0")}, Then: m.Close},
+		},
+		Timeout: m.POSIXTime(100),
+		Then:    m.Close,
+	}
+
+	input, ok := m.FindRejectingInput(m.Environment{}, m.State{}, contract)
+	if !ok {
+		t.Fatal("Expected a rejecting input")
+	}
+	assertRejected(t, contract, input)
+}
+
+func TestFindRejectingInput_Deposit(t *testing.T) {
+	deposit := m.Deposit{
+		IntoAccount: m.Role{Name: "seller"},
+		Party:       m.Role{Name: "buyer"},
+		Token:       m.Ada,
+		Deposits:    m.SetConstant("50000000"),
+	}
+
+	input, ok := m.FindRejectingInput(m.Environment{}, m.State{}, setupWhenContract(deposit))
+	if !ok {
+		t.Fatal("Expected a rejecting input for a Deposit When")
+	}
+
+	iDeposit, ok := input.(m.IDeposit)
+	if !ok {
+		t.Fatalf("Expected an IDeposit input, got: %T", input)
+	}
+
+	if iDeposit.Token == deposit.Token {
+		t.Errorf("Expected a token other than %v", deposit.Token)
+	}
+	assertRejected(t, setupWhenContract(deposit), input)
+}
+
+func TestFindRejectingInput_NotWhen(t *testing.T) {
+	if _, ok := m.FindRejectingInput(m.Environment{}, m.State{}, m.Close); ok {
+		t.Error("Expected no rejecting input for a Close contract")
+	}
+}
@@ -15,6 +15,8 @@
 package language
 
 import (
+	"math/big"
+
 	"github.com/btcsuite/btcutil/bech32"
 )
 
@@ -28,3 +30,615 @@ func (a Address) ValidateEncoding() error {
 
 	return nil
 }
+
+// "2.2.10 Evaluating a Value
+//
+// Given the Environment and the current State, the evalValue function
+// evaluates a Value into a number" (§2.2.10)
+//
+// Evaluation uses arbitrary-precision arithmetic throughout. DivValue truncates
+// towards zero and evaluates to zero when dividing by zero; AvailableMoney,
+// ChoiceValue and UseValue evaluate to zero when there is no entry in the State.
+// A new big.Int is returned on every call, so the result may be modified freely.
+//
+// A node that cannot be evaluated, such as an Extended parameter that was not
+// instantiated, evaluates to zero; ComputeTransaction rejects it with a
+// TEUnsupportedNode instead.
+func EvalValue(env Environment, state State, v Value) *big.Int {
+	return (&direct{env: env, state: state}).value(v)
+}
+
+// An evaluation evaluates the operands of a Value or Observation for
+// evalValue and evalObservation, and is told of any node that cannot be
+// evaluated. The Evaluator takes operands apart itself, and only relies on
+// these rules for terms that have none.
+type evaluation interface {
+	value(v Value) *big.Int
+	observation(o Observation) bool
+	unsupported(node any)
+}
+
+// A direct evaluation evaluates every operand afresh, and records the first
+// node that cannot be evaluated.
+type direct struct {
+	env   Environment
+	state State
+	err   TransactionError
+}
+
+func (d *direct) value(v Value) *big.Int {
+	return evalValue(d, d.env, d.state, v)
+}
+
+func (d *direct) observation(o Observation) bool {
+	return evalObservation(d, d.env, d.state, o)
+}
+
+func (d *direct) unsupported(node any) {
+	if d.err == nil {
+		d.err = unsupportedNode(node)
+	}
+}
+
+// Evaluate the value as EvalValue does, or return a TEUnsupportedNode.
+func evalValueChecked(env Environment, state State, v Value) (*big.Int, TransactionError) {
+	d := &direct{env: env, state: state}
+	n := d.value(v)
+	return n, d.err
+}
+
+// Evaluate the observation as EvalObservation does, or return a
+// TEUnsupportedNode.
+func evalObservationChecked(env Environment, state State, o Observation) (bool, TransactionError) {
+	d := &direct{env: env, state: state}
+	b := d.observation(o)
+	return b, d.err
+}
+
+// Evaluate a single node of a Value, delegating its operands to the evaluation.
+// The result may be shared with the evaluation and must not be modified.
+func evalValue(e evaluation, env Environment, state State, v Value) *big.Int {
+	eval := e.value
+
+	switch v := v.(type) {
+	case AvailableMoney:
+		balance := state.Accounts[Account{AccountId: v.Account, Token: v.Amount}]
+		return new(big.Int).SetUint64(balance)
+	case Constant:
+		return new(big.Int).Set(v.Int())
+	case NegValue:
+		return new(big.Int).Neg(eval(v.Neg))
+	case AddValue:
+		return new(big.Int).Add(eval(v.Add), eval(v.To))
+	case SubValue:
+		return new(big.Int).Sub(eval(v.From), eval(v.Subtract))
+	case MulValue:
+		return new(big.Int).Mul(eval(v.Multiply), eval(v.By))
+	case DivValue:
+		n, d := eval(v.Divide), eval(v.By)
+		if d.Sign() == 0 {
+			return new(big.Int)
+		}
+		return new(big.Int).Quo(n, d)
+	case ChoiceValue:
+		return new(big.Int).Set(state.Choices[v.Value].Int())
+	case TimeIntervalValue:
+		if v == TimeIntervalStart {
+			return big.NewInt(int64(env.TimeInterval.start))
+		}
+		return big.NewInt(int64(env.TimeInterval.end))
+	case UseValue:
+		if bound, ok := state.BoundValues[v.Value]; ok {
+			return new(big.Int).Set(bound)
+		}
+		return new(big.Int)
+	case Cond:
+		if e.observation(v.Observation) {
+			return eval(v.IfTrue)
+		}
+		return eval(v.IfFalse)
+	}
+
+	e.unsupported(v)
+	return new(big.Int)
+}
+
+// "2.2.11 Evaluating an Observation
+//
+// Given the Environment and the current State, the evalObservation function
+// evaluates an Observation into a boolean" (§2.2.11)
+//
+// Comparisons evaluate their operands with EvalValue, and ChoseSomething
+// reports whether the State holds a value for the choice. Evaluation has no
+// side effects, so AndObs and OrObs short-circuit without changing the result.
+//
+// A node that cannot be evaluated is false, or zero for a Value, as in
+// EvalValue.
+func EvalObservation(env Environment, state State, o Observation) bool {
+	return (&direct{env: env, state: state}).observation(o)
+}
+
+// Evaluate a single node of an Observation, delegating its operands to the
+// evaluation.
+func evalObservation(e evaluation, env Environment, state State, o Observation) bool {
+	eval := e.observation
+	compare := func(x, y Value) int {
+		return e.value(x).Cmp(e.value(y))
+	}
+
+	switch o := o.(type) {
+	case AndObs:
+		return eval(o.Both) && eval(o.And)
+	case OrObs:
+		return eval(o.Either) || eval(o.Or)
+	case NotObs:
+		return !eval(o.Not)
+	case ChoseSomething:
+		_, ok := state.Choices[o.Choice]
+		return ok
+	case ValueGE:
+		return compare(o.Value, o.Ge) >= 0
+	case ValueGT:
+		return compare(o.Value, o.Gt) > 0
+	case ValueLT:
+		return compare(o.Value, o.Lt) < 0
+	case ValueLE:
+		return compare(o.Value, o.Le) <= 0
+	case ValueEQ:
+		return compare(o.Value, o.Eq) == 0
+	case BoolObs:
+		return bool(o)
+	}
+
+	e.unsupported(o)
+	return false
+}
+
+// "2.2.1 Compute Transaction
+//
+// The entry point of Marlowe semantics is the function computeTransaction.
+// It first trims the time interval of the transaction against the minTime of
+// the State, then applies every input of the transaction in order, reducing
+// the contract until it is quiescent before and after each input." (§2.2.1)
+//
+// A transaction that neither changes the contract nor pays out a Close
+// contract's remaining balances is rejected as a TEUselessTransaction.
+func ComputeTransaction(tx TransactionInput, state State, contract Contract) TransactionOutput {
+	return computeTransaction(tx, state, contract, nil)
+}
+
+// Compute the transaction, recording each step in the trace unless it is nil.
+func computeTransaction(tx TransactionInput, state State, contract Contract, trace *ExecutionTrace) TransactionOutput {
+	env, fixState, intervalErr := fixInterval(tx.Interval, state)
+	if intervalErr != nil {
+		return TransactionOutput{Error: TEIntervalError{IntervalError: intervalErr}}
+	}
+	if trace != nil {
+		trace.Interval = env.TimeInterval
+	}
+
+	result := applyAllInputs(env, fixState, contract, tx.Inputs, trace)
+	if result.err != nil {
+		return TransactionOutput{Error: result.err}
+	}
+
+	if _, closed := contract.(CloseContract); !result.changed && (!closed || len(state.Accounts) == 0) {
+		return TransactionOutput{Error: TEUselessTransaction{}}
+	}
+
+	return TransactionOutput{
+		Warnings: result.warnings,
+		Payments: result.payments,
+		State:    result.state,
+		Contract: result.contract,
+	}
+}
+
+// Trim the start of the interval to the minimum time of the state, so that
+// TimeIntervalStart never decreases between transactions.
+func fixInterval(interval TimeInterval, state State) (Environment, State, IntervalError) {
+	if interval.end < interval.start {
+		return Environment{}, state, InvalidInterval{Interval: interval}
+	}
+
+	if interval.end < state.MinTime {
+		return Environment{}, state, IntervalInPastError{MinTime: state.MinTime, Interval: interval}
+	}
+
+	if interval.start < state.MinTime {
+		interval.start = state.MinTime
+	}
+	state.MinTime = interval.start
+
+	return Environment{TimeInterval: interval}, state, nil
+}
+
+// The outcome of a single reduction step.
+type reduceStepResult uint8
+
+const (
+	notReduced reduceStepResult = iota
+	reduced
+)
+
+// Reduce the contract by one step that needs no input, returning the state and
+// continuation along with any warning or payment the step produced, or the
+// error that stops the reduction.
+func reduceContractStep(env Environment, state State, contract Contract) (reduceStepResult, State, Contract, Warning, *Payment, TransactionError) {
+	switch c := contract.(type) {
+	case CloseContract:
+		account, balance, ok := refundOne(state.Accounts)
+		if !ok {
+			return notReduced, state, c, nil, nil, nil
+		}
+		state.Accounts = state.Accounts.without(account)
+		payment := Payment{
+			PaymentFromAccount: account.AccountId,
+			To:                 Payee{Party: account.AccountId},
+			Token:              account.Token,
+			Amount:             new(big.Int).SetUint64(balance),
+		}
+		return reduced, state, c, nil, &payment, nil
+
+	case Pay:
+		amountToPay, err := evalValueChecked(env, state, c.Pay)
+		if err != nil {
+			return notReduced, state, c, nil, nil, err
+		}
+		if amountToPay.Sign() <= 0 {
+			warning := NonPositivePay{AccountId: c.From, Payee: c.To, Token: c.Token, Amount: amountToPay}
+			return reduced, state, c.Then, warning, nil, nil
+		}
+
+		account := Account{AccountId: c.From, Token: c.Token}
+		balance := new(big.Int).SetUint64(state.Accounts[account])
+		paidAmount := amountToPay
+		var warning Warning
+		if balance.Cmp(amountToPay) < 0 {
+			paidAmount = balance
+			warning = PartialPay{AccountId: c.From, Payee: c.To, Token: c.Token, Paid: paidAmount, Expected: amountToPay}
+		}
+
+		// The balance only falls, so it still fits.
+		newBalance := new(big.Int).Sub(balance, paidAmount)
+		state.Accounts = state.Accounts.with(account, newBalance.Uint64())
+		if c.To.Account != nil {
+			// Paying into an internal account moves the money rather than
+			// paying it out, although the Payment is still recorded.
+			accounts, err := state.Accounts.add(Account{AccountId: c.To.Account, Token: c.Token}, paidAmount)
+			if err != nil {
+				return notReduced, state, c, nil, nil, err
+			}
+			state.Accounts = accounts
+		}
+		payment := Payment{PaymentFromAccount: c.From, To: c.To, Token: c.Token, Amount: paidAmount}
+		return reduced, state, c.Then, warning, &payment, nil
+
+	case If:
+		observed, err := evalObservationChecked(env, state, c.Observe)
+		if err != nil {
+			return notReduced, state, c, nil, nil, err
+		}
+		if observed {
+			return reduced, state, c.Then, nil, nil, nil
+		}
+		return reduced, state, c.Else, nil, nil, nil
+
+	case When:
+		timeout, ok := c.Timeout.(POSIXTime)
+		if !ok {
+			return notReduced, state, c, nil, nil, unsupportedNode(c.Timeout)
+		}
+		if env.TimeInterval.Before(timeout) {
+			return notReduced, state, c, nil, nil, nil
+		}
+		if env.TimeInterval.After(timeout) {
+			return reduced, state, c.Then, nil, nil, nil
+		}
+		return notReduced, state, c, nil, nil, TEAmbiguousTimeIntervalError{}
+
+	case Let:
+		evaluatedValue, err := evalValueChecked(env, state, c.Value)
+		if err != nil {
+			return notReduced, state, c, nil, nil, err
+		}
+		var warning Warning
+		if oldValue, ok := state.BoundValues[c.Name]; ok {
+			warning = ShadowedLet{ValueId: c.Name, OldValue: oldValue, NewValue: evaluatedValue}
+		}
+		boundValues := make(map[ValueId]*big.Int, len(state.BoundValues)+1)
+		for id, v := range state.BoundValues {
+			boundValues[id] = v
+		}
+		boundValues[c.Name] = evaluatedValue
+		state.BoundValues = boundValues
+		return reduced, state, c.Then, warning, nil, nil
+
+	case Assert:
+		observed, err := evalObservationChecked(env, state, c.Observe)
+		if err != nil {
+			return notReduced, state, c, nil, nil, err
+		}
+		if !observed {
+			return reduced, state, c.Then, AssertionFailed{}, nil, nil
+		}
+		return reduced, state, c.Then, nil, nil, nil
+	}
+
+	return notReduced, state, contract, nil, nil, unsupportedNode(contract)
+}
+
+// Pick the first account, in the order of the Haskell implementation, that
+// has a positive balance to refund.
+func refundOne(accounts Accounts) (Account, uint64, bool) {
+	var first Account
+	found := false
+
+	for account, balance := range accounts {
+		if balance > 0 && (!found || lessAccount(account, first)) {
+			first, found = account, true
+		}
+	}
+
+	return first, accounts[first], found
+}
+
+// The result of reducing a contract until it is quiescent.
+type reduceResult struct {
+	reduced  bool
+	warnings []Warning
+	payments []Payment
+	state    State
+	contract Contract
+	err      TransactionError
+}
+
+// ReduceContractUntilQuiescent reduces every construct of the contract that
+// needs no input, i.e. Pay, If, Let, Assert and When constructs that have timed
+// out, until it reaches a When that is waiting for input or a Close with no
+// balances left to refund. The payments and warnings produced along the way
+// are returned in order. The input state is left unmodified.
+//
+// A When whose timeout falls within the time interval of the environment can
+// be neither waited on nor timed out, so an ApplyAllAmbiguousTimeIntervalError
+// is returned for it, an ApplyAllBalanceOverflow for a payment into an
+// account whose balance would overflow, and an ApplyAllUnsupportedNode for a
+// node that cannot be evaluated. On an error the input state and
+// contract are returned with no payments or warnings, as in ApplyAllInputs.
+func ReduceContractUntilQuiescent(env Environment, state State, contract Contract) (State, Contract, []Payment, []Warning, error) {
+	result := reduceContractUntilQuiescent(env, state, contract, nil)
+	if result.err != nil {
+		return state, contract, nil, nil, applyAllError(result.err)
+	}
+	return result.state, result.contract, result.payments, result.warnings, nil
+}
+
+// Reduce the contract until it is quiescent, recording each step in the trace
+// unless it is nil. On an ambiguous time interval or an overflowing balance the
+// error is set alongside the result of the reductions made so far.
+func reduceContractUntilQuiescent(env Environment, state State, contract Contract, trace *ExecutionTrace) reduceResult {
+	result := reduceResult{state: state, contract: contract}
+
+	for {
+		step, state, contract, warning, payment, err := reduceContractStep(env, result.state, result.contract)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		if step == notReduced {
+			return result
+		}
+
+		if trace != nil {
+			trace.Steps = append(trace.Steps, TraceStep{
+				Kind: ReduceStep, Contract: result.contract, Warning: warning, Payment: payment, State: state,
+			})
+		}
+
+		result.reduced = true
+		result.state, result.contract = state, contract
+		if warning != nil {
+			result.warnings = append(result.warnings, warning)
+		}
+		if payment != nil {
+			result.payments = append(result.payments, *payment)
+		}
+	}
+}
+
+// ApplyInput applies the input to the first of the cases of a When whose
+// action it matches, returning the updated state and the continuation of that
+// case, or an ApplyAllNoMatchError when no case matches. An
+// ApplyAllHashMismatch, ApplyAllBalanceOverflow or ApplyAllUnsupportedNode is
+// returned when the input cannot be applied to a case. The input state is
+// left unmodified. Warnings for non-positive deposits are reported by
+// ApplyAllInputs and ComputeTransaction.
+func ApplyInput(env Environment, state State, input Input, cases []Case) (State, Contract, error) {
+	newState, contract, _, err := applyCases(env, state, input, cases)
+	if err != nil {
+		return state, nil, applyAllError(err)
+	}
+	return newState, contract, nil
+}
+
+// Apply an input to the first case of a When whose action it matches. As in
+// marlowe-cardano, the case must then yield its continuation: a case that is
+// not merkleized takes an input that is not either, and a merkleized case
+// takes a MerkleizedInput carrying the continuation with the hash of the
+// case. Otherwise the input fails with a TEHashMismatch.
+func applyCases(env Environment, state State, input Input, cases []Case) (State, Contract, Warning, TransactionError) {
+	content := input
+	merkleized, isMerkleized := input.(MerkleizedInput)
+	if isMerkleized {
+		content = merkleized.Input
+	}
+
+	for _, cs := range cases {
+		newState, warning, err := applyAction(env, state, content, cs.Action)
+		if _, noMatch := err.(TEApplyNoMatchError); noMatch {
+			continue
+		}
+		if err != nil {
+			return state, nil, nil, err
+		}
+
+		switch {
+		case cs.MerkleizedThen == "" && !isMerkleized:
+			return newState, cs.Then, warning, nil
+		case cs.MerkleizedThen != "" && isMerkleized && merkleized.Hash == cs.MerkleizedThen:
+			// The ledger checks that the continuation hashes to the hash
+			// the input claims; check it here too.
+			if hash, err := continuationHash(merkleized.Continuation); err == nil && hash == cs.MerkleizedThen {
+				return newState, merkleized.Continuation, warning, nil
+			}
+		}
+		return state, nil, nil, TEHashMismatch{}
+	}
+
+	return state, nil, nil, TEApplyNoMatchError{}
+}
+
+func applyAction(env Environment, state State, input Input, action Action) (State, Warning, TransactionError) {
+	switch a := action.(type) {
+	case Deposit:
+		i, ok := input.(IDeposit)
+		if !ok || i.Value == nil || i.AccountId != a.IntoAccount || i.Party != a.Party || i.Token != a.Token {
+			return state, nil, TEApplyNoMatchError{}
+		}
+		deposits, err := evalValueChecked(env, state, a.Deposits)
+		if err != nil {
+			return state, nil, err
+		}
+		if i.Value.Cmp(deposits) != 0 {
+			return state, nil, TEApplyNoMatchError{}
+		}
+
+		amount := new(big.Int).Set(i.Value)
+		if amount.Sign() <= 0 {
+			return state, NonPositiveDeposit{Party: a.Party, AccountId: a.IntoAccount, Token: a.Token, Amount: amount}, nil
+		}
+
+		accounts, err := state.Accounts.add(Account{AccountId: i.AccountId, Token: i.Token}, amount)
+		if err != nil {
+			return state, nil, err
+		}
+		state.Accounts = accounts
+		return state, nil, nil
+
+	case Choice:
+		i, ok := input.(IChoice)
+		if !ok || i.ChoiceId != a.ChoiceId || !inBounds(i.ChosenNum, a.Bounds) {
+			return state, nil, TEApplyNoMatchError{}
+		}
+
+		choices := make(map[ChoiceId]ChosenNum, len(state.Choices)+1)
+		for id, num := range state.Choices {
+			choices[id] = num
+		}
+		choices[i.ChoiceId] = i.ChosenNum
+		state.Choices = choices
+		return state, nil, nil
+
+	case Notify:
+		if _, ok := input.(INotify); !ok {
+			return state, nil, TEApplyNoMatchError{}
+		}
+		observed, err := evalObservationChecked(env, state, a.If)
+		if err != nil {
+			return state, nil, err
+		}
+		if !observed {
+			return state, nil, TEApplyNoMatchError{}
+		}
+		return state, nil, nil
+	}
+
+	return state, nil, unsupportedNode(action)
+}
+
+func inBounds(num ChosenNum, bounds []Bound) bool {
+	return AcceptedNumbers(bounds)(num.Int())
+}
+
+// ApplyAllInputs applies the inputs in order to the contract, reducing the
+// contract until it is quiescent before the first input, between inputs and
+// after the last. It returns the final state and continuation along with every
+// payment and warning produced, or an ApplyAllNoMatchError,
+// ApplyAllAmbiguousTimeIntervalError, ApplyAllHashMismatch,
+// ApplyAllBalanceOverflow or ApplyAllUnsupportedNode. Unlike ComputeTransaction, the time
+// interval of the environment is used as is.
+func ApplyAllInputs(env Environment, state State, contract Contract, inputs []Input) (State, Contract, []Payment, []Warning, error) {
+	result := applyAllInputs(env, state, contract, inputs, nil)
+	if result.err != nil {
+		return state, contract, nil, nil, applyAllError(result.err)
+	}
+	return result.state, result.contract, result.payments, result.warnings, nil
+}
+
+// Return the error of ApplyAllInputs for the transaction error.
+func applyAllError(err TransactionError) error {
+	switch err := err.(type) {
+	case TEAmbiguousTimeIntervalError:
+		return ApplyAllAmbiguousTimeIntervalError{}
+	case TEHashMismatch:
+		return ApplyAllHashMismatch{}
+	case TEBalanceOverflow:
+		return ApplyAllBalanceOverflow{Account: err.Account}
+	case TEUnsupportedNode:
+		return ApplyAllUnsupportedNode{Node: err.Node}
+	}
+	return ApplyAllNoMatchError{}
+}
+
+// The result of applying every input of a transaction.
+type applyAllResult struct {
+	changed  bool
+	warnings []Warning
+	payments []Payment
+	state    State
+	contract Contract
+	err      TransactionError
+}
+
+func applyAllInputs(env Environment, state State, contract Contract, inputs []Input, trace *ExecutionTrace) applyAllResult {
+	result := applyAllResult{state: state, contract: contract}
+
+	for {
+		quiescent := reduceContractUntilQuiescent(env, result.state, result.contract, trace)
+		if quiescent.err != nil {
+			return applyAllResult{err: quiescent.err}
+		}
+
+		result.changed = result.changed || quiescent.reduced
+		result.warnings = append(result.warnings, quiescent.warnings...)
+		result.payments = append(result.payments, quiescent.payments...)
+		result.state, result.contract = quiescent.state, quiescent.contract
+
+		if len(inputs) == 0 {
+			return result
+		}
+
+		when, ok := result.contract.(When)
+		if !ok {
+			return applyAllResult{err: TEApplyNoMatchError{}}
+		}
+
+		state, contract, warning, err := applyCases(env, result.state, inputs[0], when.Cases)
+		if err != nil {
+			return applyAllResult{err: err}
+		}
+
+		if trace != nil {
+			trace.Steps = append(trace.Steps, TraceStep{
+				Kind: InputStep, Contract: when, Input: inputs[0], Warning: warning, State: state,
+			})
+		}
+
+		result.changed = true
+		result.state, result.contract = state, contract
+		if warning != nil {
+			result.warnings = append(result.warnings, warning)
+		}
+		inputs = inputs[1:]
+	}
+}
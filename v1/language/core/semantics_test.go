@@ -1,6 +1,8 @@
 package language_test
 
 import (
+	"math"
+	"math/big"
 	"testing"
 
 	lang "github.com/menabrealabs/marlowe/v1/language/core"
@@ -54,3 +56,448 @@ func TestAddress_ValidateEncoding_ShouldFail(t *testing.T) {
 		}
 	}
 }
+
+func TestEvalValue(t *testing.T) {
+	buyer := lang.Role{Name: "buyer"}
+	choiceId := lang.ChoiceId{Name: "price", Owner: buyer}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(1000, 2000)}
+	huge := "123456789012345678901234567890"
+	state := lang.State{
+		Accounts: lang.Accounts{{AccountId: buyer, Token: lang.Ada}: 50},
+		Choices: map[lang.ChoiceId]lang.ChosenNum{
+			choiceId:                     lang.SetChosenNum("7"),
+			{Name: "huge", Owner: buyer}: lang.SetChosenNum(huge),
+		},
+		BoundValues: map[lang.ValueId]*big.Int{"x": big.NewInt(-3)},
+	}
+
+	tests := []struct {
+		name     string
+		value    lang.Value
+		expected string
+	}{
+		{"Constant", lang.SetConstant(huge), huge},
+		{"NegValue", lang.NegValue{lang.SetConstant("5")}, "-5"},
+		{"AddValue", lang.AddValue{Add: lang.SetConstant(huge), To: lang.SetConstant(huge)}, "246913578024691357802469135780"},
+		{"SubValue", lang.SubValue{From: lang.SetConstant("10"), Subtract: lang.SetConstant("3")}, "7"},
+		{"MulValue", lang.MulValue{Multiply: lang.SetConstant(huge), By: lang.SetConstant("10")}, huge + "0"},
+		{"DivValue", lang.DivValue{Divide: lang.SetConstant("7"), By: lang.SetConstant("2")}, "3"},
+		{"DivValue truncates towards zero", lang.DivValue{Divide: lang.NegValue{lang.SetConstant("7")}, By: lang.SetConstant("2")}, "-3"},
+		{"DivValue by zero", lang.DivValue{Divide: lang.SetConstant("7"), By: lang.SetConstant("0")}, "0"},
+		{"AvailableMoney", lang.AvailableMoney{Amount: lang.Ada, Account: buyer}, "50"},
+		{"AvailableMoney missing", lang.AvailableMoney{Amount: lang.Ada, Account: lang.Role{Name: "seller"}}, "0"},
+		{"ChoiceValue", lang.ChoiceValue{Value: choiceId}, "7"},
+		{"ChoiceValue huge", lang.ChoiceValue{Value: lang.ChoiceId{Name: "huge", Owner: buyer}}, huge},
+		{"ChoiceValue missing", lang.ChoiceValue{Value: lang.ChoiceId{Name: "other", Owner: buyer}}, "0"},
+		{"UseValue", lang.UseValue{Value: "x"}, "-3"},
+		{"UseValue missing", lang.UseValue{Value: "y"}, "0"},
+		{"TimeIntervalStart", lang.TimeIntervalStart, "1000"},
+		{"TimeIntervalEnd", lang.TimeIntervalEnd, "2000"},
+		{"Cond", lang.Cond{Observation: lang.ValueGT{Value: lang.SetConstant("2"), Gt: lang.SetConstant("1")}, IfTrue: lang.SetConstant("1"), IfFalse: lang.SetConstant("0")}, "1"},
+		{"Cond false", lang.Cond{Observation: lang.FalseObs, IfTrue: lang.SetConstant("1"), IfFalse: lang.SetConstant("0")}, "0"},
+	}
+
+	for _, test := range tests {
+		if got := lang.EvalValue(env, state, test.value); got.String() != test.expected {
+			t.Errorf("%v: expected %v, got: %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestEvalValue_SubValueFromJSON(t *testing.T) {
+	// SubValue x y is x − y, with x under "value" and y under "minus".
+	v, err := lang.UnmarshalValue([]byte(`{"value":20,"minus":10}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lang.EvalValue(lang.Environment{}, lang.State{}, v); got.Int64() != 10 {
+		t.Errorf("Expected 20 minus 10 to be 10, got: %v", got)
+	}
+}
+
+func TestEvalObservation(t *testing.T) {
+	buyer := lang.Role{Name: "buyer"}
+	chosen := lang.ChoiceId{Name: "price", Owner: buyer}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(1000, 2000)}
+	state := lang.State{
+		Choices: map[lang.ChoiceId]lang.ChosenNum{chosen: lang.SetChosenNum("7")},
+	}
+
+	one, two := lang.SetConstant("1"), lang.SetConstant("2")
+
+	tests := []struct {
+		name        string
+		observation lang.Observation
+		expected    bool
+	}{
+		{"TrueObs", lang.TrueObs, true},
+		{"FalseObs", lang.FalseObs, false},
+		{"AndObs", lang.AndObs{Both: lang.TrueObs, And: lang.FalseObs}, false},
+		{"OrObs", lang.OrObs{Either: lang.FalseObs, Or: lang.TrueObs}, true},
+		{"NotObs", lang.NotObs{Not: lang.FalseObs}, true},
+		{"ChoseSomething", lang.ChoseSomething{Choice: chosen}, true},
+		{"ChoseSomething missing", lang.ChoseSomething{Choice: lang.ChoiceId{Name: "other", Owner: buyer}}, false},
+		{"ValueGE equal", lang.ValueGE{Value: one, Ge: one}, true},
+		{"ValueGE less", lang.ValueGE{Value: one, Ge: two}, false},
+		{"ValueGT", lang.ValueGT{Value: two, Gt: one}, true},
+		{"ValueGT equal", lang.ValueGT{Value: one, Gt: one}, false},
+		{"ValueLT", lang.ValueLT{Value: one, Lt: two}, true},
+		{"ValueLE equal", lang.ValueLE{Value: two, Le: two}, true},
+		{"ValueEQ", lang.ValueEQ{Value: lang.ChoiceValue{Value: chosen}, Eq: lang.SetConstant("7")}, true},
+		{"ValueEQ time", lang.ValueEQ{Value: lang.TimeIntervalStart, Eq: lang.SetConstant("1000")}, true},
+	}
+
+	for _, test := range tests {
+		if got := lang.EvalObservation(env, state, test.observation); got != test.expected {
+			t.Errorf("%v: expected %v, got: %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestComputeTransaction_Escrow(t *testing.T) {
+	buyer, seller := lang.Role{Name: "buyer"}, lang.Role{Name: "seller"}
+	contract := setupEscrowContract()
+	interval := lang.NewTimeInterval(1666000000000, 1666000001000)
+
+	deposit := lang.NewIDeposit(buyer, buyer, lang.Ada, big.NewInt(50000000))
+	approve := lang.IChoice{ChoiceId: lang.ChoiceId{Name: "approve", Owner: buyer}, ChosenNum: lang.SetChosenNum("1")}
+
+	out := lang.ComputeTransaction(lang.TransactionInput{Interval: interval, Inputs: []lang.Input{deposit}}, lang.State{}, contract)
+	if out.Error != nil {
+		t.Fatalf("Expected the deposit to succeed, got: %v", out.Error)
+	}
+	if got := out.State.Accounts[lang.Account{AccountId: buyer, Token: lang.Ada}]; got != 50000000 {
+		t.Errorf("Expected 50000000 in the buyer's account, got: %v", got)
+	}
+	if out.State.MinTime != 1666000000000 {
+		t.Errorf("Expected MinTime to advance to the interval start, got: %v", out.State.MinTime)
+	}
+
+	out = lang.ComputeTransaction(lang.TransactionInput{Interval: interval, Inputs: []lang.Input{approve}}, out.State, out.Contract)
+	if out.Error != nil {
+		t.Fatalf("Expected the choice to succeed, got: %v", out.Error)
+	}
+	if out.Contract != lang.Close {
+		t.Errorf("Expected the contract to close, got: %v", out.Contract)
+	}
+	if len(out.Payments) != 1 || out.Payments[0].To.Party != seller || out.Payments[0].Amount.Int64() != 50000000 {
+		t.Errorf("Expected a single payment of 50000000 to the seller, got: %v", out.Payments)
+	}
+	if len(out.State.Accounts) != 0 {
+		t.Errorf("Expected no remaining balances, got: %v", out.State.Accounts)
+	}
+}
+
+func TestComputeTransaction_Timeout(t *testing.T) {
+	buyer := lang.Role{Name: "buyer"}
+	state := lang.State{Accounts: lang.Accounts{{AccountId: buyer, Token: lang.Ada}: 10}}
+	contract := lang.When{Timeout: lang.POSIXTime(100), Then: lang.Close}
+
+	out := lang.ComputeTransaction(lang.TransactionInput{Interval: lang.NewTimeInterval(100, 200)}, state, contract)
+	if out.Error != nil {
+		t.Fatalf("Expected the timeout to succeed, got: %v", out.Error)
+	}
+	if len(out.Payments) != 1 || out.Payments[0].To.Party != buyer || out.Payments[0].Amount.Int64() != 10 {
+		t.Errorf("Expected the buyer to be refunded, got: %v", out.Payments)
+	}
+	if len(state.Accounts) != 1 {
+		t.Errorf("Expected the input state to be left unmodified, got: %v", state.Accounts)
+	}
+}
+
+func TestComputeTransaction_Errors(t *testing.T) {
+	buyer := lang.Role{Name: "buyer"}
+	contract := setupEscrowContract()
+
+	wrongDeposit := lang.NewIDeposit(buyer, buyer, lang.Ada, big.NewInt(1))
+
+	tests := []struct {
+		name     string
+		tx       lang.TransactionInput
+		contract lang.Contract
+		expected lang.TransactionError
+	}{
+		{
+			"no matching case",
+			lang.TransactionInput{Interval: lang.NewTimeInterval(0, 10), Inputs: []lang.Input{wrongDeposit}},
+			contract,
+			lang.TEApplyNoMatchError{},
+		},
+		{
+			"invalid interval",
+			lang.TransactionInput{Interval: lang.NewTimeInterval(10, 0)},
+			contract,
+			lang.TEIntervalError{IntervalError: lang.InvalidInterval{Interval: lang.NewTimeInterval(10, 0)}},
+		},
+		{
+			"ambiguous interval",
+			lang.TransactionInput{Interval: lang.NewTimeInterval(1666078977000, 1666078978000)},
+			contract,
+			lang.TEAmbiguousTimeIntervalError{},
+		},
+		{
+			"useless transaction",
+			lang.TransactionInput{Interval: lang.NewTimeInterval(0, 10)},
+			contract,
+			lang.TEUselessTransaction{},
+		},
+		{
+			"useless close",
+			lang.TransactionInput{Interval: lang.NewTimeInterval(0, 10)},
+			lang.Close,
+			lang.TEUselessTransaction{},
+		},
+	}
+
+	for _, test := range tests {
+		out := lang.ComputeTransaction(test.tx, lang.State{}, test.contract)
+		if out.Error != test.expected {
+			t.Errorf("%v: expected %#v, got: %#v", test.name, test.expected, out.Error)
+		}
+	}
+}
+
+func TestReduceContractUntilQuiescent(t *testing.T) {
+	buyer, seller := lang.Role{Name: "buyer"}, lang.Role{Name: "seller"}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(1000, 2000)}
+	state := lang.State{Accounts: lang.Accounts{{AccountId: buyer, Token: lang.Ada}: 50}}
+	awaiting := lang.When{Timeout: lang.POSIXTime(5000), Then: lang.Close}
+
+	contract := lang.When{
+		Timeout: lang.POSIXTime(500),
+		Then: lang.Let{
+			Name:  "price",
+			Value: lang.SetConstant("30"),
+			Then: lang.Pay{
+				From:  buyer,
+				To:    lang.Payee{Party: seller},
+				Token: lang.Ada,
+				Pay:   lang.UseValue{Value: "price"},
+				Then:  lang.Assert{Observe: lang.FalseObs, Then: awaiting},
+			},
+		},
+	}
+
+	newState, continuation, payments, warnings, err := lang.ReduceContractUntilQuiescent(env, state, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := continuation.(lang.When); !ok {
+		t.Errorf("Expected to stop at the When awaiting input, got: %v", continuation)
+	}
+	if len(payments) != 1 || payments[0].To.Party != seller || payments[0].Amount.Int64() != 30 {
+		t.Errorf("Expected a payment of 30 to the seller, got: %v", payments)
+	}
+	if got := newState.Accounts[lang.Account{AccountId: buyer, Token: lang.Ada}]; got != 20 {
+		t.Errorf("Expected 20 left in the buyer's account, got: %v", got)
+	}
+	if got := newState.BoundValues["price"]; got == nil || got.Int64() != 30 {
+		t.Errorf("Expected price to be bound to 30, got: %v", got)
+	}
+	if len(warnings) != 1 || warnings[0] != lang.Warning(lang.AssertionFailed{}) {
+		t.Errorf("Expected an AssertionFailed warning, got: %v", warnings)
+	}
+	if got := state.Accounts[lang.Account{AccountId: buyer, Token: lang.Ada}]; got != 50 {
+		t.Errorf("Expected the input state to be left unmodified, got: %v", got)
+	}
+}
+
+func TestApplyInput(t *testing.T) {
+	buyer := lang.Role{Name: "buyer"}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(0, 10)}
+	cases := setupEscrowContract().(lang.When).Cases
+
+	deposit := lang.NewIDeposit(buyer, buyer, lang.Ada, big.NewInt(50000000))
+
+	state, continuation, err := lang.ApplyInput(env, lang.State{}, deposit, cases)
+	if err != nil {
+		t.Fatalf("Expected the deposit to match, got: %v", err)
+	}
+	if got := state.Accounts[lang.Account{AccountId: buyer, Token: lang.Ada}]; got != 50000000 {
+		t.Errorf("Expected 50000000 in the buyer's account, got: %v", got)
+	}
+	if _, ok := continuation.(lang.When); !ok {
+		t.Errorf("Expected the continuation of the deposit case, got: %v", continuation)
+	}
+
+	_, _, err = lang.ApplyInput(env, lang.State{}, lang.INotify{}, cases)
+	if _, ok := err.(lang.ApplyAllNoMatchError); !ok {
+		t.Errorf("Expected an ApplyAllNoMatchError, got: %v", err)
+	}
+}
+
+func TestApplyInput_NegativeDeposit(t *testing.T) {
+	buyer := lang.Role{Name: "buyer"}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(0, 10)}
+	contract := setupWhenContract(lang.Deposit{IntoAccount: buyer, Party: buyer, Token: lang.Ada, Deposits: lang.SetConstant("-5")})
+
+	deposit := lang.NewIDeposit(buyer, buyer, lang.Ada, big.NewInt(-5))
+
+	state, continuation, err := lang.ApplyInput(env, lang.State{}, deposit, contract.(lang.When).Cases)
+	if err != nil {
+		t.Fatalf("Expected the negative deposit to match, got: %v", err)
+	}
+	if continuation != lang.Close || len(state.Accounts) != 0 {
+		t.Errorf("Expected nothing to be deposited, got: %v %v", continuation, state.Accounts)
+	}
+
+	_, _, _, warnings, err := lang.ApplyAllInputs(env, lang.State{}, contract, []lang.Input{deposit})
+	if err != nil {
+		t.Fatalf("Expected the negative deposit to apply, got: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("Expected a single warning, got: %v", warnings)
+	}
+	warning, ok := warnings[0].(lang.NonPositiveDeposit)
+	if !ok || warning.Party != buyer || warning.AccountId != buyer || warning.Amount.Cmp(big.NewInt(-5)) != 0 {
+		t.Errorf("Expected a NonPositiveDeposit of -5, got: %v", warnings[0])
+	}
+}
+
+func TestApplyAllInputs(t *testing.T) {
+	buyer, seller := lang.Role{Name: "buyer"}, lang.Role{Name: "seller"}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(0, 10)}
+
+	deposit := lang.NewIDeposit(buyer, buyer, lang.Ada, big.NewInt(50000000))
+	approve := lang.IChoice{ChoiceId: lang.ChoiceId{Name: "approve", Owner: buyer}, ChosenNum: lang.SetChosenNum("1")}
+
+	state, continuation, payments, _, err := lang.ApplyAllInputs(env, lang.State{}, setupEscrowContract(), []lang.Input{deposit, approve})
+	if err != nil {
+		t.Fatalf("Expected both inputs to apply, got: %v", err)
+	}
+	if continuation != lang.Close || len(state.Accounts) != 0 {
+		t.Errorf("Expected the contract to close with no balances, got: %v %v", continuation, state.Accounts)
+	}
+	if len(payments) != 1 || payments[0].To.Party != seller {
+		t.Errorf("Expected a single payment to the seller, got: %v", payments)
+	}
+
+	_, _, _, _, err = lang.ApplyAllInputs(env, lang.State{}, setupEscrowContract(), []lang.Input{approve})
+	if _, ok := err.(lang.ApplyAllNoMatchError); !ok {
+		t.Errorf("Expected an ApplyAllNoMatchError, got: %v", err)
+	}
+}
+
+func TestComputeTransaction_CloseRefundOrder(t *testing.T) {
+	alice, bob := lang.Role{Name: "alice"}, lang.Role{Name: "bob"}
+	address := lang.Address("addr_test1vz3ppzmmzuz0nlsjeyrqjm4pvdxl3cyfe8x06eg6htj2gwgv02qjt")
+	dollar := lang.Token{Symbol: "85bb65", Name: "dollar"}
+
+	state := lang.State{Accounts: lang.Accounts{
+		{AccountId: bob, Token: lang.Ada}:     4,
+		{AccountId: alice, Token: dollar}:     3,
+		{AccountId: alice, Token: lang.Ada}:   2,
+		{AccountId: address, Token: lang.Ada}: 1,
+	}}
+
+	out := lang.ComputeTransaction(lang.TransactionInput{Interval: lang.NewTimeInterval(0, 10)}, state, lang.Close)
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+
+	expected := []lang.Account{
+		{AccountId: address, Token: lang.Ada},
+		{AccountId: alice, Token: lang.Ada},
+		{AccountId: alice, Token: dollar},
+		{AccountId: bob, Token: lang.Ada},
+	}
+	if len(out.Payments) != len(expected) {
+		t.Fatalf("Expected %v refunds, got: %v", len(expected), out.Payments)
+	}
+	for i, account := range expected {
+		p := out.Payments[i]
+		if p.PaymentFromAccount != account.AccountId || p.To.Party != account.AccountId || p.Token != account.Token ||
+			p.Amount.Uint64() != state.Accounts[account] {
+			t.Errorf("Refund %v: expected the balance of %v, got: %v", i, account, p)
+		}
+	}
+	if len(out.State.Accounts) != 0 {
+		t.Errorf("Expected every account to be drained, got: %v", out.State.Accounts)
+	}
+}
+
+func TestReduceContractUntilQuiescent_PayToAccount(t *testing.T) {
+	buyer, seller := lang.Role{Name: "buyer"}, lang.Role{Name: "seller"}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(0, 10)}
+	state := lang.State{Accounts: lang.Accounts{{AccountId: buyer, Token: lang.Ada}: 50}}
+
+	contract := lang.Pay{
+		From:  buyer,
+		To:    lang.Payee{Account: seller},
+		Token: lang.Ada,
+		Pay:   lang.SetConstant("30"),
+		Then:  lang.When{Timeout: lang.POSIXTime(100), Then: lang.Close},
+	}
+
+	newState, _, payments, _, err := lang.ReduceContractUntilQuiescent(env, state, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := newState.Accounts[lang.Account{AccountId: buyer, Token: lang.Ada}]; got != 20 {
+		t.Errorf("Expected 20 left in the buyer's account, got: %v", got)
+	}
+	if got := newState.Accounts[lang.Account{AccountId: seller, Token: lang.Ada}]; got != 30 {
+		t.Errorf("Expected 30 transferred into the seller's account, got: %v", got)
+	}
+	if len(payments) != 1 || payments[0].To.Account != lang.AccountId(seller) {
+		t.Errorf("Expected the transfer to be recorded as a payment, got: %v", payments)
+	}
+}
+
+func TestComputeTransaction_BalanceOverflow(t *testing.T) {
+	buyer, seller := lang.Role{Name: "buyer"}, lang.Role{Name: "seller"}
+	full := lang.Account{AccountId: seller, Token: lang.Ada}
+	state := lang.State{Accounts: lang.Accounts{
+		{AccountId: buyer, Token: lang.Ada}: 10,
+		full:                                math.MaxUint64,
+	}}
+	interval := lang.NewTimeInterval(0, 10)
+
+	// A payment into the full account.
+	pay := lang.Pay{
+		From:  buyer,
+		To:    lang.Payee{Account: seller},
+		Token: lang.Ada,
+		Pay:   lang.SetConstant("1"),
+		Then:  lang.When{Timeout: lang.POSIXTime(100), Then: lang.Close},
+	}
+	out := lang.ComputeTransaction(lang.TransactionInput{Interval: interval}, state, pay)
+	if out.Error != (lang.TEBalanceOverflow{Account: full}) {
+		t.Errorf("Expected a TEBalanceOverflow for the payment, got: %v", out.Error)
+	}
+
+	// A deposit into the full account.
+	deposit := setupWhenContract(lang.Deposit{IntoAccount: seller, Party: buyer, Token: lang.Ada, Deposits: lang.SetConstant("1")})
+	input := lang.NewIDeposit(seller, buyer, lang.Ada, big.NewInt(1))
+	out = lang.ComputeTransaction(lang.TransactionInput{Interval: interval, Inputs: []lang.Input{input}}, state, deposit)
+	if out.Error != (lang.TEBalanceOverflow{Account: full}) {
+		t.Errorf("Expected a TEBalanceOverflow for the deposit, got: %v", out.Error)
+	}
+
+	env := lang.Environment{TimeInterval: interval}
+	_, _, err := lang.ApplyInput(env, state, input, deposit.(lang.When).Cases)
+	if err != (lang.ApplyAllBalanceOverflow{Account: full}) {
+		t.Errorf("Expected an ApplyAllBalanceOverflow, got: %v", err)
+	}
+}
+
+func TestReduceContractUntilQuiescent_AmbiguousInterval(t *testing.T) {
+	buyer, seller := lang.Role{Name: "buyer"}, lang.Role{Name: "seller"}
+	env := lang.Environment{TimeInterval: lang.NewTimeInterval(0, 200)}
+	state := lang.State{Accounts: lang.Accounts{{AccountId: buyer, Token: lang.Ada}: 50}}
+
+	// The payment is made before reaching the When, whose timeout lies
+	// within the interval.
+	when := lang.When{Timeout: lang.POSIXTime(100), Then: lang.Close}
+	contract := lang.Pay{From: buyer, To: lang.Payee{Party: seller}, Token: lang.Ada, Pay: lang.SetConstant("10"), Then: when}
+
+	newState, continuation, payments, warnings, err := lang.ReduceContractUntilQuiescent(env, state, contract)
+	if err != (lang.ApplyAllAmbiguousTimeIntervalError{}) {
+		t.Errorf("Expected an ApplyAllAmbiguousTimeIntervalError, got: %v", err)
+	}
+	if !lang.EqualStates(newState, state) || !lang.Equal(continuation, contract) || payments != nil || warnings != nil {
+		t.Errorf("Expected the input state and contract, got: %v %v %v %v", newState, continuation, payments, warnings)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// An Outcome is one terminal path through a contract: the conditions that
+// lead to it, in order, and the payouts made to parties along the way,
+// including the refunds made when the path closes.
+type Outcome struct {
+	Conditions []string
+	Payments   []SettlementPayment
+}
+
+// A SettlementPayment is a payout to a party. Amount is nil when the amount
+// cannot be known without running the contract, in which case Symbolic
+// describes it instead.
+type SettlementPayment struct {
+	From     AccountId
+	To       Party
+	Token    Token
+	Amount   *big.Int
+	Symbolic string
+}
+
+// The balances of a path: literal balances are tracked exactly, while an
+// account that has received or paid a computed amount is symbolic.
+type settlementBalances struct {
+	literal  map[Account]*big.Int
+	symbolic map[Account]bool
+}
+
+func (b settlementBalances) copy() settlementBalances {
+	c := settlementBalances{
+		literal:  make(map[Account]*big.Int, len(b.literal)),
+		symbolic: make(map[Account]bool, len(b.symbolic)),
+	}
+	for k, v := range b.literal {
+		c.literal[k] = v
+	}
+	for k, v := range b.symbolic {
+		c.symbolic[k] = v
+	}
+	return c
+}
+
+func (b settlementBalances) add(account Account, amount *big.Int) {
+	if b.symbolic[account] {
+		return
+	}
+	if balance, ok := b.literal[account]; ok {
+		amount = new(big.Int).Add(balance, amount)
+	}
+	b.literal[account] = amount
+}
+
+func (b settlementBalances) markSymbolic(account Account) {
+	delete(b.literal, account)
+	b.symbolic[account] = true
+}
+
+// SettlementOutcomes enumerates the terminal paths of the contract as a
+// decision tree of payouts, for documentation. Each path is labelled by the
+// actions, timeouts and If observations that lead to it, and lists the
+// payouts of its Pay constructs followed by the refunds made by its Close, in
+// the order Close makes them. Only constant amounts are followed exactly: a
+// computed amount, and any balance it flows into, is reported symbolically.
+// Paths through merkleized cases cannot be followed and are left out.
+func SettlementOutcomes(c Contract) []Outcome {
+	var outcomes []Outcome
+
+	var visit func(c Contract, conditions []string, payments []SettlementPayment, balances settlementBalances)
+	visit = func(c Contract, conditions []string, payments []SettlementPayment, balances settlementBalances) {
+		switch c := c.(type) {
+		case CloseContract:
+			outcomes = append(outcomes, Outcome{
+				Conditions: conditions,
+				Payments:   append(payments, settlementRefunds(balances)...),
+			})
+		case Pay:
+			balances = balances.copy()
+			payment, paid := settlementPay(c, balances)
+			if paid {
+				payments = appendPayment(payments, payment)
+			}
+			visit(c.Then, conditions, payments, balances)
+		case If:
+			observation := summarizeValue(c.Observe)
+			visit(c.Then, appendCondition(conditions, observation), payments, balances)
+			visit(c.Else, appendCondition(conditions, "it is not the case that "+observation), payments, balances)
+		case When:
+			for _, cs := range c.Cases {
+				if cs.MerkleizedThen != "" {
+					continue
+				}
+				caseBalances := balances
+				if deposit, ok := cs.Action.(Deposit); ok {
+					caseBalances = balances.copy()
+					settlementDeposit(deposit, caseBalances)
+				}
+				visit(cs.Then, appendCondition(conditions, summarizeAction(cs.Action)), payments, caseBalances)
+			}
+			timeout := fmt.Sprintf("nothing happens by %v", summarizeTimeout(c.Timeout))
+			visit(c.Then, appendCondition(conditions, timeout), payments, balances)
+		case Let:
+			visit(c.Then, conditions, payments, balances)
+		case Assert:
+			visit(c.Then, conditions, payments, balances)
+		}
+	}
+	visit(c, nil, nil, settlementBalances{literal: map[Account]*big.Int{}, symbolic: map[Account]bool{}})
+
+	return outcomes
+}
+
+// Append without sharing the backing array with sibling paths.
+func appendCondition(conditions []string, condition string) []string {
+	return append(conditions[:len(conditions):len(conditions)], condition)
+}
+
+func appendPayment(payments []SettlementPayment, payment SettlementPayment) []SettlementPayment {
+	return append(payments[:len(payments):len(payments)], payment)
+}
+
+func settlementDeposit(d Deposit, balances settlementBalances) {
+	account := Account{AccountId: d.IntoAccount, Token: d.Token}
+	amount, ok := d.Deposits.(Constant)
+	if !ok {
+		balances.markSymbolic(account)
+		return
+	}
+
+	// A non-positive deposit deposits nothing.
+	if n := amount.Int(); n.Sign() > 0 {
+		balances.add(account, new(big.Int).Set(n))
+	}
+}
+
+// Apply the Pay to the balances, returning the payout it makes to a party, if
+// any. As in reduceContractStep, a payment is capped at the balance of the
+// account and a non-positive payment pays nothing.
+func settlementPay(p Pay, balances settlementBalances) (SettlementPayment, bool) {
+	from := Account{AccountId: p.From, Token: p.Token}
+	payment := SettlementPayment{From: p.From, To: p.To.Party, Token: p.Token}
+
+	amount, ok := p.Pay.(Constant)
+	if !ok || balances.symbolic[from] {
+		balances.markSymbolic(from)
+		if p.To.Account != nil {
+			balances.markSymbolic(Account{AccountId: p.To.Account, Token: p.Token})
+			return payment, false
+		}
+		payment.Symbolic = fmt.Sprintf("up to %v", summarizeValue(p.Pay))
+		return payment, true
+	}
+
+	paid := new(big.Int).Set(amount.Int())
+	balance, ok := balances.literal[from]
+	if paid.Sign() <= 0 || !ok {
+		return payment, false
+	}
+	if paid.Cmp(balance) > 0 {
+		paid.Set(balance)
+	}
+	balances.literal[from] = new(big.Int).Sub(balance, paid)
+
+	if p.To.Account != nil {
+		balances.add(Account{AccountId: p.To.Account, Token: p.Token}, paid)
+		return payment, false
+	}
+	payment.Amount = paid
+	return payment, true
+}
+
+// The refunds made by Close, in the order it makes them.
+func settlementRefunds(balances settlementBalances) []SettlementPayment {
+	var accounts []Account
+	for account, balance := range balances.literal {
+		if balance.Sign() > 0 {
+			accounts = append(accounts, account)
+		}
+	}
+	for account := range balances.symbolic {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return lessAccount(accounts[i], accounts[j]) })
+
+	refunds := make([]SettlementPayment, len(accounts))
+	for i, account := range accounts {
+		refunds[i] = SettlementPayment{From: account.AccountId, To: account.AccountId, Token: account.Token}
+		if balances.symbolic[account] {
+			refunds[i].Symbolic = "the remaining balance"
+		} else {
+			refunds[i].Amount = balances.literal[account]
+		}
+	}
+
+	return refunds
+}
@@ -0,0 +1,94 @@
+package language_test
+
+import (
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestSettlementOutcomes_BinaryChoiceEscrow(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	choice := func(name string, then m.Contract) m.Case {
+		return m.Case{
+			Action: m.Choice{
+				ChoiceId: m.ChoiceId{Name: name, Owner: buyer},
+				Bounds:   []m.Bound{{Lower: 1, Upper: 1}},
+			},
+			Then: then,
+		}
+	}
+	contract := m.When{
+		Cases: []m.Case{{
+			Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("50")},
+			Then: m.When{
+				Cases: []m.Case{
+					choice("approve", m.Pay{From: buyer, To: m.Payee{Party: seller}, Token: m.Ada, Pay: m.SetConstant("50"), Then: m.Close}),
+					choice("reject", m.Close),
+				},
+				Timeout: m.POSIXTime(2000),
+				Then:    m.Close,
+			},
+		}},
+		Timeout: m.POSIXTime(1000),
+		Then:    m.Close,
+	}
+
+	outcomes := make(map[string]m.Outcome)
+	for _, outcome := range m.SettlementOutcomes(contract) {
+		outcomes[strings.Join(outcome.Conditions, "; ")] = outcome
+	}
+	if len(outcomes) != 4 {
+		t.Fatalf("Expected 4 outcomes, got: %v", outcomes)
+	}
+
+	tests := []struct {
+		choice string
+		payee  m.Party
+	}{
+		{"approve", seller},
+		{"reject", buyer},
+	}
+	for _, test := range tests {
+		var outcome *m.Outcome
+		for conditions, o := range outcomes {
+			if strings.Contains(conditions, `"`+test.choice+`"`) {
+				o := o
+				outcome = &o
+			}
+		}
+		if outcome == nil {
+			t.Fatalf("No outcome for %q in: %v", test.choice, outcomes)
+		}
+		if len(outcome.Conditions) != 2 {
+			t.Errorf("%v: expected the deposit and the choice as conditions, got: %v", test.choice, outcome.Conditions)
+		}
+		if len(outcome.Payments) != 1 {
+			t.Fatalf("%v: expected a single payout, got: %v", test.choice, outcome.Payments)
+		}
+		payment := outcome.Payments[0]
+		if payment.To != test.payee || payment.Amount == nil || payment.Amount.Int64() != 50 {
+			t.Errorf("%v: expected 50 paid to %v, got: %+v", test.choice, test.payee, payment)
+		}
+	}
+}
+
+func TestSettlementOutcomes_ComputedAmount(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	contract := m.When{
+		Cases: []m.Case{{
+			Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.UseValue{Value: "price"}},
+			Then:   m.Close,
+		}},
+		Timeout: m.POSIXTime(1000),
+		Then:    m.Close,
+	}
+
+	outcomes := m.SettlementOutcomes(contract)
+	if len(outcomes) != 2 || len(outcomes[0].Payments) != 1 {
+		t.Fatalf("Expected a refund on the deposit path, got: %v", outcomes)
+	}
+	if refund := outcomes[0].Payments[0]; refund.Amount != nil || refund.Symbolic == "" {
+		t.Errorf("Expected a symbolic refund, got: %+v", refund)
+	}
+}
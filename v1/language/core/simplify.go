@@ -0,0 +1,199 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "math/big"
+
+// SimplifyValue returns an equivalent value with its constant subexpressions
+// folded, identity operations such as adding 0 or multiplying by 1 removed,
+// and each Cond on a constant observation, or with equal branches, replaced by
+// the branch it takes. Since evaluating a value has no side effects and never
+// fails, dropping an operand never changes the result; folding follows
+// EvalValue, so division truncates towards zero and dividing by zero gives 0.
+// The value is not modified.
+func SimplifyValue(v Value) Value {
+	switch v := v.(type) {
+	case NegValue:
+		neg := SimplifyValue(v.Neg)
+		if n, ok := constantOf(neg); ok {
+			return fold(new(big.Int).Neg(n))
+		}
+		if inner, ok := neg.(NegValue); ok {
+			return inner.Neg
+		}
+		return NegValue{Neg: neg}
+	case AddValue:
+		x, y := SimplifyValue(v.Add), SimplifyValue(v.To)
+		switch {
+		case isConstant(x, 0):
+			return y
+		case isConstant(y, 0):
+			return x
+		}
+		if a, b, ok := constants(x, y); ok {
+			return fold(new(big.Int).Add(a, b))
+		}
+		return AddValue{Add: x, To: y}
+	case SubValue:
+		x, y := SimplifyValue(v.From), SimplifyValue(v.Subtract)
+		switch {
+		case isConstant(y, 0):
+			return x
+		case isConstant(x, 0):
+			return SimplifyValue(NegValue{Neg: y})
+		}
+		if a, b, ok := constants(x, y); ok {
+			return fold(new(big.Int).Sub(a, b))
+		}
+		return SubValue{From: x, Subtract: y}
+	case MulValue:
+		x, y := SimplifyValue(v.Multiply), SimplifyValue(v.By)
+		switch {
+		case isConstant(x, 0), isConstant(y, 0):
+			return fold(new(big.Int))
+		case isConstant(x, 1):
+			return y
+		case isConstant(y, 1):
+			return x
+		}
+		if a, b, ok := constants(x, y); ok {
+			return fold(new(big.Int).Mul(a, b))
+		}
+		return MulValue{Multiply: x, By: y}
+	case DivValue:
+		x, y := SimplifyValue(v.Divide), SimplifyValue(v.By)
+		switch {
+		case isConstant(y, 0), isConstant(x, 0):
+			return fold(new(big.Int))
+		case isConstant(y, 1):
+			return x
+		}
+		if a, b, ok := constants(x, y); ok {
+			return fold(new(big.Int).Quo(a, b))
+		}
+		return DivValue{Divide: x, By: y}
+	case Cond:
+		obs := SimplifyObservation(v.Observation)
+		ifTrue, ifFalse := SimplifyValue(v.IfTrue), SimplifyValue(v.IfFalse)
+		if b, ok := obs.(BoolObs); ok {
+			if b {
+				return ifTrue
+			}
+			return ifFalse
+		}
+		if EqualValues(ifTrue, ifFalse) {
+			return ifTrue
+		}
+		return Cond{Observation: obs, IfTrue: ifTrue, IfFalse: ifFalse}
+	}
+
+	return v
+}
+
+// SimplifyObservation returns an equivalent observation with its values
+// simplified by SimplifyValue, comparisons of constants and negations of
+// constants folded, double negations removed, and each AndObs and OrObs with
+// a constant operand reduced to the other operand or to its constant result.
+// The observation is not modified.
+func SimplifyObservation(o Observation) Observation {
+	// Each comparison folds to a constant when both operands do.
+	compare := func(x, y Value, holds func(int) bool, rebuild func(x, y Value) Observation) Observation {
+		x, y = SimplifyValue(x), SimplifyValue(y)
+		if a, b, ok := constants(x, y); ok {
+			return BoolObs(holds(a.Cmp(b)))
+		}
+		return rebuild(x, y)
+	}
+
+	switch o := o.(type) {
+	case AndObs:
+		x, y := SimplifyObservation(o.Both), SimplifyObservation(o.And)
+		if b, ok := x.(BoolObs); ok {
+			if b {
+				return y
+			}
+			return FalseObs
+		}
+		if b, ok := y.(BoolObs); ok {
+			if b {
+				return x
+			}
+			return FalseObs
+		}
+		return AndObs{Both: x, And: y}
+	case OrObs:
+		x, y := SimplifyObservation(o.Either), SimplifyObservation(o.Or)
+		if b, ok := x.(BoolObs); ok {
+			if b {
+				return TrueObs
+			}
+			return y
+		}
+		if b, ok := y.(BoolObs); ok {
+			if b {
+				return TrueObs
+			}
+			return x
+		}
+		return OrObs{Either: x, Or: y}
+	case NotObs:
+		not := SimplifyObservation(o.Not)
+		if b, ok := not.(BoolObs); ok {
+			return !b
+		}
+		if inner, ok := not.(NotObs); ok {
+			return inner.Not
+		}
+		return NotObs{Not: not}
+	case ValueGE:
+		return compare(o.Value, o.Ge, func(c int) bool { return c >= 0 }, func(x, y Value) Observation { return ValueGE{Value: x, Ge: y} })
+	case ValueGT:
+		return compare(o.Value, o.Gt, func(c int) bool { return c > 0 }, func(x, y Value) Observation { return ValueGT{Value: x, Gt: y} })
+	case ValueLT:
+		return compare(o.Value, o.Lt, func(c int) bool { return c < 0 }, func(x, y Value) Observation { return ValueLT{Value: x, Lt: y} })
+	case ValueLE:
+		return compare(o.Value, o.Le, func(c int) bool { return c <= 0 }, func(x, y Value) Observation { return ValueLE{Value: x, Le: y} })
+	case ValueEQ:
+		return compare(o.Value, o.Eq, func(c int) bool { return c == 0 }, func(x, y Value) Observation { return ValueEQ{Value: x, Eq: y} })
+	}
+
+	return o
+}
+
+func fold(n *big.Int) Value {
+	return NewConstant(n)
+}
+
+func constantOf(v Value) (*big.Int, bool) {
+	c, ok := v.(Constant)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).Set(c.Int()), true
+}
+
+func constants(x, y Value) (*big.Int, *big.Int, bool) {
+	a, ok := constantOf(x)
+	if !ok {
+		return nil, nil, false
+	}
+	b, ok := constantOf(y)
+	return a, b, ok
+}
+
+func isConstant(v Value, n int64) bool {
+	c, ok := constantOf(v)
+	return ok && c.Cmp(big.NewInt(n)) == 0
+}
@@ -0,0 +1,147 @@
+package language_test
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestSimplifyValue(t *testing.T) {
+	x := m.UseValue{Value: "x"}
+	obs := m.ValueGT{Value: x, Gt: m.SetConstant("0")}
+
+	tests := []struct {
+		name     string
+		value    m.Value
+		expected m.Value
+	}{
+		{"add zero", m.AddValue{Add: m.SetConstant("0"), To: x}, x},
+		{"multiply by one", m.MulValue{Multiply: x, By: m.SetConstant("1")}, x},
+		{"multiply by zero", m.MulValue{Multiply: x, By: m.SetConstant("0")}, m.SetConstant("0")},
+		{"subtract from zero", m.SubValue{From: m.SetConstant("0"), Subtract: x}, m.NegValue{Neg: x}},
+		{"double negation", m.NegValue{Neg: m.NegValue{Neg: x}}, x},
+		{"constant arithmetic", m.AddValue{Add: m.MulValue{Multiply: m.SetConstant("3"), By: m.SetConstant("4")}, To: m.SetConstant("-2")}, m.SetConstant("10")},
+		{"truncating division", m.DivValue{Divide: m.SetConstant("-7"), By: m.SetConstant("2")}, m.SetConstant("-3")},
+		{"division by zero", m.DivValue{Divide: x, By: m.SubValue{Subtract: m.SetConstant("1"), From: m.SetConstant("1")}}, m.SetConstant("0")},
+		{"divide by one", m.DivValue{Divide: x, By: m.SetConstant("1")}, x},
+		{"constant condition", m.Cond{Observation: m.ValueLT{Value: m.SetConstant("1"), Lt: m.SetConstant("2")}, IfTrue: x, IfFalse: m.SetConstant("0")}, x},
+		{"equal branches", m.Cond{Observation: obs, IfTrue: m.AddValue{Add: x, To: m.SetConstant("0")}, IfFalse: x}, x},
+		{"irreducible", m.AddValue{Add: x, To: m.SetConstant("1")}, m.AddValue{Add: x, To: m.SetConstant("1")}},
+	}
+
+	for _, test := range tests {
+		if got := m.SimplifyValue(test.value); !m.EqualValues(got, test.expected) {
+			t.Errorf("%v: expected %v, got: %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSimplifyObservation(t *testing.T) {
+	x := m.ChoseSomething{Choice: m.ChoiceId{Name: "c", Owner: m.Role{Name: "alice"}}}
+
+	tests := []struct {
+		name        string
+		observation m.Observation
+		expected    m.Observation
+	}{
+		{"and true", m.AndObs{Both: m.TrueObs, And: x}, x},
+		{"and false", m.AndObs{Both: x, And: m.FalseObs}, m.FalseObs},
+		{"or true", m.OrObs{Either: x, Or: m.TrueObs}, m.TrueObs},
+		{"or false", m.OrObs{Either: m.FalseObs, Or: x}, x},
+		{"not constant", m.NotObs{Not: m.ValueEQ{Value: m.SetConstant("1"), Eq: m.SetConstant("2")}}, m.TrueObs},
+		{"double negation", m.NotObs{Not: m.NotObs{Not: x}}, x},
+		{"folded comparison", m.ValueGE{Value: m.MulValue{Multiply: m.SetConstant("2"), By: m.SetConstant("3")}, Ge: m.SetConstant("6")}, m.TrueObs},
+	}
+
+	for _, test := range tests {
+		if got := m.SimplifyObservation(test.observation); !m.EqualObservations(got, test.expected) {
+			t.Errorf("%v: expected %v, got: %v", test.name, test.expected, got)
+		}
+	}
+}
+
+// Build a random value over small constants, the bound value "x" and the
+// choice "c", so that division by zero and identities occur often.
+func randomValue(r *rand.Rand, depth int) m.Value {
+	if depth == 0 || r.Intn(4) == 0 {
+		switch r.Intn(3) {
+		case 0:
+			return m.UseValue{Value: "x"}
+		case 1:
+			return m.ChoiceValue{Value: m.ChoiceId{Name: "c", Owner: m.Role{Name: "alice"}}}
+		}
+		return m.NewConstant(big.NewInt(int64(r.Intn(5) - 2)))
+	}
+
+	x, y := randomValue(r, depth-1), randomValue(r, depth-1)
+	switch r.Intn(6) {
+	case 0:
+		return m.NegValue{Neg: x}
+	case 1:
+		return m.AddValue{Add: x, To: y}
+	case 2:
+		return m.SubValue{Subtract: x, From: y}
+	case 3:
+		return m.MulValue{Multiply: x, By: y}
+	case 4:
+		return m.DivValue{Divide: x, By: y}
+	}
+	return m.Cond{Observation: m.ValueLE{Value: x, Le: randomValue(r, depth-1)}, IfTrue: x, IfFalse: y}
+}
+
+func TestSimplifyValue_PreservesSemantics(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	id := m.ChoiceId{Name: "c", Owner: m.Role{Name: "alice"}}
+
+	for i := 0; i < 2000; i++ {
+		value := randomValue(r, 4)
+		simplified := m.SimplifyValue(value)
+
+		for _, x := range []int64{-3, 0, 1, 7} {
+			for _, c := range []string{"-1", "0", "2"} {
+				state := m.State{
+					Choices:     map[m.ChoiceId]m.ChosenNum{id: m.SetChosenNum(c)},
+					BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(x)},
+				}
+				expected, got := m.EvalValue(m.Environment{}, state, value), m.EvalValue(m.Environment{}, state, simplified)
+				if expected.Cmp(got) != 0 {
+					t.Fatalf("%v simplified to %v, which evaluates to %v rather than %v with x=%v, c=%v", value, simplified, got, expected, x, c)
+				}
+			}
+		}
+	}
+}
+
+func TestSimplifyValue_SubValue(t *testing.T) {
+	x := m.UseValue{Value: "x"}
+	constant := func(n int64) m.Constant { return m.NewConstant(big.NewInt(n)) }
+
+	tests := []struct {
+		name     string
+		value    m.SubValue
+		expected m.Value
+	}{
+		{"positive minus negative", m.SubValue{From: constant(5), Subtract: constant(-3)}, constant(8)},
+		{"negative minus positive", m.SubValue{From: constant(-5), Subtract: constant(3)}, constant(-8)},
+		{"negative minus negative", m.SubValue{From: constant(-5), Subtract: constant(-3)}, constant(-2)},
+		{"minus zero", m.SubValue{From: x, Subtract: constant(0)}, x},
+		{"zero minus", m.SubValue{From: constant(0), Subtract: x}, m.NegValue{Neg: x}},
+		{"zero minus negative", m.SubValue{From: constant(0), Subtract: constant(-4)}, constant(4)},
+	}
+
+	for _, test := range tests {
+		simplified := m.SimplifyValue(test.value)
+		if !m.EqualValues(simplified, test.expected) {
+			t.Errorf("%v: expected %v, got: %v", test.name, test.expected, simplified)
+		}
+		for _, n := range []int64{-7, 0, 7} {
+			state := m.State{BoundValues: map[m.ValueId]*big.Int{"x": big.NewInt(n)}}
+			expected, got := m.EvalValue(m.Environment{}, state, test.value), m.EvalValue(m.Environment{}, state, simplified)
+			if expected.Cmp(got) != 0 {
+				t.Errorf("%v: evaluates to %v rather than %v with x=%v", test.name, got, expected, n)
+			}
+		}
+	}
+}
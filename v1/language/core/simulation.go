@@ -0,0 +1,135 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// SimulationResult collects the outcome of running a contract through a
+// sequence of transactions: every payment and warning generated along the
+// way, and the final state and continuation contract.
+type SimulationResult struct {
+	Payments []Payment
+	Warnings []Warning
+	State    State
+	Contract Contract
+}
+
+// Simulate runs the contract from the state through each transaction in turn,
+// stopping at the first transaction that fails with a TransactionError.
+func Simulate(contract Contract, state State, txs []TransactionInput) (SimulationResult, TransactionError) {
+	result, _, err := simulate(contract, state, txs)
+	return result, err
+}
+
+// Run the transactions as Simulate does, also returning the index of the
+// transaction that failed, if any.
+func simulate(contract Contract, state State, txs []TransactionInput) (SimulationResult, int, TransactionError) {
+	result := SimulationResult{State: state, Contract: contract}
+
+	for i, tx := range txs {
+		out := ComputeTransaction(tx, result.State, result.Contract)
+		if out.Error != nil {
+			return result, i, out.Error
+		}
+
+		result.Payments = append(result.Payments, out.Payments...)
+		result.Warnings = append(result.Warnings, out.Warnings...)
+		result.State, result.Contract = out.State, out.Contract
+	}
+
+	return result, len(txs), nil
+}
+
+// A TraceError reports the transaction of a trace that failed, by its index
+// in the trace, and the TransactionError it failed with.
+type TraceError struct {
+	Index int
+	Err   TransactionError
+}
+
+func (e TraceError) Error() string {
+	return fmt.Sprintf("transaction %d failed with %v", e.Index, transactionErrorName(e.Err))
+}
+
+// PlayTrace computes each transaction of the trace in turn, starting from the
+// initial state, as playTrace does in marlowe-cardano. It returns the final
+// state and continuation, and the payments and warnings of every transaction
+// in order. If a transaction fails, the trace stops there with a TraceError;
+// the state, continuation, payments and warnings returned are then those
+// reached by the transactions before it.
+func PlayTrace(initialState State, c Contract, txs []TransactionInput) (State, Contract, []Payment, []Warning, error) {
+	result, i, err := simulate(c, initialState, txs)
+	if err != nil {
+		return result.State, result.Contract, result.Payments, result.Warnings, TraceError{Index: i, Err: err}
+	}
+	return result.State, result.Contract, result.Payments, result.Warnings, nil
+}
+
+// IsClosed reports whether the simulation ran the contract to completion,
+// i.e. the contract reached Close and every account was refunded.
+func (r SimulationResult) IsClosed() bool {
+	_, ok := r.Contract.(CloseContract)
+	return ok
+}
+
+// RemainingContract returns the continuation the simulation stopped at, which
+// is Close when the contract completed.
+func (r SimulationResult) RemainingContract() Contract {
+	return r.Contract
+}
+
+// A PayoutKey identifies the payouts of one token to one party.
+type PayoutKey struct {
+	Party Party
+	Token Token
+}
+
+// PayoutShares computes each payee's share of the total amount paid out in
+// each token, as an exact fraction. The shares for any one token sum to 1.
+// Transfers between internal accounts are not payouts and are left out.
+func PayoutShares(result SimulationResult) map[PayoutKey]*big.Rat {
+	totals := make(map[Token]*big.Int)
+	for _, p := range result.Payments {
+		if p.To.Account != nil {
+			continue
+		}
+		if totals[p.Token] == nil {
+			totals[p.Token] = new(big.Int)
+		}
+		totals[p.Token].Add(totals[p.Token], p.Amount)
+	}
+
+	shares := make(map[PayoutKey]*big.Rat)
+	for _, p := range result.Payments {
+		if p.To.Account != nil {
+			continue
+		}
+		total := totals[p.Token]
+		if total.Sign() == 0 {
+			continue
+		}
+
+		key := PayoutKey{Party: p.To.Party, Token: p.Token}
+		if shares[key] == nil {
+			shares[key] = new(big.Rat)
+		}
+		shares[key].Add(shares[key], new(big.Rat).SetFrac(p.Amount, total))
+	}
+
+	return shares
+}
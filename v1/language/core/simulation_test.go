@@ -0,0 +1,146 @@
+package language_test
+
+import (
+	"math/big"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestPayoutShares(t *testing.T) {
+	token := m.Token{Symbol: "8bb3b343d8e404472337966a722150048c768d0a92a9813596c5338d", Name: "coin"}
+	result := m.SimulationResult{
+		Payments: []m.Payment{
+			{
+				PaymentFromAccount: m.Role{Name: "escrow"},
+				To:                 m.Payee{Party: m.Role{Name: "seller"}},
+				Token:              m.Ada,
+				Amount:             big.NewInt(70),
+			},
+			{
+				PaymentFromAccount: m.Role{Name: "escrow"},
+				To:                 m.Payee{Party: m.Role{Name: "broker"}},
+				Token:              m.Ada,
+				Amount:             big.NewInt(30),
+			},
+			// A party that prints like the seller's role.
+			{
+				PaymentFromAccount: m.Role{Name: "escrow"},
+				To:                 m.Payee{Party: m.Address("seller")},
+				Token:              token,
+				Amount:             big.NewInt(1),
+			},
+			{
+				PaymentFromAccount: m.Role{Name: "escrow"},
+				To:                 m.Payee{Party: m.Role{Name: "seller"}},
+				Token:              token,
+				Amount:             big.NewInt(3),
+			},
+		},
+		Contract: m.Close,
+	}
+
+	shares := m.PayoutShares(result)
+
+	expected := map[m.PayoutKey]*big.Rat{
+		{Party: m.Role{Name: "seller"}, Token: m.Ada}: big.NewRat(7, 10),
+		{Party: m.Role{Name: "broker"}, Token: m.Ada}: big.NewRat(3, 10),
+		{Party: m.Address("seller"), Token: token}:    big.NewRat(1, 4),
+		{Party: m.Role{Name: "seller"}, Token: token}: big.NewRat(3, 4),
+	}
+
+	if len(shares) != len(expected) {
+		t.Fatalf("Expected %v shares, got: %v", len(expected), shares)
+	}
+
+	for key, share := range expected {
+		if shares[key] == nil || shares[key].Cmp(share) != 0 {
+			t.Errorf("Expected share %v for %v, got: %v", share, key, shares[key])
+		}
+	}
+}
+
+func TestSimulate_IsClosed(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	interval := m.NewTimeInterval(1666000000000, 1666000001000)
+
+	deposit := m.NewIDeposit(buyer, buyer, m.Ada, big.NewInt(50000000))
+	approve := m.IChoice{ChoiceId: m.ChoiceId{Name: "approve", Owner: buyer}, ChosenNum: m.SetChosenNum("1")}
+
+	full := []m.TransactionInput{
+		{Interval: interval, Inputs: []m.Input{deposit}},
+		{Interval: interval, Inputs: []m.Input{approve}},
+	}
+
+	result, err := m.Simulate(setupEscrowContract(), m.State{}, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsClosed() {
+		t.Errorf("Expected the full escrow to close, remaining: %v", result.RemainingContract())
+	}
+
+	result, err = m.Simulate(setupEscrowContract(), m.State{}, full[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsClosed() {
+		t.Error("Expected the partial escrow not to close")
+	}
+	if when, ok := result.RemainingContract().(m.When); !ok || when.Timeout != m.POSIXTime(1666165377926) {
+		t.Errorf("Expected to remain at the approval When, got: %v", result.RemainingContract())
+	}
+}
+
+func TestPlayTrace(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	interval := m.NewTimeInterval(1666000000000, 1666000001000)
+
+	deposit := m.NewIDeposit(buyer, buyer, m.Ada, big.NewInt(50000000))
+	approve := m.IChoice{ChoiceId: m.ChoiceId{Name: "approve", Owner: buyer}, ChosenNum: m.SetChosenNum("1")}
+	trace := []m.TransactionInput{
+		{Interval: interval, Inputs: []m.Input{deposit}},
+		{Interval: interval, Inputs: []m.Input{approve}},
+	}
+
+	state, contract, payments, warnings, err := m.PlayTrace(m.State{}, setupEscrowContract(), trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contract != m.Close || len(state.Accounts) != 0 {
+		t.Errorf("Expected the escrow to close with no accounts, got: %v, %v", contract, state.Accounts)
+	}
+	if len(payments) != 1 || payments[0].Amount.Int64() != 50000000 || payments[0].To.Party != m.Party(m.Role{Name: "seller"}) {
+		t.Errorf("Expected a single payment of 50 ADA to the seller, got: %v", payments)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got: %v", warnings)
+	}
+}
+
+func TestPlayTrace_StopsAtFirstError(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	interval := m.NewTimeInterval(1666000000000, 1666000001000)
+
+	deposit := m.NewIDeposit(buyer, buyer, m.Ada, big.NewInt(50000000))
+	trace := []m.TransactionInput{
+		{Interval: interval, Inputs: []m.Input{deposit}},
+		{Interval: interval, Inputs: []m.Input{deposit}},
+		{Interval: interval, Inputs: []m.Input{m.INotify{}}},
+	}
+
+	state, contract, _, _, err := m.PlayTrace(m.State{}, setupEscrowContract(), trace)
+	traceErr, ok := err.(m.TraceError)
+	if !ok || traceErr.Index != 1 || traceErr.Err != (m.TEApplyNoMatchError{}) {
+		t.Fatalf("Expected the second transaction to fail with TEApplyNoMatchError, got: %v", err)
+	}
+
+	// The state and contract are those reached by the first deposit.
+	account := m.Account{AccountId: buyer, Token: m.Ada}
+	if state.Accounts[account] != 50000000 {
+		t.Errorf("Expected the first deposit to be kept, got: %v", state.Accounts)
+	}
+	if when, ok := contract.(m.When); !ok || when.Timeout != m.POSIXTime(1666165377926) {
+		t.Errorf("Expected to stop at the approval When, got: %v", contract)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Summarize describes a contract in plain English for review by
+// non-technical stakeholders, e.g. "buyer deposits 50 ADA into seller's
+// account; ...". Each construct is rendered from a sentence template, so the
+// summary follows the structure of the contract rather than paraphrasing it.
+func Summarize(c Contract) string {
+	summary := summarizeContract(c)
+	return strings.ToUpper(summary[:1]) + summary[1:] + "."
+}
+
+func summarizeContract(c Contract) string {
+	switch c := c.(type) {
+	case CloseContract:
+		return "the contract closes, refunding any remaining balances to their owners"
+	case Pay:
+		return fmt.Sprintf("pay %v from %v's account to %v; then %v",
+			summarizeAmount(c.Pay, c.Token), c.From, summarizePayee(c.To), summarizeContract(c.Then))
+	case If:
+		return fmt.Sprintf("if %v, %v; otherwise, %v",
+			summarizeValue(c.Observe), summarizeContract(c.Then), summarizeContract(c.Else))
+	case When:
+		if len(c.Cases) == 0 {
+			return fmt.Sprintf("wait until %v; then %v", summarizeTimeout(c.Timeout), summarizeContract(c.Then))
+		}
+		cases := make([]string, len(c.Cases))
+		for i, cs := range c.Cases {
+			cases[i] = fmt.Sprintf("if %v by %v, %v",
+				summarizeAction(cs.Action), summarizeTimeout(c.Timeout), summarizeContinuation(cs))
+		}
+		return fmt.Sprintf("%v; if none of this happens by %v, %v",
+			strings.Join(cases, "; or "), summarizeTimeout(c.Timeout), summarizeContract(c.Then))
+	case Let:
+		return fmt.Sprintf("let %q be %v; then %v", c.Name, summarizeValue(c.Value), summarizeContract(c.Then))
+	case Assert:
+		return fmt.Sprintf("check that %v; then %v", summarizeValue(c.Observe), summarizeContract(c.Then))
+	}
+
+	return fmt.Sprintf("%v", c)
+}
+
+// A merkleized continuation is known only by its hash, which the input that
+// takes the case must supply along with the continuation itself.
+func summarizeContinuation(cs Case) string {
+	if cs.MerkleizedThen != "" {
+		return fmt.Sprintf("continue with the merkleized contract %v", cs.MerkleizedThen)
+	}
+	return summarizeContract(cs.Then)
+}
+
+func summarizeAction(a Action) string {
+	switch a := a.(type) {
+	case Deposit:
+		return fmt.Sprintf("%v deposits %v into %v's account",
+			a.Party, summarizeAmount(a.Deposits, a.Token), a.IntoAccount)
+	case Choice:
+		bounds := make([]string, len(a.Bounds))
+		for i, b := range a.Bounds {
+			if b.Lower == b.Upper {
+				bounds[i] = fmt.Sprint(b.Lower)
+			} else {
+				bounds[i] = fmt.Sprintf("%v to %v", b.Lower, b.Upper)
+			}
+		}
+		return fmt.Sprintf("%v chooses %q (%v)", a.ChoiceId.Owner, a.ChoiceId.Name, strings.Join(bounds, " or "))
+	case Notify:
+		return fmt.Sprintf("anyone notifies the contract that %v", summarizeValue(a.If))
+	}
+
+	return fmt.Sprintf("%v", a)
+}
+
+func summarizePayee(p Payee) string {
+	if p.Account != nil {
+		return fmt.Sprintf("%v's account", p.Account)
+	}
+	return fmt.Sprint(p.Party)
+}
+
+func summarizeTimeout(t Timeout) string {
+	if t, ok := t.(POSIXTime); ok {
+		return time.UnixMilli(int64(t)).UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("<%v>", t)
+}
+
+// Constant amounts of ADA are stated in ADA rather than lovelace.
+func summarizeAmount(v Value, t Token) string {
+	if c, ok := v.(Constant); ok && t == Ada {
+		ada := new(big.Rat).SetFrac(c.Int(), big.NewInt(1_000_000))
+		if ada.IsInt() {
+			return ada.Num().String() + " ADA"
+		}
+		return strings.TrimRight(ada.FloatString(6), "0") + " ADA"
+	}
+	return fmt.Sprintf("%v of %v", summarizeValue(v), t)
+}
+
+func summarizeValue(v Value) string {
+	switch v := v.(type) {
+	case Constant:
+		return v.Int().String()
+	case AvailableMoney:
+		return fmt.Sprintf("the amount of %v in %v's account", v.Amount, v.Account)
+	case ChoiceValue:
+		return fmt.Sprintf("the number %v chose for %q", v.Value.Owner, v.Value.Name)
+	case UseValue:
+		return fmt.Sprintf("the value of %q", v.Value)
+	case TimeIntervalValue:
+		if v == TimeIntervalStart {
+			return "the start of the transaction's time interval"
+		}
+		return "the end of the transaction's time interval"
+	case NegValue:
+		return fmt.Sprintf("-(%v)", summarizeValue(v.Neg))
+	case AddValue:
+		return fmt.Sprintf("(%v + %v)", summarizeValue(v.Add), summarizeValue(v.To))
+	case SubValue:
+		return fmt.Sprintf("(%v - %v)", summarizeValue(v.From), summarizeValue(v.Subtract))
+	case MulValue:
+		return fmt.Sprintf("(%v * %v)", summarizeValue(v.Multiply), summarizeValue(v.By))
+	case DivValue:
+		return fmt.Sprintf("(%v / %v)", summarizeValue(v.Divide), summarizeValue(v.By))
+	case Cond:
+		return fmt.Sprintf("(%v if %v, otherwise %v)", summarizeValue(v.IfTrue), summarizeValue(v.Observation), summarizeValue(v.IfFalse))
+	case AndObs:
+		return fmt.Sprintf("both %v and %v", summarizeValue(v.Both), summarizeValue(v.And))
+	case OrObs:
+		return fmt.Sprintf("either %v or %v", summarizeValue(v.Either), summarizeValue(v.Or))
+	case NotObs:
+		return fmt.Sprintf("it is not the case that %v", summarizeValue(v.Not))
+	case ChoseSomething:
+		return fmt.Sprintf("%v has made a choice for %q", v.Choice.Owner, v.Choice.Name)
+	case ValueGE:
+		return fmt.Sprintf("%v is at least %v", summarizeValue(v.Value), summarizeValue(v.Ge))
+	case ValueGT:
+		return fmt.Sprintf("%v is greater than %v", summarizeValue(v.Value), summarizeValue(v.Gt))
+	case ValueLT:
+		return fmt.Sprintf("%v is less than %v", summarizeValue(v.Value), summarizeValue(v.Lt))
+	case ValueLE:
+		return fmt.Sprintf("%v is at most %v", summarizeValue(v.Value), summarizeValue(v.Le))
+	case ValueEQ:
+		return fmt.Sprintf("%v equals %v", summarizeValue(v.Value), summarizeValue(v.Eq))
+	case BoolObs:
+		return fmt.Sprint(bool(v))
+	}
+
+	return fmt.Sprintf("<%v>", v)
+}
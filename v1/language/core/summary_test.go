@@ -0,0 +1,75 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// Helper function returning a simple escrow: the buyer deposits 50 ADA and
+// either approves payment to the seller or lets the deadline pass, in which
+// case the deposit is refunded to the buyer.
+func setupEscrowContract() m.Contract {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+
+	return m.When{
+		Cases: []m.Case{
+			{
+				Action: m.Deposit{
+					IntoAccount: buyer,
+					Party:       buyer,
+					Token:       m.Ada,
+					Deposits:    m.SetConstant("50000000"),
+				},
+				Then: m.When{
+					Cases: []m.Case{
+						{
+							Action: m.Choice{
+								ChoiceId: m.ChoiceId{Name: "approve", Owner: buyer},
+								Bounds:   []m.Bound{{Lower: 1, Upper: 1}},
+							},
+							Then: m.Pay{
+								From:  buyer,
+								To:    m.Payee{Party: seller},
+								Token: m.Ada,
+								Pay:   m.SetConstant("50000000"),
+								Then:  m.Close,
+							},
+						},
+					},
+					Timeout: m.POSIXTime(1666165377926),
+					Then:    m.Close,
+				},
+			},
+		},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+}
+
+func TestSummarize_Escrow(t *testing.T) {
+	expected := "If buyer deposits 50 ADA into buyer's account by 2022-10-18T07:42:57Z, " +
+		`if buyer chooses "approve" (1) by 2022-10-19T07:42:57Z, ` +
+		"pay 50 ADA from buyer's account to seller; then the contract closes, refunding any remaining balances to their owners; " +
+		"if none of this happens by 2022-10-19T07:42:57Z, the contract closes, refunding any remaining balances to their owners; " +
+		"if none of this happens by 2022-10-18T07:42:57Z, the contract closes, refunding any remaining balances to their owners."
+
+	if summary := m.Summarize(setupEscrowContract()); summary != expected {
+		t.Errorf("Expected %q, got: %q", expected, summary)
+	}
+}
+
+func TestSummarize_MerkleizedCase(t *testing.T) {
+	contract := m.When{
+		Cases:   []m.Case{m.MerkleizedCase(m.Notify{If: m.TrueObs}, closeHash)},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	expected := "If anyone notifies the contract that true by 2022-10-18T07:42:57Z, " +
+		"continue with the merkleized contract " + string(closeHash) + "; " +
+		"if none of this happens by 2022-10-18T07:42:57Z, the contract closes, refunding any remaining balances to their owners."
+	if summary := m.Summarize(contract); summary != expected {
+		t.Errorf("Expected %q, got: %q", expected, summary)
+	}
+}
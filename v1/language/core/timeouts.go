@@ -0,0 +1,118 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FiringTimeouts reports the When timeouts that could fire if inputs arrive
+// at the planned times, in ascending order. Each When consumes the next input
+// time if it falls before its timeout; otherwise the timeout fires and the
+// input is left for the timeout continuation. Since the input times do not say
+// which case an input matches, nor how an If will be decided, every case and
+// branch is followed and the timeouts that fire on any of them are reported.
+func FiringTimeouts(c Contract, inputTimes []POSIXTime) []POSIXTime {
+	firing := make(map[POSIXTime]bool)
+
+	var visit func(c Contract, inputTimes []POSIXTime)
+	visit = func(c Contract, inputTimes []POSIXTime) {
+		switch c := c.(type) {
+		case Pay:
+			visit(c.Then, inputTimes)
+		case If:
+			visit(c.Then, inputTimes)
+			visit(c.Else, inputTimes)
+		case Let:
+			visit(c.Then, inputTimes)
+		case Assert:
+			visit(c.Then, inputTimes)
+		case When:
+			timeout, ok := c.Timeout.(POSIXTime)
+			if !ok {
+				return
+			}
+			if len(c.Cases) > 0 && len(inputTimes) > 0 && inputTimes[0] < timeout {
+				for _, cs := range c.Cases {
+					visit(cs.Then, inputTimes[1:])
+				}
+				return
+			}
+			firing[timeout] = true
+			visit(c.Then, inputTimes)
+		}
+	}
+	visit(c, inputTimes)
+
+	timeouts := make([]POSIXTime, 0, len(firing))
+	for t := range firing {
+		timeouts = append(timeouts, t)
+	}
+	sort.Slice(timeouts, func(i, j int) bool { return timeouts[i] < timeouts[j] })
+
+	return timeouts
+}
+
+// A TimeoutWarning reports a When, identified by its path from the root of the
+// contract, whose timeout does not exceed that of an enclosing When.
+type TimeoutWarning struct {
+	Path      Path
+	Timeout   POSIXTime
+	Enclosing POSIXTime
+}
+
+func (w TimeoutWarning) String() string {
+	return fmt.Sprintf("%v: When timeout %v does not exceed enclosing timeout %v", w.Path, w.Timeout, w.Enclosing)
+}
+
+// AnalyzeTimeouts reports every When whose timeout is less than or equal to
+// the timeout of an enclosing When, since it can time out before it becomes
+// reachable. The timeout continuation of a When only runs once it has timed
+// out, so a When nested there is held to the same rule as one nested in a
+// case. Timeouts given by a TimeParam are not compared.
+func AnalyzeTimeouts(c Contract) []TimeoutWarning {
+	return analyzeTimeouts(c, "", nil)
+}
+
+func analyzeTimeouts(c Contract, path string, enclosing *POSIXTime) []TimeoutWarning {
+	var warnings []TimeoutWarning
+
+	switch c := c.(type) {
+	case Pay:
+		warnings = analyzeTimeouts(c.Then, joinPath(path, "then"), enclosing)
+	case If:
+		warnings = append(analyzeTimeouts(c.Then, joinPath(path, "then"), enclosing),
+			analyzeTimeouts(c.Else, joinPath(path, "else"), enclosing)...)
+	case Let:
+		warnings = analyzeTimeouts(c.Then, joinPath(path, "then"), enclosing)
+	case Assert:
+		warnings = analyzeTimeouts(c.Then, joinPath(path, "then"), enclosing)
+	case When:
+		inner := enclosing
+		if timeout, ok := c.Timeout.(POSIXTime); ok {
+			if enclosing != nil && timeout <= *enclosing {
+				warnings = append(warnings, TimeoutWarning{Path: path, Timeout: timeout, Enclosing: *enclosing})
+			}
+			inner = &timeout
+		}
+		for i, cs := range c.Cases {
+			warnings = append(warnings, analyzeTimeouts(cs.Then, joinPath(path, fmt.Sprintf("when[%d].then", i)), inner)...)
+		}
+		warnings = append(warnings, analyzeTimeouts(c.Then, joinPath(path, "timeout_continuation"), inner)...)
+	}
+
+	return warnings
+}
@@ -0,0 +1,68 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestFiringTimeouts(t *testing.T) {
+	contract := setupEscrowContract()
+	depositDeadline, approvalDeadline := m.POSIXTime(1666078977926), m.POSIXTime(1666165377926)
+
+	tests := []struct {
+		name       string
+		inputTimes []m.POSIXTime
+		expected   []m.POSIXTime
+	}{
+		{"timely deposit and approval", []m.POSIXTime{depositDeadline - 1000, depositDeadline}, nil},
+		{"timely deposit without approval", []m.POSIXTime{depositDeadline - 1000}, []m.POSIXTime{approvalDeadline}},
+		{"late deposit", []m.POSIXTime{depositDeadline + 1000}, []m.POSIXTime{depositDeadline}},
+		{"deposit at the deadline", []m.POSIXTime{depositDeadline}, []m.POSIXTime{depositDeadline}},
+	}
+
+	for _, test := range tests {
+		got := m.FiringTimeouts(contract, test.inputTimes)
+		if len(got) != len(test.expected) {
+			t.Errorf("%v: expected %v, got: %v", test.name, test.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Errorf("%v: expected %v, got: %v", test.name, test.expected, got)
+			}
+		}
+	}
+}
+
+func TestAnalyzeTimeouts(t *testing.T) {
+	if warnings := m.AnalyzeTimeouts(setupEscrowContract()); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for increasing timeouts, got: %v", warnings)
+	}
+
+	contract := m.When{
+		Cases: []m.Case{
+			{Action: m.Notify{If: m.TrueObs}, Then: m.If{
+				Observe: m.TrueObs,
+				Then:    m.Close,
+				Else:    m.When{Timeout: m.POSIXTime(50), Then: m.Close},
+			}},
+		},
+		Timeout: m.POSIXTime(100),
+		Then:    m.When{Timeout: m.POSIXTime(100), Then: m.Close},
+	}
+
+	expected := []m.TimeoutWarning{
+		{Path: "when[0].then.else", Timeout: 50, Enclosing: 100},
+		{Path: "timeout_continuation", Timeout: 100, Enclosing: 100},
+	}
+	got := m.AnalyzeTimeouts(contract)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got: %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got: %v", expected[i], got[i])
+		}
+	}
+}
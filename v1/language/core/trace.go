@@ -0,0 +1,76 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// StepKind distinguishes the steps of an ExecutionTrace.
+type StepKind int
+
+const (
+	// ReduceStep is a reduction that needs no input: a Pay, If, Let or
+	// Assert, a When timing out, or a Close refunding one account.
+	ReduceStep StepKind = iota
+	// InputStep is the application of an input to a case of a When.
+	InputStep
+)
+
+func (k StepKind) String() string {
+	switch k {
+	case ReduceStep:
+		return "reduce"
+	case InputStep:
+		return "apply_input"
+	}
+	return fmt.Sprintf("StepKind(%d)", int(k))
+}
+
+// Marshal the StepKind by its name.
+func (k StepKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+// A TraceStep records a single step of a transaction: the contract it was
+// taken from, the input it applied, if any, the warning or payment it
+// produced, if any, and the state it left.
+type TraceStep struct {
+	Kind     StepKind `json:"step"`
+	Contract Contract `json:"contract"`
+	Input    Input    `json:"input,omitempty"`
+	Warning  Warning  `json:"warning,omitempty"`
+	Payment  *Payment `json:"payment,omitempty"`
+	State    State    `json:"state"`
+}
+
+// An ExecutionTrace records every step ComputeTransactionTraced took, in
+// order, within the time interval of the transaction once trimmed to the
+// minimum time of the state. It marshals to JSON, so that a transaction can
+// be logged and its steps replayed when debugging.
+type ExecutionTrace struct {
+	Interval TimeInterval `json:"interval"`
+	Steps    []TraceStep  `json:"steps"`
+}
+
+// ComputeTransactionTraced computes the transaction as ComputeTransaction
+// does, also returning the trace of its steps. When the transaction fails,
+// the trace holds the steps taken up to the failure.
+func ComputeTransactionTraced(tx TransactionInput, state State, c Contract) (TransactionOutput, ExecutionTrace) {
+	var trace ExecutionTrace
+	out := computeTransaction(tx, state, c, &trace)
+	return out, trace
+}
@@ -0,0 +1,56 @@
+package language_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestComputeTransactionTraced_PartialPay(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	contract := m.When{
+		Cases: []m.Case{{
+			Action: m.Deposit{IntoAccount: seller, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("10")},
+			Then:   m.Pay{From: seller, To: m.Payee{Party: buyer}, Token: m.Ada, Pay: m.SetConstant("20"), Then: m.Close},
+		}},
+		Timeout: m.POSIXTime(1000),
+		Then:    m.Close,
+	}
+	tx := m.TransactionInput{
+		Interval: m.NewTimeInterval(0, 10),
+		Inputs:   []m.Input{m.NewIDeposit(seller, buyer, m.Ada, big.NewInt(10))},
+	}
+
+	out, trace := m.ComputeTransactionTraced(tx, m.State{}, contract)
+	if out.Error != nil {
+		t.Fatalf("Unexpected error: %v", out.Error)
+	}
+	if expected := m.ComputeTransaction(tx, m.State{}, contract); !m.EqualStates(out.State, expected.State) || !m.Equal(out.Contract, expected.Contract) {
+		t.Errorf("Expected the output of ComputeTransaction, got: %v", out)
+	}
+
+	if len(trace.Steps) != 2 || trace.Steps[0].Kind != m.InputStep || trace.Steps[1].Kind != m.ReduceStep {
+		t.Fatalf("Expected the deposit and the payment, got: %v", trace.Steps)
+	}
+	step := trace.Steps[1]
+	if _, ok := step.Contract.(m.Pay); !ok {
+		t.Errorf("Expected the payment to reduce the Pay, got: %v", step.Contract)
+	}
+	if _, ok := step.Warning.(m.PartialPay); !ok {
+		t.Errorf("Expected the payment to warn of a partial pay, got: %v", step.Warning)
+	}
+	if step.Payment == nil || step.Payment.Amount.Int64() != 10 {
+		t.Errorf("Expected 10 to be paid, got: %v", step.Payment)
+	}
+
+	data, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"step":"apply_input"`) || !strings.Contains(string(data), `"step":"reduce"`) {
+		t.Errorf("Expected the steps to marshal by name, got: %s", data)
+	}
+}
@@ -0,0 +1,439 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package language contains types and methods that implement the Marlowe DSL in Go
+// See: https://github.com/input-output-hk/marlowe-cardano/tree/main/marlowe/specification
+// See: https://github.com/input-output-hk/marlowe-cardano/blob/main/marlowe/src/Language/Marlowe/Core/V1/Semantics.hs
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// A Payment records an amount of a Token paid out of an internal account to
+// a Payee.
+//
+//	datatype Payment = Payment AccountId Payee Token int
+type Payment struct {
+	PaymentFromAccount AccountId `json:"payment_from"`
+	To                 Payee     `json:"to"`
+	Token              Token     `json:"token"`
+	Amount             *big.Int  `json:"amount"`
+}
+
+// Warnings are issued during the evaluation of a transaction for situations
+// that do not prevent the transaction from succeeding but that are likely to
+// be unintended.
+type Warning interface{ isWarning() }
+
+// The result of computing a transaction: the warnings and payments it
+// generated, and the new state and continuation contract.
+//
+//	datatype TransactionOutput =
+//		TransactionOutput
+//			txOutWarnings :: TransactionWarning list
+//			txOutPayments :: Payment list
+//			txOutState :: State
+//			txOutContract :: Contract
+//		| Error TransactionError
+//
+// Error is nil when the transaction succeeds, and the other fields are only
+// meaningful in that case.
+type TransactionOutput struct {
+	Warnings []Warning
+	Payments []Payment
+	State    State
+	Contract Contract
+	Error    TransactionError
+}
+
+// MarshalJSON emits the transaction output in the shape reported by the
+// Marlowe Runtime, so the results of the Go evaluator can be compared against
+// those of a live Runtime.
+func (o TransactionOutput) MarshalJSON() ([]byte, error) {
+	if o.Error != nil {
+		return json.Marshal(struct {
+			Error string `json:"transaction_error"`
+		}{transactionErrorName(o.Error)})
+	}
+
+	// Marshal empty lists as [] rather than null, as the Runtime does.
+	warnings, payments := o.Warnings, o.Payments
+	if warnings == nil {
+		warnings = []Warning{}
+	}
+	if payments == nil {
+		payments = []Payment{}
+	}
+
+	return json.Marshal(struct {
+		Warnings []Warning `json:"warnings"`
+		Payments []Payment `json:"payments"`
+		State    stateJSON `json:"state"`
+		Contract Contract  `json:"contract"`
+	}{warnings, payments, newStateJSON(o.State), o.Contract})
+}
+
+// The Marlowe State is serialized with its maps as association lists of
+// [key, value] pairs, in the key order of the Haskell implementation.
+type stateJSON struct {
+	Accounts    [][2]any  `json:"accounts"`
+	Choices     [][2]any  `json:"choices"`
+	BoundValues [][2]any  `json:"boundValues"`
+	MinTime     POSIXTime `json:"minTime"`
+}
+
+func newStateJSON(s State) stateJSON {
+	out := stateJSON{
+		Accounts:    [][2]any{},
+		Choices:     [][2]any{},
+		BoundValues: [][2]any{},
+		MinTime:     s.MinTime,
+	}
+
+	accounts := make([]Account, 0, len(s.Accounts))
+	for account := range s.Accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return lessAccount(accounts[i], accounts[j]) })
+	for _, account := range accounts {
+		out.Accounts = append(out.Accounts, [2]any{[2]any{account.AccountId, account.Token}, s.Accounts[account]})
+	}
+
+	choices := make([]ChoiceId, 0, len(s.Choices))
+	for id := range s.Choices {
+		choices = append(choices, id)
+	}
+	sort.Slice(choices, func(i, j int) bool { return lessChoiceId(choices[i], choices[j]) })
+	for _, id := range choices {
+		out.Choices = append(out.Choices, [2]any{id, s.Choices[id]})
+	}
+
+	values := make([]ValueId, 0, len(s.BoundValues))
+	for id := range s.BoundValues {
+		values = append(values, id)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	for _, id := range values {
+		out.BoundValues = append(out.BoundValues, [2]any{id, s.BoundValues[id]})
+	}
+
+	return out
+}
+
+// Parties are ordered as in the Haskell implementation: addresses before
+// roles, and then by the address or role name.
+func lessParty(a, b Party) bool {
+	switch a := a.(type) {
+	case Address:
+		b, ok := b.(Address)
+		return !ok || a < b
+	case Role:
+		b, ok := b.(Role)
+		return ok && a.Name < b.Name
+	}
+	return false
+}
+
+func lessToken(a, b Token) bool {
+	if a.Symbol != b.Symbol {
+		return a.Symbol < b.Symbol
+	}
+	return a.Name < b.Name
+}
+
+func lessAccount(a, b Account) bool {
+	if a.AccountId != b.AccountId {
+		return lessParty(a.AccountId, b.AccountId)
+	}
+	return lessToken(a.Token, b.Token)
+}
+
+func lessChoiceId(a, b ChoiceId) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return lessParty(a.Owner, b.Owner)
+}
+
+// A transaction is a list of inputs together with the time interval within
+// which it is valid.
+//
+//	record Transaction = interval :: TimeInterval
+//		inputs :: Input list
+type TransactionInput struct {
+	Interval TimeInterval
+	Inputs   []Input
+}
+
+// The JSON of a TransactionInput in marlowe-cardano.
+type transactionInputJSON struct {
+	Interval TimeInterval      `json:"tx_interval"`
+	Inputs   []json.RawMessage `json:"tx_inputs"`
+}
+
+// Marshal the TransactionInput as the JSON of marlowe-cardano:
+//
+//	{"tx_interval": {"from": start, "to": end}, "tx_inputs": [input, ...]}
+func (tx TransactionInput) MarshalJSON() ([]byte, error) {
+	out := transactionInputJSON{Interval: tx.Interval}
+	out.Inputs = make([]json.RawMessage, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		out.Inputs[i] = data
+	}
+
+	return json.Marshal(out)
+}
+
+// Unmarshal the TransactionInput from the JSON written by MarshalJSON,
+// decoding each input with UnmarshalInput.
+func (tx *TransactionInput) UnmarshalJSON(data []byte) error {
+	var in transactionInputJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	var inputs []Input
+	for i, raw := range in.Inputs {
+		input, err := UnmarshalInput(raw)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+		inputs = append(inputs, input)
+	}
+
+	*tx = TransactionInput{Interval: in.Interval, Inputs: inputs}
+	return nil
+}
+
+// Warnings issued while computing a transaction. Each variant carries the
+// fields of its constructor in marlowe-cardano and marshals to the same JSON.
+//
+//	datatype TransactionWarning =
+//		TransactionNonPositiveDeposit Party AccountId Token int
+//		| TransactionNonPositivePay AccountId Payee Token int
+//		| TransactionPartialPay AccountId Payee Token int int
+//		| TransactionShadowing ValueId int int
+//		| TransactionAssertionFailed
+
+// NonPositiveDeposit is issued when an IDeposit of zero or less is applied.
+type NonPositiveDeposit struct {
+	Party     Party     `json:"party"`
+	AccountId AccountId `json:"in_account"`
+	Token     Token     `json:"of_token"`
+	Amount    *big.Int  `json:"asked_to_deposit"`
+}
+
+// NonPositivePay is issued when a Pay evaluates to zero or less, in which
+// case nothing is paid.
+type NonPositivePay struct {
+	AccountId AccountId `json:"account"`
+	Payee     Payee     `json:"to_payee"`
+	Token     Token     `json:"of_token"`
+	Amount    *big.Int  `json:"asked_to_pay"`
+}
+
+// PartialPay is issued when the account does not hold enough to make a
+// payment in full, in which case the available balance is paid instead.
+type PartialPay struct {
+	AccountId AccountId `json:"account"`
+	Payee     Payee     `json:"to_payee"`
+	Token     Token     `json:"of_token"`
+	Paid      *big.Int  `json:"but_only_paid"`
+	Expected  *big.Int  `json:"asked_to_pay"`
+}
+
+// ShadowedLet is issued when a Let rebinds a ValueId that is already bound.
+type ShadowedLet struct {
+	ValueId  ValueId  `json:"value_id"`
+	OldValue *big.Int `json:"had_value"`
+	NewValue *big.Int `json:"is_now_assigned"`
+}
+
+// AssertionFailed is issued when the observation of an Assert is false.
+type AssertionFailed struct{}
+
+func (w AssertionFailed) MarshalJSON() ([]byte, error) {
+	return []byte(`"assertion_failed"`), nil
+}
+
+func (w NonPositiveDeposit) isWarning() {}
+func (w NonPositivePay) isWarning()     {}
+func (w PartialPay) isWarning()         {}
+func (w ShadowedLet) isWarning()        {}
+func (w AssertionFailed) isWarning()    {}
+
+// A TransactionError is returned in place of a TransactionOutput's payload
+// when a transaction cannot be applied to a contract.
+//
+//	datatype TransactionError =
+//		TEAmbiguousTimeIntervalError
+//		| TEApplyNoMatchError
+//		| TEIntervalError IntervalError
+//		| TEUselessTransaction
+//		| TEHashMismatch
+//
+// Callers can switch on the concrete type to tell the failures apart.
+type TransactionError interface{ isTransactionError() }
+
+// TEAmbiguousTimeIntervalError is returned when the time interval of the
+// transaction straddles the timeout of a When.
+type TEAmbiguousTimeIntervalError struct{}
+
+// TEApplyNoMatchError is returned when an input matches none of the cases of
+// the When it is applied to, or is applied to a contract that is not a When.
+type TEApplyNoMatchError struct{}
+
+// TEIntervalError is returned when the time interval of the transaction is
+// invalid, see IntervalError.
+type TEIntervalError struct {
+	IntervalError IntervalError
+}
+
+// TEUselessTransaction is returned when a transaction would neither change
+// the contract nor pay anything out.
+type TEUselessTransaction struct{}
+
+// TEHashMismatch is returned when an input matches the action of a case but
+// does not carry its continuation, see ApplyAllHashMismatch.
+type TEHashMismatch struct{}
+
+// TEUnsupportedNode is returned when the contract holds a node that the
+// semantics cannot evaluate, such as an Extended parameter that was not
+// instantiated. Node names its type. marlowe-cardano has no such error, since
+// its types admit no other nodes.
+type TEUnsupportedNode struct {
+	Node string
+}
+
+func unsupportedNode(node any) TEUnsupportedNode {
+	return TEUnsupportedNode{Node: fmt.Sprintf("%T", node)}
+}
+
+// TEBalanceOverflow is returned when a deposit or a payment into an account
+// would take its balance beyond the uint64 range of Accounts. Balances are
+// unbounded in marlowe-cardano, which has no such error.
+type TEBalanceOverflow struct {
+	Account Account
+}
+
+func transactionErrorName(e TransactionError) string {
+	switch e.(type) {
+	case TEAmbiguousTimeIntervalError:
+		return "TEAmbiguousTimeIntervalError"
+	case TEApplyNoMatchError:
+		return "TEApplyNoMatchError"
+	case TEIntervalError:
+		return "TEIntervalError"
+	case TEUselessTransaction:
+		return "TEUselessTransaction"
+	case TEHashMismatch:
+		return "TEHashMismatch"
+	case TEBalanceOverflow:
+		return "TEBalanceOverflow"
+	case TEUnsupportedNode:
+		return "TEUnsupportedNode"
+	}
+	return fmt.Sprint(e)
+}
+
+func (e TEAmbiguousTimeIntervalError) isTransactionError() {}
+func (e TEApplyNoMatchError) isTransactionError()          {}
+func (e TEIntervalError) isTransactionError()              {}
+func (e TEUselessTransaction) isTransactionError()         {}
+func (e TEHashMismatch) isTransactionError()               {}
+func (e TEBalanceOverflow) isTransactionError()            {}
+func (e TEUnsupportedNode) isTransactionError()            {}
+
+// An IntervalError explains why the time interval of a transaction was rejected.
+//
+//	datatype IntervalError =
+//		InvalidInterval TimeInterval
+//		| IntervalInPastError POSIXTime TimeInterval
+type IntervalError interface{ isIntervalError() }
+
+// InvalidInterval is an interval whose end is before its start.
+type InvalidInterval struct {
+	Interval TimeInterval
+}
+
+// IntervalInPastError is an interval that ends before the MinTime of the
+// contract State.
+type IntervalInPastError struct {
+	MinTime  POSIXTime
+	Interval TimeInterval
+}
+
+func (e InvalidInterval) isIntervalError()     {}
+func (e IntervalInPastError) isIntervalError() {}
+
+// Errors returned by ApplyInput and ApplyAllInputs.
+//
+//	data ApplyAllResult = ApplyAllSuccess Bool [TransactionWarning] [Payment] State Contract
+//		| ApplyAllNoMatchError
+//		| ApplyAllAmbiguousTimeIntervalError
+//		| ApplyAllHashMismatch
+
+// ApplyAllNoMatchError is returned when an input matches none of the cases of
+// the When it is applied to, or is applied to a contract that is not a When.
+type ApplyAllNoMatchError struct{}
+
+// ApplyAllAmbiguousTimeIntervalError is returned when the time interval of the
+// environment straddles the timeout of a When.
+type ApplyAllAmbiguousTimeIntervalError struct{}
+
+// ApplyAllHashMismatch is returned when an input matches the action of a case
+// but does not carry the continuation the case holds: a MerkleizedInput for
+// a case that is not merkleized, an input that is not merkleized for one that
+// is, or a continuation whose hash differs from that of the case.
+type ApplyAllHashMismatch struct{}
+
+// ApplyAllUnsupportedNode is returned when the contract holds a node that
+// cannot be evaluated, see TEUnsupportedNode.
+type ApplyAllUnsupportedNode struct {
+	Node string
+}
+
+// ApplyAllBalanceOverflow is returned when the balance of an account would
+// overflow, see TEBalanceOverflow.
+type ApplyAllBalanceOverflow struct {
+	Account Account
+}
+
+func (e ApplyAllNoMatchError) Error() string {
+	return "input matches no case of the contract"
+}
+
+func (e ApplyAllAmbiguousTimeIntervalError) Error() string {
+	return "time interval straddles the timeout of the contract"
+}
+
+func (e ApplyAllHashMismatch) Error() string {
+	return "input does not carry the continuation of the case it matches"
+}
+
+func (e ApplyAllUnsupportedNode) Error() string {
+	return fmt.Sprintf("cannot evaluate a node of type %v", e.Node)
+}
+
+func (e ApplyAllBalanceOverflow) Error() string {
+	return fmt.Sprintf("balance of %v in account %v overflows", e.Account.Token, e.Account.AccountId)
+}
@@ -0,0 +1,165 @@
+package language_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	assert "github.com/menabrealabs/marlowe/assertion"
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestTransactionOutput_MarshalJSON(t *testing.T) {
+	// The buyer deposited 10 ADA into the seller's account and the contract
+	// then closed, refunding the seller's account balance.
+	output := m.TransactionOutput{
+		Payments: []m.Payment{
+			{
+				PaymentFromAccount: m.Role{Name: "seller"},
+				To:                 m.Payee{Party: m.Role{Name: "seller"}},
+				Token:              m.Ada,
+				Amount:             big.NewInt(10_000_000),
+			},
+		},
+		State: m.State{
+			MinTime: m.POSIXTime(1666078977926),
+		},
+		Contract: m.Close,
+	}
+
+	assert.Json(t, output, `{"warnings":[],"payments":[{"payment_from":{"role_token":"seller"},"to":{"party":{"role_token":"seller"}},"token":{"currency_symbol":"","token_name":""},"amount":10000000}],"state":{"accounts":[],"choices":[],"boundValues":[],"minTime":1666078977926},"contract":"close"}`)
+}
+
+func TestTransactionOutput_MarshalJSON_State(t *testing.T) {
+	choiceId := m.ChoiceId{Name: "option", Owner: m.Role{Name: "buyer"}}
+	output := m.TransactionOutput{
+		State: m.State{
+			Accounts: m.Accounts{
+				{AccountId: m.Role{Name: "seller"}, Token: m.Ada}: 5,
+				{AccountId: m.Role{Name: "buyer"}, Token: m.Ada}:  3,
+			},
+			Choices:     map[m.ChoiceId]m.ChosenNum{choiceId: m.SetChosenNum("1")},
+			BoundValues: map[m.ValueId]*big.Int{"b": big.NewInt(2), "a": big.NewInt(1)},
+			MinTime:     m.POSIXTime(10),
+		},
+		Contract: m.Close,
+	}
+
+	assert.Json(t, output, `{"warnings":[],"payments":[],"state":{"accounts":[[[{"role_token":"buyer"},{"currency_symbol":"","token_name":""}],3],[[{"role_token":"seller"},{"currency_symbol":"","token_name":""}],5]],"choices":[[{"choice_name":"option","choice_owner":{"role_token":"buyer"}},1]],"boundValues":[["a",1],["b",2]],"minTime":10},"contract":"close"}`)
+}
+
+func TestWarnings_Emitted(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Role{Name: "seller"}
+	env := m.Environment{TimeInterval: m.NewTimeInterval(0, 10)}
+	state := m.State{Accounts: m.Accounts{{AccountId: buyer, Token: m.Ada}: 5}}
+
+	pay := func(amount string, then m.Contract) m.Contract {
+		return m.Pay{From: buyer, To: m.Payee{Party: seller}, Token: m.Ada, Pay: m.SetConstant(amount), Then: then}
+	}
+	contract := m.Let{
+		Name:  "x",
+		Value: m.SetConstant("1"),
+		Then: m.Let{
+			Name:  "x",
+			Value: m.SetConstant("2"),
+			Then:  pay("0", pay("10", m.Assert{Observe: m.FalseObs, Then: m.When{Timeout: m.POSIXTime(100), Then: m.Close}})),
+		},
+	}
+
+	_, _, _, warnings, err := m.ReduceContractUntilQuiescent(env, state, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Json(t, warnings, `[`+
+		`{"value_id":"x","had_value":1,"is_now_assigned":2},`+
+		`{"account":{"role_token":"buyer"},"to_payee":{"party":{"role_token":"seller"}},"of_token":{"currency_symbol":"","token_name":""},"asked_to_pay":0},`+
+		`{"account":{"role_token":"buyer"},"to_payee":{"party":{"role_token":"seller"}},"of_token":{"currency_symbol":"","token_name":""},"but_only_paid":5,"asked_to_pay":10},`+
+		`"assertion_failed"]`)
+}
+
+func TestWarnings_NonPositiveDeposit(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	contract := setupWhenContract(m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("-1")})
+
+	deposit := m.NewIDeposit(buyer, buyer, m.Ada, big.NewInt(-1))
+
+	out := m.ComputeTransaction(m.TransactionInput{Interval: m.NewTimeInterval(0, 10), Inputs: []m.Input{deposit}}, m.State{}, contract)
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+
+	assert.Json(t, out.Warnings, `[{"party":{"role_token":"buyer"},"in_account":{"role_token":"buyer"},"of_token":{"currency_symbol":"","token_name":""},"asked_to_deposit":-1}]`)
+	if len(out.State.Accounts) != 0 {
+		t.Errorf("Expected nothing to be deposited, got: %v", out.State.Accounts)
+	}
+}
+
+func TestTransactionOutput_MarshalJSON_Error(t *testing.T) {
+	errors := map[string]m.TransactionError{
+		"TEAmbiguousTimeIntervalError": m.TEAmbiguousTimeIntervalError{},
+		"TEApplyNoMatchError":          m.TEApplyNoMatchError{},
+		"TEIntervalError":              m.TEIntervalError{IntervalError: m.InvalidInterval{}},
+		"TEUselessTransaction":         m.TEUselessTransaction{},
+		"TEHashMismatch":               m.TEHashMismatch{},
+		"TEBalanceOverflow":            m.TEBalanceOverflow{},
+		"TEUnsupportedNode":            m.TEUnsupportedNode{},
+	}
+
+	for name, err := range errors {
+		assert.Json(t, m.TransactionOutput{Error: err}, `{"transaction_error":"`+name+`"}`)
+	}
+}
+
+func TestTransactionInput_JSON(t *testing.T) {
+	buyer := m.Role{Name: "buyer"}
+	tx := m.TransactionInput{
+		Interval: m.NewTimeInterval(1666078977926, 1666078987926),
+		Inputs: []m.Input{
+			m.NewIDeposit(buyer, m.Address("addr1_buyer"), m.Ada, big.NewInt(50000000)),
+			m.IChoice{ChoiceId: m.ChoiceId{Name: "approve", Owner: buyer}, ChosenNum: m.SetChosenNum("-1")},
+			m.INotify{},
+		},
+	}
+	expected := `{"tx_interval":{"from":1666078977926,"to":1666078987926},"tx_inputs":[` +
+		`{"input_from_party":{"address":"addr1_buyer"},"that_deposits":50000000,` +
+		`"of_token":{"currency_symbol":"","token_name":""},"into_account":{"role_token":"buyer"}},` +
+		`{"for_choice_id":{"choice_name":"approve","choice_owner":{"role_token":"buyer"}},"input_that_chooses_num":-1},` +
+		`"input_notify"]}`
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Errorf("Expected %v, got: %s", expected, data)
+	}
+
+	var decoded m.TransactionInput
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := json.Marshal(decoded); string(again) != expected {
+		t.Errorf("Expected the decoded transaction to marshal as %v, got: %s", expected, again)
+	}
+	if _, ok := decoded.Inputs[0].(m.IDeposit); !ok {
+		t.Errorf("Expected an IDeposit, got: %#v", decoded.Inputs[0])
+	}
+}
+
+func TestInput_JSONErrors(t *testing.T) {
+	if _, err := json.Marshal(m.IDeposit{AccountId: m.Role{Name: "buyer"}}); err == nil {
+		t.Error("Expected an error for a deposit without a value")
+	}
+
+	for _, data := range []string{`"input_deposit"`, `{"unknown": 1}`, `{"that_deposits": 1}`, `42`} {
+		if input, err := m.UnmarshalInput([]byte(data)); err == nil {
+			t.Errorf("Expected an error for %v, got: %#v", data, input)
+		}
+	}
+
+	var choice m.IChoice
+	if err := json.Unmarshal([]byte(`"input_notify"`), &choice); err == nil {
+		t.Error("Expected an error decoding a notify into an IChoice")
+	}
+}
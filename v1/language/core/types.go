@@ -18,6 +18,9 @@
 package language
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"math/big"
 )
 
@@ -55,6 +58,61 @@ type Role struct {
 func (r Role) isParty()    {}
 func (p Address) isParty() {}
 
+func (r Role) String() string { return r.Name }
+
+// The longest token name, in bytes, that the ledger accepts.
+const maxTokenNameSize = 32
+
+// Validate that the role can be minted as a token: its name, which is the
+// token name of the role token, must not exceed 32 bytes.
+func (r Role) Validate() error {
+	if len(r.Name) > maxTokenNameSize {
+		return fmt.Errorf("role %q is %d bytes long, more than the %d allowed for a token name", r.Name, len(r.Name), maxTokenNameSize)
+	}
+	return nil
+}
+
+// Marshal the Address as Marlowe does, e.g. {"address":"addr1..."}, so that
+// it can be told apart from a Role, which marshals as {"role_token":...}.
+func (p Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Address string `json:"address"`
+	}{string(p)})
+}
+
+// Unmarshal an Address from its {"address": ...} form.
+func (p *Address) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Address *string `json:"address"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Address == nil {
+		return fmt.Errorf("address party has no \"address\" key: %s", data)
+	}
+
+	*p = Address(*v.Address)
+	return nil
+}
+
+// Unmarshal a Role from its {"role_token": ...} form, rejecting objects such
+// as an Address that lack the key.
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Name *string `json:"role_token"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Name == nil {
+		return fmt.Errorf("role party has no \"role_token\" key: %s", data)
+	}
+
+	r.Name = *v.Name
+	return nil
+}
+
 // "Inspired by Cardano’s Multi-Asset tokens, Marlowe also supports to transact with different assets.
 // A Token consists of a CurrencySymbol that represents the monetary policy of the Token and a TokenName
 // which allows to have multiple tokens with the same monetary policy.
@@ -72,9 +130,34 @@ type Token struct {
 	Name   string `json:"token_name"`
 }
 
-// Belongs in Cardano-specific implementation semantics
+// Belongs in Cardano-specific implementation semantics. Since tokens are
+// compared by value, any Token with an empty symbol and name is Ada, however
+// it was constructed, for equality, hashing and account ordering alike.
 var Ada Token = Token{} // empty token defaults to $ADA
 
+// Validate that the token can exist on the ledger: either it is Ada, or its
+// symbol is the hex encoding of a 28-byte policy id and its name does not
+// exceed 32 bytes.
+func (t Token) Validate() error {
+	if t == Ada {
+		return nil
+	}
+	if policy, err := hex.DecodeString(t.Symbol); err != nil || len(policy) != 28 {
+		return fmt.Errorf("token %v has currency symbol %q, which is not a hex encoded policy id", t, t.Symbol)
+	}
+	if len(t.Name) > maxTokenNameSize {
+		return fmt.Errorf("token %v has a name of %d bytes, more than the %d allowed", t, len(t.Name), maxTokenNameSize)
+	}
+	return nil
+}
+
+func (t Token) String() string {
+	if t == Ada {
+		return "ADA"
+	}
+	return t.Symbol + "." + t.Name
+}
+
 // "The Timeouts that prevent us from waiting forever for external Inputs are
 // represented by the number of milliseconds from the Unix Epoch.
 //
@@ -98,12 +181,115 @@ func (t POSIXTime) IsTimeout() {}
 
 // Spec specifies a tuple, but Go doesn't have that datatype natively
 type TimeInterval struct {
-	// start is exclusive and end is inclusive
+	// Despite the wording of §1.4, marlowe-cardano treats both start and end
+	// as inclusive: a When has timed out once timeout <= start, and is still
+	// waiting for input while end < timeout.
 	start, end POSIXTime
 }
 
+// NewTimeInterval returns the interval from start to end, both inclusive. It
+// does not check that start <= end, so that an invalid interval can be given
+// to ComputeTransaction, which rejects it with an InvalidInterval error; use
+// NewTimeIntervalChecked to reject one up front.
+func NewTimeInterval(start, end POSIXTime) TimeInterval {
+	return TimeInterval{start: start, end: end}
+}
+
+// NewTimeIntervalChecked returns the interval from start to end, both
+// inclusive, or an error if end is before start.
+func NewTimeIntervalChecked(start, end POSIXTime) (TimeInterval, error) {
+	if end < start {
+		return TimeInterval{}, fmt.Errorf("time interval ends at %v before it starts at %v", end, start)
+	}
+	return TimeInterval{start: start, end: end}, nil
+}
+
+// Start returns the first time of the interval.
+func (i TimeInterval) Start() POSIXTime {
+	return i.start
+}
+
+// End returns the last time of the interval.
+func (i TimeInterval) End() POSIXTime {
+	return i.end
+}
+
+// The JSON of a TimeInterval in marlowe-cardano.
+type timeIntervalJSON struct {
+	From *POSIXTime `json:"from"`
+	To   *POSIXTime `json:"to"`
+}
+
+// Marshal the TimeInterval as {"from": start, "to": end}.
+func (i TimeInterval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timeIntervalJSON{&i.start, &i.end})
+}
+
+// Unmarshal the TimeInterval from {"from": start, "to": end}. As with
+// NewTimeInterval, an interval ending before it starts is not rejected.
+func (i *TimeInterval) UnmarshalJSON(data []byte) error {
+	var in timeIntervalJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.From == nil || in.To == nil {
+		return fmt.Errorf("time interval %s needs both \"from\" and \"to\"", data)
+	}
+	*i = NewTimeInterval(*in.From, *in.To)
+	return nil
+}
+
+// Contains reports whether t lies within the inclusive interval [start, end].
+func (i TimeInterval) Contains(t POSIXTime) bool {
+	return i.start <= t && t <= i.end
+}
+
+// Before reports whether the whole interval lies before t (end < t), which is
+// when a When with timeout t is still waiting for input.
+func (i TimeInterval) Before(t POSIXTime) bool {
+	return i.end < t
+}
+
+// After reports whether the whole interval lies at or after t (t <= start),
+// which is when a When with timeout t has timed out. An interval that is
+// neither Before nor After t straddles the timeout and is ambiguous.
+func (i TimeInterval) After(t POSIXTime) bool {
+	return t <= i.start
+}
+
+// "A payment can be made to one of the parties to the contract, or to one of
+// the accounts of the contract, and this is reflected in the definition
+//
+//	datatype Payee = Account AccountId
+//		| Party Party" (§2.1.3)
+//
+// A Payee pays out to its Party, unless its Account is set, in which case the
+// payment is transferred into that internal account instead.
 type Payee struct {
-	Party Party
+	Party   Party
+	Account AccountId
+}
+
+// Marshal the Payee as {"account": ...} when it names an internal account, or
+// as {"party": ...} otherwise.
+func (p Payee) MarshalJSON() ([]byte, error) {
+	if p.Account != nil {
+		return json.Marshal(struct {
+			Account AccountId `json:"account"`
+		}{p.Account})
+	}
+
+	return json.Marshal(struct {
+		Party Party `json:"party"`
+	}{p.Party})
+}
+
+// The party that receives the payment, or that owns the account receiving it.
+func (p Payee) owner() Party {
+	if p.Account != nil {
+		return p.Account
+	}
+	return p.Party
 }
 
 type AccountId Party
@@ -139,14 +325,71 @@ type Accounts map[Account]uint64 // This is a type in the Marlowe Core specs.
 // minTime :: POSIXTime
 type State struct {
 	Accounts    Accounts
-	BoundValues map[ValueId]uint64
+	Choices     map[ChoiceId]ChosenNum
+	BoundValues map[ValueId]*big.Int
 	MinTime     POSIXTime
 }
 
+// Marshal the State in the shape of the Haskell implementation, with its maps
+// as association lists in key order:
+//
+//	{"accounts": [[[accountId, token], balance], ...],
+//	 "choices": [[choiceId, chosenNum], ...],
+//	 "boundValues": [[valueId, integer], ...],
+//	 "minTime": posixTime}
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newStateJSON(s))
+}
+
+// Unmarshal the State from the shape written by MarshalJSON, as served by the
+// Marlowe Runtime.
+func (s *State) UnmarshalJSON(data []byte) error {
+	state, err := unmarshalState(data)
+	if err != nil {
+		return err
+	}
+	*s = state
+	return nil
+}
+
 // The execution environment of a Marlowe contract simply consists of the
 // (inclusive) time interval within which the transaction is occurring.
 
 // record Environment = timeInterval :: TimeInterval"
+//
+// An Environment marshals as {"timeInterval": {"from": start, "to": end}}.
 type Environment struct {
-	TimeInterval TimeInterval
+	TimeInterval TimeInterval `json:"timeInterval"`
+}
+
+// Return a copy of the accounts with the balance of the account set, removing
+// the account when its balance is zero. The receiver is left unmodified.
+func (a Accounts) with(account Account, balance uint64) Accounts {
+	accounts := make(Accounts, len(a)+1)
+	for k, v := range a {
+		accounts[k] = v
+	}
+
+	if balance == 0 {
+		delete(accounts, account)
+	} else {
+		accounts[account] = balance
+	}
+
+	return accounts
+}
+
+// Return a copy of the accounts without the account.
+func (a Accounts) without(account Account) Accounts {
+	return a.with(account, 0)
+}
+
+// Return a copy of the accounts with the amount added to the balance of the
+// account, or a TEBalanceOverflow if the balance no longer fits in a uint64.
+func (a Accounts) add(account Account, amount *big.Int) (Accounts, TransactionError) {
+	balance := new(big.Int).SetUint64(a[account])
+	if !balance.Add(balance, amount).IsUint64() {
+		return a, TEBalanceOverflow{Account: account}
+	}
+	return a.with(account, balance.Uint64()), nil
 }
@@ -0,0 +1,141 @@
+package language_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// Boundary behaviour must match marlowe-cardano's reduceContractStep, which
+// treats the interval as inclusive on both ends: timed out when
+// timeout <= start, still waiting when end < timeout, ambiguous otherwise.
+
+func TestTimeInterval_StartEqualsTimeout(t *testing.T) {
+	timeout := m.POSIXTime(1000)
+	interval := m.NewTimeInterval(1000, 2000)
+
+	if !interval.After(timeout) {
+		t.Error("Interval starting at the timeout should count as timed out")
+	}
+
+	if interval.Before(timeout) {
+		t.Error("Interval starting at the timeout should not be waiting")
+	}
+
+	if !interval.Contains(timeout) {
+		t.Error("Interval start should be inclusive")
+	}
+}
+
+func TestTimeInterval_EndEqualsTimeout(t *testing.T) {
+	timeout := m.POSIXTime(2000)
+	interval := m.NewTimeInterval(1000, 2000)
+
+	if interval.Before(timeout) || interval.After(timeout) {
+		t.Error("Interval ending at the timeout should be ambiguous")
+	}
+
+	if !interval.Contains(timeout) {
+		t.Error("Interval end should be inclusive")
+	}
+}
+
+func TestTimeInterval_EndBeforeTimeout(t *testing.T) {
+	interval := m.NewTimeInterval(1000, 1999)
+
+	if !interval.Before(2000) {
+		t.Error("Interval ending before the timeout should still be waiting")
+	}
+
+	if interval.Contains(2000) {
+		t.Error("Interval should not contain a time after its end")
+	}
+}
+
+func TestNewTimeIntervalChecked(t *testing.T) {
+	interval, err := m.NewTimeIntervalChecked(1000, 2000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if interval.Start() != 1000 || interval.End() != 2000 {
+		t.Errorf("Expected [1000, 2000], got: [%v, %v]", interval.Start(), interval.End())
+	}
+
+	if _, err := m.NewTimeIntervalChecked(1000, 1000); err != nil {
+		t.Errorf("Expected a single instant to be a valid interval, got: %v", err)
+	}
+	if _, err := m.NewTimeIntervalChecked(2000, 1000); err == nil {
+		t.Error("Expected an error for an interval ending before it starts")
+	}
+}
+
+func TestTimeInterval_Environment(t *testing.T) {
+	// Outside the package an Environment can evaluate the interval's bounds.
+	env := m.Environment{TimeInterval: m.NewTimeInterval(1000, 2000)}
+	span := m.EvalValue(env, m.State{}, m.SubValue{From: m.TimeIntervalEnd, Subtract: m.TimeIntervalStart})
+
+	if span.Int64() != 1000 {
+		t.Errorf("Expected the interval to span 1000, got: %v", span)
+	}
+}
+
+func TestEnvironment_JSON(t *testing.T) {
+	env := m.Environment{TimeInterval: m.NewTimeInterval(1666078977926, 1666078987926)}
+	expected := `{"timeInterval":{"from":1666078977926,"to":1666078987926}}`
+
+	data, err := json.Marshal(env)
+	if err != nil || string(data) != expected {
+		t.Fatalf("Expected %v, got: %s (%v)", expected, data, err)
+	}
+
+	var decoded m.Environment
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != env {
+		t.Errorf("Expected %v, got: %v", env, decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"timeInterval":{"from":1}}`), &decoded); err == nil {
+		t.Error("Expected an error for an interval without an end")
+	}
+}
+
+func TestRole_Validate(t *testing.T) {
+	if err := (m.Role{Name: strings.Repeat("r", 32)}).Validate(); err != nil {
+		t.Errorf("Expected a 32-byte role to be valid, got: %v", err)
+	}
+	if err := (m.Role{Name: strings.Repeat("r", 33)}).Validate(); err == nil {
+		t.Error("Expected a 33-byte role to be rejected")
+	}
+}
+
+func TestToken_Validate(t *testing.T) {
+	policy := "85bb65085bb65085bb65085bb65085bb65085bb65085bb65085bb650"
+
+	valid := []m.Token{
+		m.Ada,
+		{Symbol: policy, Name: "dollar"},
+		{Symbol: policy, Name: ""},
+		{Symbol: policy, Name: strings.Repeat("n", 32)},
+	}
+	for _, token := range valid {
+		if err := token.Validate(); err != nil {
+			t.Errorf("Expected %v to be valid, got: %v", token, err)
+		}
+	}
+
+	invalid := []m.Token{
+		{Symbol: "", Name: "dollar"},
+		{Symbol: "85bb65", Name: "dollar"},
+		{Symbol: strings.Repeat("z", 56), Name: "dollar"},
+		{Symbol: policy, Name: strings.Repeat("n", 33)},
+	}
+	for _, token := range invalid {
+		if err := token.Validate(); err == nil {
+			t.Errorf("Expected %v to be rejected", token)
+		}
+	}
+}
@@ -0,0 +1,725 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+)
+
+// UnmarshalContract decodes a contract from the JSON produced by this package,
+// the Marlowe Playground or the Marlowe Runtime. Each JSON object is decoded
+// into the construct named by its keys, e.g. "when" for a When and
+// "from_account" for a Pay, recursively. An object whose keys name no
+// construct is an error.
+func UnmarshalContract(data []byte) (Contract, error) {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		if s != string(Close) {
+			return nil, fmt.Errorf("unknown contract %q", s)
+		}
+		return Close, nil
+	}
+
+	object, err := unmarshalObject(data, "contract")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case object.has("when"):
+		return unmarshalWhen(object)
+	case object.has("from_account"), object.has("pay"):
+		return unmarshalPay(object)
+	case object.has("if"):
+		return unmarshalIf(object)
+	case object.has("let"):
+		return unmarshalLet(object)
+	case object.has("assert"):
+		return unmarshalAssert(object)
+	}
+
+	return nil, object.unknown("contract")
+}
+
+// UnmarshalContract decodes a contract as UnmarshalContract does, first
+// checking its keys if the options are Strict.
+func (o UnmarshalOptions) UnmarshalContract(data []byte) (Contract, error) {
+	if o.Strict {
+		if err := checkKnownKeys(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return UnmarshalContract(data)
+}
+
+// LoadContractWithState decodes a contract together with its state from a
+// single {"contract": ..., "state": ...} document, as stored by Marlowe tooling
+// for contracts that are part way through their execution.
+func LoadContractWithState(r io.Reader) (Contract, State, error) {
+	var document struct {
+		Contract json.RawMessage `json:"contract"`
+		State    json.RawMessage `json:"state"`
+	}
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, State{}, err
+	}
+	if document.Contract == nil || document.State == nil {
+		return nil, State{}, fmt.Errorf("document must have both a \"contract\" and a \"state\"")
+	}
+
+	contract, err := UnmarshalContract(document.Contract)
+	if err != nil {
+		return nil, State{}, fmt.Errorf("contract: %w", err)
+	}
+
+	state, err := unmarshalState(document.State)
+	if err != nil {
+		return nil, State{}, fmt.Errorf("state: %w", err)
+	}
+
+	return contract, state, nil
+}
+
+// Decode the state from the association lists of the Haskell implementation:
+//
+//	{"accounts": [[[accountId, token], balance], ...],
+//	 "choices": [[choiceId, chosenNum], ...],
+//	 "boundValues": [[valueId, integer], ...],
+//	 "minTime": posixTime}
+func unmarshalState(data []byte) (State, error) {
+	var document struct {
+		Accounts    [][2]json.RawMessage `json:"accounts"`
+		Choices     [][2]json.RawMessage `json:"choices"`
+		BoundValues [][2]json.RawMessage `json:"boundValues"`
+		MinTime     *POSIXTime           `json:"minTime"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return State{}, err
+	}
+	if document.MinTime == nil {
+		return State{}, fmt.Errorf("state has no \"minTime\"")
+	}
+
+	state := State{
+		Accounts:    make(Accounts, len(document.Accounts)),
+		Choices:     make(map[ChoiceId]ChosenNum, len(document.Choices)),
+		BoundValues: make(map[ValueId]*big.Int, len(document.BoundValues)),
+		MinTime:     *document.MinTime,
+	}
+
+	for _, entry := range document.Accounts {
+		var key [2]json.RawMessage
+		if err := json.Unmarshal(entry[0], &key); err != nil {
+			return State{}, fmt.Errorf("account: %w", err)
+		}
+		owner, err := UnmarshalParty(key[0])
+		if err != nil {
+			return State{}, fmt.Errorf("account: %w", err)
+		}
+		var token Token
+		if err := json.Unmarshal(key[1], &token); err != nil {
+			return State{}, fmt.Errorf("account token: %w", err)
+		}
+		var balance uint64
+		if err := json.Unmarshal(entry[1], &balance); err != nil {
+			return State{}, fmt.Errorf("account balance: %w", err)
+		}
+		state.Accounts[Account{AccountId: owner, Token: token}] = balance
+	}
+
+	for _, entry := range document.Choices {
+		choice, err := unmarshalChoiceId(entry[0])
+		if err != nil {
+			return State{}, err
+		}
+		var num ChosenNum
+		if err := json.Unmarshal(entry[1], &num); err != nil {
+			return State{}, fmt.Errorf("chosen number: %w", err)
+		}
+		state.Choices[choice] = num
+	}
+
+	for _, entry := range document.BoundValues {
+		var id ValueId
+		if err := json.Unmarshal(entry[0], &id); err != nil {
+			return State{}, fmt.Errorf("value id: %w", err)
+		}
+		var c Constant
+		if err := c.UnmarshalJSON(entry[1]); err != nil {
+			return State{}, fmt.Errorf("bound value: %w", err)
+		}
+		state.BoundValues[id] = c.Int()
+	}
+
+	return state, nil
+}
+
+// A JSON object whose fields are decoded on demand.
+type jsonObject map[string]json.RawMessage
+
+func unmarshalObject(data []byte, what string) (jsonObject, error) {
+	var object jsonObject
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return nil, fmt.Errorf("%v is not a JSON object: %s", what, data)
+	}
+	return object, nil
+}
+
+func (o jsonObject) has(key string) bool {
+	_, ok := o[key]
+	return ok
+}
+
+// Return the field, or an error naming the construct that lacks it.
+func (o jsonObject) field(construct, key string) (json.RawMessage, error) {
+	raw, ok := o[key]
+	if !ok {
+		return nil, fmt.Errorf("%v has no %q", construct, key)
+	}
+	return raw, nil
+}
+
+func (o jsonObject) unknown(what string) error {
+	keys := make([]string, 0, len(o))
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("unknown %v with keys %q", what, keys)
+}
+
+// Decode each of the named fields in turn, stopping at the first error.
+func (o jsonObject) decode(construct string, fields ...func(jsonObject) error) error {
+	for _, field := range fields {
+		if err := field(o); err != nil {
+			return fmt.Errorf("%v: %w", construct, err)
+		}
+	}
+	return nil
+}
+
+func contractField(key string, c *Contract) func(jsonObject) error {
+	return func(o jsonObject) (err error) {
+		raw, ok := o[key]
+		if !ok {
+			return fmt.Errorf("no %q", key)
+		}
+		*c, err = UnmarshalContract(raw)
+		return err
+	}
+}
+
+func valueField(key string, v *Value) func(jsonObject) error {
+	return func(o jsonObject) (err error) {
+		raw, ok := o[key]
+		if !ok {
+			return fmt.Errorf("no %q", key)
+		}
+		*v, err = UnmarshalValue(raw)
+		return err
+	}
+}
+
+func observationField(key string, obs *Observation) func(jsonObject) error {
+	return func(o jsonObject) (err error) {
+		raw, ok := o[key]
+		if !ok {
+			return fmt.Errorf("no %q", key)
+		}
+		*obs, err = UnmarshalObservation(raw)
+		return err
+	}
+}
+
+func partyField(key string, p *Party) func(jsonObject) error {
+	return func(o jsonObject) (err error) {
+		raw, ok := o[key]
+		if !ok {
+			return fmt.Errorf("no %q", key)
+		}
+		*p, err = UnmarshalParty(raw)
+		return err
+	}
+}
+
+func accountField(key string, a *AccountId) func(jsonObject) error {
+	return func(o jsonObject) error {
+		var p Party
+		err := partyField(key, &p)(o)
+		*a = p
+		return err
+	}
+}
+
+func jsonField(key string, v any) func(jsonObject) error {
+	return func(o jsonObject) error {
+		raw, ok := o[key]
+		if !ok {
+			return fmt.Errorf("no %q", key)
+		}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return fmt.Errorf("%q: %w", key, err)
+		}
+		return nil
+	}
+}
+
+func unmarshalWhen(o jsonObject) (Contract, error) {
+	var c When
+	var cases []json.RawMessage
+	var timeout POSIXTime
+	err := o.decode("When",
+		jsonField("when", &cases),
+		jsonField("timeout", &timeout),
+		contractField("timeout_continuation", &c.Then),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Timeout = timeout
+	for _, raw := range cases {
+		cs, err := unmarshalCase(raw)
+		if err != nil {
+			return nil, err
+		}
+		c.Cases = append(c.Cases, cs)
+	}
+
+	return c, nil
+}
+
+func unmarshalCase(data []byte) (Case, error) {
+	var c Case
+	object, err := unmarshalObject(data, "case")
+	if err != nil {
+		return c, err
+	}
+
+	raw, err := object.field("Case", "case")
+	if err != nil {
+		return c, err
+	}
+	if c.Action, err = unmarshalAction(raw); err != nil {
+		return c, err
+	}
+
+	if object.has("merkleized_then") {
+		err = object.decode("Case", jsonField("merkleized_then", &c.MerkleizedThen))
+	} else {
+		err = object.decode("Case", contractField("then", &c.Then))
+	}
+	return c, err
+}
+
+func unmarshalPay(o jsonObject) (Contract, error) {
+	var c Pay
+	var to json.RawMessage
+	err := o.decode("Pay",
+		accountField("from_account", &c.From),
+		jsonField("to", &to),
+		jsonField("token", &c.Token),
+		valueField("pay", &c.Pay),
+		contractField("then", &c.Then),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.To, err = unmarshalPayee(to)
+	return c, err
+}
+
+func unmarshalIf(o jsonObject) (Contract, error) {
+	var c If
+	err := o.decode("If",
+		observationField("if", &c.Observe),
+		contractField("then", &c.Then),
+		contractField("else", &c.Else),
+	)
+	return c, err
+}
+
+func unmarshalLet(o jsonObject) (Contract, error) {
+	var c Let
+	err := o.decode("Let",
+		jsonField("let", &c.Name),
+		valueField("be", &c.Value),
+		contractField("then", &c.Then),
+	)
+	return c, err
+}
+
+func unmarshalAssert(o jsonObject) (Contract, error) {
+	var c Assert
+	err := o.decode("Assert",
+		observationField("assert", &c.Observe),
+		contractField("then", &c.Then),
+	)
+	return c, err
+}
+
+func unmarshalAction(data []byte) (Action, error) {
+	object, err := unmarshalObject(data, "action")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case object.has("into_account"):
+		var a Deposit
+		err := object.decode("Deposit",
+			accountField("into_account", &a.IntoAccount),
+			partyField("party", &a.Party),
+			jsonField("of_token", &a.Token),
+			valueField("deposits", &a.Deposits),
+		)
+		return a, err
+	case object.has("for_choice"):
+		var a Choice
+		var choice json.RawMessage
+		err := object.decode("Choice",
+			jsonField("for_choice", &choice),
+			jsonField("choose_between", &a.Bounds),
+		)
+		if err != nil {
+			return nil, err
+		}
+		a.ChoiceId, err = unmarshalChoiceId(choice)
+		return a, err
+	case object.has("notify_if"):
+		var a Notify
+		err := object.decode("Notify", observationField("notify_if", &a.If))
+		return a, err
+	}
+
+	return nil, object.unknown("action")
+}
+
+func unmarshalChoiceId(data []byte) (ChoiceId, error) {
+	var c ChoiceId
+	object, err := unmarshalObject(data, "choice id")
+	if err != nil {
+		return c, err
+	}
+
+	err = object.decode("ChoiceId",
+		jsonField("choice_name", &c.Name),
+		partyField("choice_owner", &c.Owner),
+	)
+	return c, err
+}
+
+// UnmarshalParty decodes a Party: {"role_token": ...} is a Role and
+// {"address": ...} an Address. An object with neither key is an error.
+func UnmarshalParty(data []byte) (Party, error) {
+	object, err := unmarshalObject(data, "party")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case object.has("role_token"):
+		var r Role
+		err := json.Unmarshal(data, &r)
+		return r, err
+	case object.has("address"):
+		var a Address
+		err := json.Unmarshal(data, &a)
+		return a, err
+	}
+
+	return nil, object.unknown("party")
+}
+
+// UnmarshalInput decodes an Input from the JSON written by its MarshalJSON:
+// an object with "that_deposits" is an IDeposit, one with "for_choice_id" an
+// IChoice, and the string "input_notify" an INotify.
+func UnmarshalInput(data []byte) (Input, error) {
+	var notify string
+	if err := json.Unmarshal(data, &notify); err == nil {
+		if notify != "input_notify" {
+			return nil, fmt.Errorf("unknown input %q", notify)
+		}
+		return INotify{}, nil
+	}
+
+	object, err := unmarshalObject(data, "input")
+	if err != nil {
+		return nil, err
+	}
+
+	if object.has("continuation_hash") {
+		var i MerkleizedInput
+		err := object.decode("MerkleizedInput",
+			jsonField("continuation_hash", &i.Hash),
+			contractField("merkleized_continuation", &i.Continuation),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// The rest of the object is the input, or nothing for an INotify.
+		delete(object, "continuation_hash")
+		delete(object, "merkleized_continuation")
+		if len(object) == 0 {
+			i.Input = INotify{}
+			return i, nil
+		}
+		content, err := json.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		i.Input, err = UnmarshalInput(content)
+		return i, err
+	}
+
+	switch {
+	case object.has("that_deposits"):
+		var i IDeposit
+		var value Constant
+		err := object.decode("IDeposit",
+			partyField("input_from_party", &i.Party),
+			jsonField("that_deposits", &value),
+			jsonField("of_token", &i.Token),
+			accountField("into_account", &i.AccountId),
+		)
+		i.Value = value.Int()
+		return i, err
+	case object.has("for_choice_id"):
+		var i IChoice
+		err := object.decode("IChoice",
+			jsonField("for_choice_id", &i.ChoiceId),
+			jsonField("input_that_chooses_num", &i.ChosenNum),
+		)
+		return i, err
+	}
+
+	return nil, object.unknown("input")
+}
+
+// Unmarshal the IDeposit, decoding its parties with UnmarshalParty.
+func (i *IDeposit) UnmarshalJSON(data []byte) error {
+	input, err := UnmarshalInput(data)
+	if err != nil {
+		return err
+	}
+	deposit, ok := input.(IDeposit)
+	if !ok {
+		return fmt.Errorf("input %s is not an IDeposit", data)
+	}
+	*i = deposit
+	return nil
+}
+
+// Unmarshal the IChoice from its {"for_choice_id": ...} form.
+func (i *IChoice) UnmarshalJSON(data []byte) error {
+	input, err := UnmarshalInput(data)
+	if err != nil {
+		return err
+	}
+	choice, ok := input.(IChoice)
+	if !ok {
+		return fmt.Errorf("input %s is not an IChoice", data)
+	}
+	*i = choice
+	return nil
+}
+
+// Unmarshal the INotify from the string "input_notify".
+func (i *INotify) UnmarshalJSON(data []byte) error {
+	input, err := UnmarshalInput(data)
+	if err != nil {
+		return err
+	}
+	if _, ok := input.(INotify); !ok {
+		return fmt.Errorf("input %s is not an INotify", data)
+	}
+	return nil
+}
+
+// Unmarshal the ChoiceId, decoding its owner with UnmarshalParty.
+func (c *ChoiceId) UnmarshalJSON(data []byte) (err error) {
+	*c, err = unmarshalChoiceId(data)
+	return err
+}
+
+// Unmarshal the Payee from its {"account": ...} or {"party": ...} form.
+func (p *Payee) UnmarshalJSON(data []byte) (err error) {
+	*p, err = unmarshalPayee(data)
+	return err
+}
+
+func unmarshalPayee(data []byte) (Payee, error) {
+	var p Payee
+	object, err := unmarshalObject(data, "payee")
+	if err != nil {
+		return p, err
+	}
+
+	switch {
+	case object.has("account"):
+		err = object.decode("Payee", accountField("account", &p.Account))
+	case object.has("party"):
+		err = object.decode("Payee", partyField("party", &p.Party))
+	default:
+		err = object.unknown("payee")
+	}
+	return p, err
+}
+
+// UnmarshalValue decodes a Value from its JSON. Each form decodes to exactly
+// one Value: a number is a Constant, "time_interval_start" and
+// "time_interval_end" are the TimeIntervalValues, and an object is the Value
+// named by its distinguishing key, e.g. "add" for AddValue and "minus" for
+// SubValue. Observations, which share keys such as "and" and "value" with
+// Values, are decoded only by UnmarshalObservation and are an error here.
+func UnmarshalValue(data []byte) (Value, error) {
+	var n json.Number
+	if json.Unmarshal(data, &n) == nil {
+		var c Constant
+		err := c.UnmarshalJSON(data)
+		return c, err
+	}
+
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		switch TimeIntervalValue(s) {
+		case TimeIntervalStart, TimeIntervalEnd:
+			return TimeIntervalValue(s), nil
+		}
+		return nil, fmt.Errorf("unknown value %q", s)
+	}
+
+	object, err := unmarshalObject(data, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case object.has("amount_of_token"):
+		var v AvailableMoney
+		err := object.decode("AvailableMoney",
+			jsonField("amount_of_token", &v.Amount),
+			accountField("in_account", &v.Account),
+		)
+		return v, err
+	case object.has("value_of_choice"):
+		var raw json.RawMessage
+		if err := object.decode("ChoiceValue", jsonField("value_of_choice", &raw)); err != nil {
+			return nil, err
+		}
+		choice, err := unmarshalChoiceId(raw)
+		return ChoiceValue{Value: choice}, err
+	case object.has("use_value"):
+		var v UseValue
+		err := object.decode("UseValue", jsonField("use_value", &v.Value))
+		return v, err
+	case object.has("negate"):
+		var v NegValue
+		err := object.decode("NegValue", valueField("negate", &v.Neg))
+		return v, err
+	case object.has("add"):
+		var v AddValue
+		err := object.decode("AddValue", valueField("add", &v.Add), valueField("and", &v.To))
+		return v, err
+	case object.has("minus"):
+		var v SubValue
+		err := object.decode("SubValue", valueField("minus", &v.Subtract), valueField("value", &v.From))
+		return v, err
+	case object.has("multiply"):
+		var v MulValue
+		err := object.decode("MulValue", valueField("multiply", &v.Multiply), valueField("times", &v.By))
+		return v, err
+	case object.has("divide"):
+		var v DivValue
+		err := object.decode("DivValue", valueField("divide", &v.Divide), valueField("by", &v.By))
+		return v, err
+	case object.has("if"):
+		var v Cond
+		err := object.decode("Cond",
+			observationField("if", &v.Observation),
+			valueField("then", &v.IfTrue),
+			valueField("else", &v.IfFalse),
+		)
+		return v, err
+	}
+
+	return nil, object.unknown("value")
+}
+
+// UnmarshalObservation decodes an Observation from its JSON: true and false
+// are the BoolObs constants, and an object is the Observation named by its
+// distinguishing key, e.g. "both" for AndObs and "gt" for ValueGT.
+func UnmarshalObservation(data []byte) (Observation, error) {
+	var b bool
+	if json.Unmarshal(data, &b) == nil {
+		return BoolObs(b), nil
+	}
+
+	object, err := unmarshalObject(data, "observation")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case object.has("both"):
+		var o AndObs
+		err := object.decode("AndObs", observationField("both", &o.Both), observationField("and", &o.And))
+		return o, err
+	case object.has("either"):
+		var o OrObs
+		err := object.decode("OrObs", observationField("either", &o.Either), observationField("or", &o.Or))
+		return o, err
+	case object.has("not"):
+		var o NotObs
+		err := object.decode("NotObs", observationField("not", &o.Not))
+		return o, err
+	case object.has("chose_something_for"):
+		var raw json.RawMessage
+		if err := object.decode("ChoseSomething", jsonField("chose_something_for", &raw)); err != nil {
+			return nil, err
+		}
+		choice, err := unmarshalChoiceId(raw)
+		return ChoseSomething{Choice: choice}, err
+	case object.has("ge_than"):
+		var o ValueGE
+		err := object.decode("ValueGE", valueField("value", &o.Value), valueField("ge_than", &o.Ge))
+		return o, err
+	case object.has("gt"):
+		var o ValueGT
+		err := object.decode("ValueGT", valueField("value", &o.Value), valueField("gt", &o.Gt))
+		return o, err
+	case object.has("lt"):
+		var o ValueLT
+		err := object.decode("ValueLT", valueField("value", &o.Value), valueField("lt", &o.Lt))
+		return o, err
+	case object.has("le_than"):
+		var o ValueLE
+		err := object.decode("ValueLE", valueField("value", &o.Value), valueField("le_than", &o.Le))
+		return o, err
+	case object.has("equal_to"):
+		var o ValueEQ
+		err := object.decode("ValueEQ", valueField("value", &o.Value), valueField("equal_to", &o.Eq))
+		return o, err
+	}
+
+	return nil, object.unknown("observation")
+}
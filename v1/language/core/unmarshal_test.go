@@ -0,0 +1,321 @@
+package language_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// Decoding the JSON of a contract and marshalling it again should give back
+// the same JSON.
+func assertRoundTrip(t *testing.T, contract m.Contract) {
+	t.Helper()
+
+	want, err := json.Marshal(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := m.UnmarshalContract(want)
+	if err != nil {
+		t.Fatalf("Unmarshalling %s: %v", want, err)
+	}
+
+	got, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Round trip changed the contract:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestUnmarshalContract_Escrow(t *testing.T) {
+	assertRoundTrip(t, setupEscrowContract())
+}
+
+func TestUnmarshalContract_AllConstructs(t *testing.T) {
+	buyer, seller := m.Role{Name: "buyer"}, m.Address("addr1_seller")
+	choice := m.ChoiceId{Name: "price", Owner: seller}
+
+	contract := m.When{
+		Cases: []m.Case{
+			{
+				Action: m.Choice{ChoiceId: choice, Bounds: []m.Bound{{Lower: 1, Upper: 100}}},
+				Then: m.Let{
+					Name:  "price",
+					Value: m.ChoiceValue{Value: choice},
+					Then: m.Assert{
+						Observe: m.ChoseSomething{Choice: choice},
+						Then: m.If{
+							Observe: m.ValueGT{Value: m.UseValue{Value: "price"}, Gt: m.SetConstant("50")},
+							Then: m.Pay{
+								From:  buyer,
+								To:    m.Payee{Account: seller},
+								Token: m.Token{Symbol: "abcd", Name: "coin"},
+								Pay:   m.SubValue{Subtract: m.UseValue{Value: "price"}, From: m.SetConstant("1")},
+								Then:  m.Close,
+							},
+							Else: m.Pay{
+								From:  buyer,
+								To:    m.Payee{Party: seller},
+								Token: m.Ada,
+								Pay:   m.AvailableMoney{Amount: m.Ada, Account: buyer},
+								Then:  m.Close,
+							},
+						},
+					},
+				},
+			},
+			{
+				Action: m.Notify{If: m.AndObs{Both: m.TrueObs, And: m.NotObs{Not: m.FalseObs}}},
+				Then:   m.Close,
+			},
+			m.MerkleizedCase(
+				m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.TimeIntervalStart},
+				"0123abcd",
+			),
+		},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+
+	assertRoundTrip(t, contract)
+}
+
+func TestUnmarshalContract_UnknownKeys(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`"open"`, `unknown contract "open"`},
+		{`{"pay_to":"close"}`, `unknown contract with keys ["pay_to"]`},
+		{`{"if":true,"then":"close"}`, `If: no "else"`},
+		{`{"let":"x","be":{"sum":1},"then":"close"}`, `unknown value with keys ["sum"]`},
+		{`42`, `contract is not a JSON object`},
+	}
+
+	for _, test := range tests {
+		contract, err := m.UnmarshalContract([]byte(test.data))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: expected an error containing %q, got: %v, %v", test.data, test.want, contract, err)
+		}
+	}
+}
+
+func TestUnmarshalOptions_UnmarshalContract(t *testing.T) {
+	data := []byte(`{"assert":true,"then":"close","else":"close"}`)
+
+	if _, err := (m.UnmarshalOptions{}).UnmarshalContract(data); err != nil {
+		t.Errorf("Expected the unknown key to be ignored, got: %v", err)
+	}
+	if _, err := (m.UnmarshalOptions{Strict: true}).UnmarshalContract(data); err == nil {
+		t.Error("Expected the unknown key to be rejected")
+	}
+}
+
+func TestUnmarshalValue_AllValues(t *testing.T) {
+	choice := m.ChoiceId{Name: "price", Owner: m.Role{Name: "oracle"}}
+	values := []m.Value{
+		m.SetConstant("-7"),
+		m.SetConstant("123456789012345678901234567890"),
+		m.TimeIntervalStart,
+		m.TimeIntervalEnd,
+		m.AvailableMoney{Amount: m.Ada, Account: m.Address("addr1_buyer")},
+		m.ChoiceValue{Value: choice},
+		m.UseValue{Value: "x"},
+		m.NegValue{Neg: m.SetConstant("1")},
+		m.AddValue{Add: m.SetConstant("1"), To: m.SetConstant("2")},
+		m.SubValue{Subtract: m.SetConstant("1"), From: m.SetConstant("2")},
+		m.MulValue{Multiply: m.SetConstant("1"), By: m.SetConstant("2")},
+		m.DivValue{Divide: m.SetConstant("1"), By: m.SetConstant("2")},
+		m.Cond{Observation: m.TrueObs, IfTrue: m.SetConstant("1"), IfFalse: m.SetConstant("2")},
+	}
+
+	for _, value := range values {
+		want, _ := json.Marshal(value)
+		decoded, err := m.UnmarshalValue(want)
+		if err != nil {
+			t.Errorf("Unmarshalling %s: %v", want, err)
+			continue
+		}
+		if got, _ := json.Marshal(decoded); string(got) != string(want) {
+			t.Errorf("Round trip changed the value: got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestUnmarshalObservation_AllObservations(t *testing.T) {
+	one, two := m.SetConstant("1"), m.SetConstant("2")
+	observations := []m.Observation{
+		m.TrueObs,
+		m.FalseObs,
+		m.AndObs{Both: m.TrueObs, And: m.FalseObs},
+		m.OrObs{Either: m.TrueObs, Or: m.FalseObs},
+		m.NotObs{Not: m.TrueObs},
+		m.ChoseSomething{Choice: m.ChoiceId{Name: "price", Owner: m.Role{Name: "oracle"}}},
+		m.ValueGE{Value: one, Ge: two},
+		m.ValueGT{Value: one, Gt: two},
+		m.ValueLT{Value: one, Lt: two},
+		m.ValueLE{Value: one, Le: two},
+		m.ValueEQ{Value: one, Eq: two},
+	}
+
+	for _, observation := range observations {
+		want, _ := json.Marshal(observation)
+		decoded, err := m.UnmarshalObservation(want)
+		if err != nil {
+			t.Errorf("Unmarshalling %s: %v", want, err)
+			continue
+		}
+		if got, _ := json.Marshal(decoded); string(got) != string(want) {
+			t.Errorf("Round trip changed the observation: got %s, want %s", got, want)
+		}
+	}
+}
+
+// Values and observations sharing keys are told apart by their distinguishing
+// key, and each decoder only accepts its own kind.
+func TestUnmarshalValue_SharedKeys(t *testing.T) {
+	if v, err := m.UnmarshalValue([]byte(`{"minus":1,"value":2}`)); err != nil {
+		t.Error(err)
+	} else if _, ok := v.(m.SubValue); !ok {
+		t.Errorf("Expected a SubValue, got: %T", v)
+	}
+
+	if _, err := m.UnmarshalValue([]byte(`{"value":1,"ge_than":2}`)); err == nil {
+		t.Error("Expected an observation to be rejected as a value")
+	}
+	if _, err := m.UnmarshalObservation([]byte(`{"add":1,"and":2}`)); err == nil {
+		t.Error("Expected a value to be rejected as an observation")
+	}
+	if _, err := m.UnmarshalValue([]byte(`1.5`)); err == nil {
+		t.Error("Expected a fractional constant to be rejected")
+	}
+}
+
+func TestUnmarshalParty(t *testing.T) {
+	role, err := m.UnmarshalParty([]byte(`{"role_token":"buyer"}`))
+	if err != nil || role != m.Party(m.Role{Name: "buyer"}) {
+		t.Errorf("Expected the buyer role, got: %v, %v", role, err)
+	}
+
+	address, err := m.UnmarshalParty([]byte(`{"address":"addr1_buyer"}`))
+	if err != nil || address != m.Party(m.Address("addr1_buyer")) {
+		t.Errorf("Expected the buyer address, got: %v, %v", address, err)
+	}
+
+	if party, err := m.UnmarshalParty([]byte(`{"pk_hash":"abcd"}`)); err == nil {
+		t.Errorf("Expected an error for an unknown party, got: %v", party)
+	}
+}
+
+func TestUnmarshalParty_ChoiceOwnerAndPayee(t *testing.T) {
+	var choice m.ChoiceId
+	if err := json.Unmarshal([]byte(`{"choice_name":"price","choice_owner":{"address":"addr1_oracle"}}`), &choice); err != nil {
+		t.Fatal(err)
+	}
+	if choice.Owner != m.Party(m.Address("addr1_oracle")) {
+		t.Errorf("Expected an address owner, got: %#v", choice.Owner)
+	}
+
+	var payee m.Payee
+	if err := json.Unmarshal([]byte(`{"party":{"role_token":"seller"}}`), &payee); err != nil {
+		t.Fatal(err)
+	}
+	if payee.Party != m.Party(m.Role{Name: "seller"}) || payee.Account != nil {
+		t.Errorf("Expected the seller role as payee, got: %#v", payee)
+	}
+}
+
+func TestLoadContractWithState(t *testing.T) {
+	document := `{
+		"contract": {
+			"when": [{"case": {"notify_if": true}, "then": "close"}],
+			"timeout": 1666165377926,
+			"timeout_continuation": "close"
+		},
+		"state": {
+			"accounts": [[[{"role_token": "buyer"}, {"currency_symbol": "", "token_name": ""}], 50000000]],
+			"choices": [[{"choice_name": "approve", "choice_owner": {"address": "addr1_buyer"}}, 1]],
+			"boundValues": [["price", 123456789012345678901234567890]],
+			"minTime": 1666078977926
+		}
+	}`
+
+	contract, state, err := m.LoadContractWithState(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if when, ok := contract.(m.When); !ok || len(when.Cases) != 1 || when.Timeout != m.POSIXTime(1666165377926) {
+		t.Errorf("Expected a When with one case, got: %#v", contract)
+	}
+
+	account := m.Account{AccountId: m.Role{Name: "buyer"}, Token: m.Ada}
+	if len(state.Accounts) != 1 || state.Accounts[account] != 50000000 {
+		t.Errorf("Expected the buyer's balance, got: %v", state.Accounts)
+	}
+	choice := m.ChoiceId{Name: "approve", Owner: m.Address("addr1_buyer")}
+	if num, ok := state.Choices[choice]; !ok || num.String() != "1" {
+		t.Errorf("Expected the approve choice, got: %v", state.Choices)
+	}
+	if price := state.BoundValues["price"]; price == nil || price.String() != "123456789012345678901234567890" {
+		t.Errorf("Expected the bound price, got: %v", state.BoundValues)
+	}
+	if state.MinTime != 1666078977926 {
+		t.Errorf("Expected the min time, got: %v", state.MinTime)
+	}
+}
+
+func TestLoadContractWithState_Missing(t *testing.T) {
+	if _, _, err := m.LoadContractWithState(strings.NewReader(`{"contract": "close"}`)); err == nil {
+		t.Error("Expected an error for a document without a state")
+	}
+}
+
+func TestState_JSON(t *testing.T) {
+	state := `{"accounts":[[[{"address":"addr1_seller"},{"currency_symbol":"8bb3","token_name":"coin"}],3],` +
+		`[[{"role_token":"buyer"},{"currency_symbol":"","token_name":""}],50000000]],` +
+		`"choices":[[{"choice_name":"approve","choice_owner":{"address":"addr1_buyer"}},-1]],` +
+		`"boundValues":[["a",1],["price",123456789012345678901234567890]],` +
+		`"minTime":1666078977926}`
+
+	var decoded m.State
+	if err := json.Unmarshal([]byte(state), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	account := m.Account{AccountId: m.Address("addr1_seller"), Token: m.Token{Symbol: "8bb3", Name: "coin"}}
+	if len(decoded.Accounts) != 2 || decoded.Accounts[account] != 3 {
+		t.Errorf("Expected the seller's coin balance, got: %v", decoded.Accounts)
+	}
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != state {
+		t.Errorf("Expected %v, got: %s", state, encoded)
+	}
+
+	// Within another document the State is marshalled in the same shape.
+	wrapped, err := json.Marshal(struct{ State m.State }{decoded})
+	if err != nil || string(wrapped) != `{"State":`+state+`}` {
+		t.Errorf("Expected the embedded state to marshal as %v, got: %s (%v)", state, wrapped, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"accounts": []}`), &decoded); err == nil {
+		t.Error("Expected an error for a state without a min time")
+	}
+}
+
+func TestState_JSONEmpty(t *testing.T) {
+	encoded, err := json.Marshal(m.State{})
+	expected := `{"accounts":[],"choices":[],"boundValues":[],"minTime":0}`
+	if err != nil || string(encoded) != expected {
+		t.Errorf("Expected %v, got: %s (%v)", expected, encoded, err)
+	}
+}
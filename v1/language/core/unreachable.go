@@ -0,0 +1,75 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import "fmt"
+
+// A Path identifies a node of a contract by the fields followed from the root
+// to reach it, joined by dots: "then" and "else" for the continuations of an
+// If, "timeout_continuation" for that of a When, "when[i]" for its i-th case
+// and "when[i].then" for the continuation of that case, for example
+// "when[0].then.else". The root itself is the empty path.
+type Path = string
+
+// FindUnreachable reports the nodes of the contract that can never execute:
+// the Else of an If whose observation is TrueObs, the Then of an If whose
+// observation is FalseObs, and every Notify case of a When that follows a
+// Notify case on TrueObs, since any INotify matches the earlier case first.
+// Only the root of each dead subtree is reported, in the order they appear.
+func FindUnreachable(c Contract) []Path {
+	return findUnreachable(c, "")
+}
+
+func findUnreachable(c Contract, path Path) []Path {
+	var dead []Path
+
+	switch c := c.(type) {
+	case Pay:
+		dead = findUnreachable(c.Then, joinPath(path, "then"))
+	case If:
+		obs, constant := c.Observe.(BoolObs)
+		if constant && obs == FalseObs {
+			dead = append(dead, joinPath(path, "then"))
+		} else {
+			dead = append(dead, findUnreachable(c.Then, joinPath(path, "then"))...)
+		}
+		if constant && obs == TrueObs {
+			dead = append(dead, joinPath(path, "else"))
+		} else {
+			dead = append(dead, findUnreachable(c.Else, joinPath(path, "else"))...)
+		}
+	case Let:
+		dead = findUnreachable(c.Then, joinPath(path, "then"))
+	case Assert:
+		dead = findUnreachable(c.Then, joinPath(path, "then"))
+	case When:
+		notified := false
+		for i, cs := range c.Cases {
+			casePath := joinPath(path, fmt.Sprintf("when[%d]", i))
+			notify, ok := cs.Action.(Notify)
+			if ok && notified {
+				dead = append(dead, casePath)
+				continue
+			}
+			if ok && notify.If == TrueObs {
+				notified = true
+			}
+			dead = append(dead, findUnreachable(cs.Then, joinPath(casePath, "then"))...)
+		}
+		dead = append(dead, findUnreachable(c.Then, joinPath(path, "timeout_continuation"))...)
+	}
+
+	return dead
+}
@@ -0,0 +1,39 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestFindUnreachable(t *testing.T) {
+	if dead := m.FindUnreachable(setupEscrowContract()); len(dead) != 0 {
+		t.Errorf("Expected every node to be reachable, got: %v", dead)
+	}
+
+	buyer := m.Role{Name: "buyer"}
+	contract := m.When{
+		Cases: []m.Case{
+			{Action: m.Notify{If: m.TrueObs}, Then: m.If{Observe: m.TrueObs, Then: m.Close, Else: m.Close}},
+			{Action: m.Deposit{IntoAccount: buyer, Party: buyer, Token: m.Ada, Deposits: m.SetConstant("5")}, Then: m.Close},
+			{Action: m.Notify{If: m.FalseObs}, Then: m.Close},
+		},
+		Timeout: m.POSIXTime(100),
+		Then: m.If{
+			Observe: m.FalseObs,
+			Then:    m.Close,
+			Else:    m.If{Observe: m.ValueGT{Value: m.SetConstant("1"), Gt: m.SetConstant("0")}, Then: m.Close, Else: m.Close},
+		},
+	}
+
+	expected := []m.Path{"when[0].then.else", "when[2]", "timeout_continuation.then"}
+	got := m.FindUnreachable(contract)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got: %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got: %v", expected[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+type Severity uint8
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severities = [...]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	return severities[s]
+}
+
+// Names of the checks run by Validate, as reported in each Finding.
+const (
+	CheckTimeouts    = "timeouts"
+	CheckRoles       = "roles"
+	CheckIdentifiers = "identifiers"
+	CheckNetwork     = "network"
+	CheckBounds      = "bounds"
+	CheckShadowing   = "shadowing"
+	CheckRedundancy  = "redundancy"
+	CheckFundFlow    = "fund-flow"
+	CheckCancelled   = "cancelled" // reported by ValidateAll for skipped contracts
+	CheckComplete    = "complete"  // reported by Builder for unfinished contracts
+)
+
+// A Finding is a single issue reported by one of the checks run by Validate.
+type Finding struct {
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%v [%v]: %v", f.Severity, f.Check, f.Message)
+}
+
+// ValidationReport aggregates the findings of every check run by Validate,
+// sorted by descending severity and then by check name.
+type ValidationReport struct {
+	Findings []Finding
+}
+
+// HasErrors reports whether any finding has SeverityError.
+func (r ValidationReport) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOptions toggles which checks Validate runs.
+type ValidateOptions struct {
+	Timeouts    bool // nested When timeouts that do not increase
+	Roles       bool // role names too long to mint as role tokens
+	Identifiers bool // invalid tokens, and choices or values read before they are set
+	Network     bool // address parties that do not decode, or are on another network
+	Bounds      bool // Choice bounds that are inverted, overlapping or repeated
+	Shadowing   bool // Let bindings that shadow an earlier binding on the path
+	Redundancy  bool // unreachable branches and unused Let bindings
+	FundFlow    bool // deposits whose funds are never paid out
+
+	// ExpectedNetwork, when set, is the network every address party must
+	// belong to; addresses on another one are reported by the Network check.
+	ExpectedNetwork *Network
+}
+
+// DefaultValidateOptions enables every check.
+func DefaultValidateOptions() ValidateOptions {
+	return ValidateOptions{
+		Timeouts:    true,
+		Roles:       true,
+		Identifiers: true,
+		Network:     true,
+		Bounds:      true,
+		Shadowing:   true,
+		Redundancy:  true,
+		FundFlow:    true,
+	}
+}
+
+// Validate runs the checks enabled in opts over the contract and consolidates
+// their findings into a single report.
+func Validate(c Contract, opts ValidateOptions) ValidationReport {
+	var findings []Finding
+
+	if opts.Timeouts {
+		findings = append(findings, checkTimeouts(c)...)
+	}
+	if opts.Roles {
+		findings = append(findings, checkRoles(c)...)
+	}
+	if opts.Identifiers {
+		findings = append(findings, checkIdentifiers(c)...)
+	}
+	if opts.Network {
+		findings = append(findings, checkNetwork(c, opts.ExpectedNetwork)...)
+	}
+	if opts.Bounds {
+		findings = append(findings, checkBounds(c)...)
+	}
+	if opts.Shadowing {
+		findings = append(findings, checkShadowing(c)...)
+	}
+	if opts.Redundancy {
+		findings = append(findings, checkRedundancy(c)...)
+	}
+	if opts.FundFlow {
+		findings = append(findings, checkFundFlow(c)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		return findings[i].Check < findings[j].Check
+	})
+
+	return ValidationReport{Findings: findings}
+}
+
+// ValidateAll validates each of the contracts as Validate does, spreading the
+// work over a pool of GOMAXPROCS workers, and returns the reports in the order
+// of the contracts. Once ctx is cancelled no further contracts are validated;
+// the report of each contract skipped holds a single CheckCancelled error.
+func ValidateAll(ctx context.Context, cs []Contract, opts ValidateOptions) []ValidationReport {
+	reports := make([]ValidationReport, len(cs))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(cs) {
+		workers = len(cs)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				reports[i] = Validate(cs[i], opts)
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(cs) && ctx.Err() == nil; next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < len(cs); i++ {
+		reports[i] = ValidationReport{Findings: []Finding{{
+			Check:    CheckCancelled,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("contract was not validated: %v", ctx.Err()),
+		}}}
+	}
+
+	return reports
+}
+
+func checkTimeouts(c Contract) []Finding {
+	var findings []Finding
+
+	for _, w := range AnalyzeTimeouts(c) {
+		findings = append(findings, Finding{
+			Check:    CheckTimeouts,
+			Severity: SeverityWarning,
+			Message:  w.String(),
+		})
+	}
+
+	return findings
+}
+
+func checkNetwork(c Contract, expected *Network) []Finding {
+	var findings []Finding
+	seen := make(map[Address]bool)
+
+	inspect(c, func(node any) {
+		for _, p := range nodeParties(node) {
+			addr, ok := p.(Address)
+			if !ok || seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			if err := addr.ValidateEncoding(); err != nil {
+				findings = append(findings, Finding{
+					Check:    CheckNetwork,
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("address %q is not a valid ledger address: %v", addr, err),
+				})
+				continue
+			}
+			if expected == nil {
+				continue
+			}
+			if err := addr.ValidateNetwork(*expected); err != nil {
+				findings = append(findings, Finding{
+					Check:    CheckNetwork,
+					Severity: SeverityError,
+					Message:  err.Error(),
+				})
+			}
+		}
+	})
+
+	return findings
+}
+
+func checkBounds(c Contract) []Finding {
+	var findings []Finding
+
+	for _, w := range CheckChoiceBounds(c) {
+		// An inverted bound accepts no number; the others are merely redundant.
+		severity := SeverityWarning
+		if w.Kind == InvertedBound {
+			severity = SeverityError
+		}
+		findings = append(findings, Finding{
+			Check:    CheckBounds,
+			Severity: severity,
+			Message:  w.String(),
+		})
+	}
+
+	return findings
+}
+
+func checkShadowing(c Contract) []Finding {
+	var findings []Finding
+
+	for _, w := range CheckBindings(c) {
+		if w.Kind == ShadowedValue {
+			findings = append(findings, Finding{
+				Check:    CheckShadowing,
+				Severity: SeverityWarning,
+				Message:  w.String(),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkRoles(c Contract) []Finding {
+	var findings []Finding
+
+	for _, p := range Parties(c) {
+		role, ok := p.(Role)
+		if !ok {
+			continue
+		}
+		if err := role.Validate(); err != nil {
+			findings = append(findings, Finding{
+				Check:    CheckRoles,
+				Severity: SeverityError,
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkIdentifiers(c Contract) []Finding {
+	var findings []Finding
+
+	for _, token := range Tokens(c) {
+		if err := token.Validate(); err != nil {
+			findings = append(findings, Finding{
+				Check:    CheckIdentifiers,
+				Severity: SeverityError,
+				Message:  err.Error(),
+			})
+		}
+	}
+	for _, id := range DanglingChoiceReferences(c) {
+		findings = append(findings, Finding{
+			Check:    CheckIdentifiers,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("choice %q of %v is read before any Choice offers it", id.Name, id.Owner),
+		})
+	}
+	for _, w := range CheckBindings(c) {
+		if w.Kind == UnboundValue {
+			findings = append(findings, Finding{
+				Check:    CheckIdentifiers,
+				Severity: SeverityWarning,
+				Message:  w.String(),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkRedundancy(c Contract) []Finding {
+	var findings []Finding
+
+	for _, path := range FindUnreachable(c) {
+		findings = append(findings, Finding{
+			Check:    CheckRedundancy,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%v can never execute", path),
+		})
+	}
+	for _, id := range UnusedLets(c) {
+		findings = append(findings, Finding{
+			Check:    CheckRedundancy,
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("Let %q binds a value that is never used", id),
+		})
+	}
+
+	return findings
+}
+
+func checkFundFlow(c Contract) []Finding {
+	var findings []Finding
+
+	for _, d := range NoOpDeposits(c) {
+		findings = append(findings, Finding{
+			Check:    CheckFundFlow,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("deposit of %v by %v into %v is never paid out, only refunded", d.Token, d.Party, d.IntoAccount),
+		})
+	}
+
+	return findings
+}
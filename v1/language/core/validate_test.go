@@ -0,0 +1,166 @@
+package language_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestValidate_FlawedContract(t *testing.T) {
+	// An inner When that times out before the outer one, an address party
+	// with a bad checksum, an inverted bound, a shadowed Let, an If that can
+	// only take one branch, and a deposit by a role whose name is too long,
+	// of a token that cannot exist, of an unbound value, that is never paid
+	// out.
+	contract := m.Let{
+		Name:  "amount",
+		Value: m.SetConstant("10"),
+		Then: m.When{
+			Cases: []m.Case{
+				{
+					Action: m.Choice{
+						ChoiceId: m.ChoiceId{Name: "option", Owner: m.Address("li1dgmt3")},
+						Bounds:   []m.Bound{{Lower: 5, Upper: 1}},
+					},
+					Then: m.Let{
+						Name:  "amount",
+						Value: m.SetConstant("20"),
+						Then: m.When{
+							Cases: []m.Case{{
+								Action: m.Deposit{
+									IntoAccount: m.Role{Name: "seller"},
+									Party:       m.Role{Name: strings.Repeat("buyer", 10)},
+									Token:       m.Token{Symbol: "dollar", Name: "dollar"},
+									Deposits:    m.UseValue{Value: "price"},
+								},
+								Then: m.Close,
+							}},
+							Timeout: m.POSIXTime(1000),
+							Then:    m.If{Observe: m.TrueObs, Then: m.Close, Else: m.Close},
+						},
+					},
+				},
+			},
+			Timeout: m.POSIXTime(2000),
+			Then:    m.Close,
+		},
+	}
+
+	report := m.Validate(contract, m.DefaultValidateOptions())
+
+	checks := make(map[string]bool)
+	for _, f := range report.Findings {
+		checks[f.Check] = true
+	}
+
+	for _, check := range []string{
+		m.CheckTimeouts, m.CheckRoles, m.CheckIdentifiers, m.CheckNetwork,
+		m.CheckBounds, m.CheckShadowing, m.CheckRedundancy, m.CheckFundFlow,
+	} {
+		if !checks[check] {
+			t.Errorf("Expected a finding from the %v check", check)
+		}
+	}
+
+	if !report.HasErrors() {
+		t.Error("Expected the report to contain errors")
+	}
+
+	if report.Findings[0].Severity != m.SeverityError {
+		t.Errorf("Expected errors to be sorted first, got: %v", report.Findings[0])
+	}
+}
+
+func TestValidate_OptionsDisableChecks(t *testing.T) {
+	contract := setupWhenContract(m.Choice{
+		ChoiceId: m.ChoiceId{Name: "option", Owner: m.Role{Name: "creditor"}},
+		Bounds:   []m.Bound{{Lower: 5, Upper: 1}},
+	})
+
+	report := m.Validate(contract, m.ValidateOptions{})
+	if len(report.Findings) != 0 {
+		t.Errorf("Expected no findings with all checks disabled, got: %v", report.Findings)
+	}
+}
+
+func TestValidate_ExpectedNetwork(t *testing.T) {
+	mainnet := m.Address("addr1w94f8ywk4fg672xasahtk4t9k6w3aql943uxz5rt62d4dvq8evxaf")
+	contract := m.Pay{
+		From:  m.Role{Name: "buyer"},
+		To:    m.Payee{Party: mainnet},
+		Token: m.Ada,
+		Pay:   m.SetConstant("1"),
+		Then: m.Pay{
+			From:  m.Role{Name: "buyer"},
+			To:    m.Payee{Party: m.Address("addr1_seller")},
+			Token: m.Ada,
+			Pay:   m.SetConstant("1"),
+			Then:  m.Close,
+		},
+	}
+
+	messages := func(expected *m.Network) []string {
+		var messages []string
+		for _, f := range m.Validate(contract, m.ValidateOptions{Network: true, ExpectedNetwork: expected}).Findings {
+			messages = append(messages, f.Message)
+		}
+		return messages
+	}
+
+	// Without an expected network only the address that does not decode is
+	// reported, and it is not reported again as being on the wrong network.
+	for _, net := range []*m.Network{nil, ptr(m.Mainnet)} {
+		found := messages(net)
+		if len(found) != 1 || !strings.Contains(found[0], "addr1_seller") || !strings.Contains(found[0], "not a valid ledger address") {
+			t.Errorf("Expected only the encoding failure, got: %v", found)
+		}
+	}
+
+	found := messages(ptr(m.Testnet))
+	if len(found) != 2 || !strings.Contains(found[0], string(mainnet)) || !strings.Contains(found[0], "expected testnet") {
+		t.Errorf("Expected the mainnet address to be reported, got: %v", found)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestValidateAll(t *testing.T) {
+	contracts := make([]m.Contract, 100)
+	for i := range contracts {
+		contracts[i] = setupWhenContract(m.Choice{
+			ChoiceId: m.ChoiceId{Name: "option", Owner: m.Role{Name: "creditor"}},
+			Bounds:   []m.Bound{{Lower: uint64(i % 7), Upper: 3}},
+		})
+	}
+	opts := m.DefaultValidateOptions()
+
+	reports := m.ValidateAll(context.Background(), contracts, opts)
+
+	if len(reports) != len(contracts) {
+		t.Fatalf("Expected %v reports, got: %v", len(contracts), len(reports))
+	}
+	for i, c := range contracts {
+		expected := m.Validate(c, opts)
+		if !reflect.DeepEqual(reports[i], expected) {
+			t.Errorf("Report %v: expected %v, got: %v", i, expected, reports[i])
+		}
+	}
+}
+
+func TestValidateAll_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	reports := m.ValidateAll(ctx, []m.Contract{m.Close, m.Close}, m.DefaultValidateOptions())
+
+	for i, report := range reports {
+		if len(report.Findings) != 1 || report.Findings[0].Check != m.CheckCancelled {
+			t.Errorf("Report %v: expected the contract to be skipped, got: %v", i, report.Findings)
+		}
+	}
+}
@@ -18,6 +18,7 @@
 package language
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 )
@@ -87,18 +88,64 @@ const TimeIntervalEnd TimeIntervalValue = "time_interval_end"
 // MulValue x y, and DivValue x y provide the common arithmetic operations -
 // x, x + y, x − y, x ∗ y, and x / y, where division always rounds (truncates)
 // its result towards zero." (§2.1.5)
-type Constant Integer
+//
+// A Constant holds its integer by pointer, so that values built from it copy
+// a single word rather than the whole big.Int; the integer is shared by the
+// copies and must not be modified. The zero Constant is 0.
+type Constant struct {
+	n *big.Int
+}
+
+// NewConstant returns the Constant holding n, which the Constant takes over:
+// n must not be modified afterwards.
+func NewConstant(n *big.Int) Constant {
+	return Constant{n: n}
+}
+
+// Int returns the integer held by the Constant, which must not be modified.
+func (i Constant) Int() *big.Int {
+	if i.n == nil {
+		return new(big.Int)
+	}
+	return i.n
+}
 
 // Make Constant a custom type for the JSON marshaller, converting big.Int to a string
 func (i Constant) MarshalJSON() ([]byte, error) {
-	i2 := big.Int(i)
-	return []byte(fmt.Sprintf(`%s`, i2.String())), nil
+	return []byte(i.Int().String()), nil
+}
+
+// Unmarshal the Constant from a JSON integer of any size.
+func (i *Constant) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+
+	num, ok := new(big.Int).SetString(n.String(), 10)
+	if !ok {
+		return fmt.Errorf("constant %v is not an integer", n)
+	}
+	*i = Constant{n: num}
+	return nil
 }
 
+// SetConstant returns the Constant for the decimal integer s, which it
+// assumes to be valid: any other string yields the zero Constant. Use
+// SetConstantChecked for input that is not known to be valid.
 func SetConstant(s string) Constant {
-	bInt := big.NewInt(0)
-	num, _ := bInt.SetString(s, 10)
-	return Constant(*num)
+	num, _ := new(big.Int).SetString(s, 10)
+	return Constant{n: num}
+}
+
+// SetConstantChecked returns the Constant for the decimal integer s, with an
+// optional sign, or an error if s is not one.
+func SetConstantChecked(s string) (Constant, error) {
+	num, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Constant{}, fmt.Errorf("constant %q is not a decimal integer", s)
+	}
+	return Constant{n: num}, nil
 }
 
 type NegValue struct {
@@ -117,7 +164,8 @@ type MulValue struct {
 	By       Value `json:"times"`
 }
 
-// subtract() value (subtraction)
+// subtract() value (subtraction), evaluating to From − Subtract: the JSON
+// {"value": x, "minus": y} is x − y.
 type SubValue struct {
 	Subtract Value `json:"minus"`
 	From     Value `json:"value"`
@@ -132,9 +180,9 @@ type DivValue struct {
 // "Cond b x y represents a condition expression that evaluates to x if b is true
 // and to y otherwise." (§2.1.5)
 type Cond struct {
-	Observation bool
-	IfTrue      Value
-	IfFalse     Value
+	Observation Observation `json:"if"`
+	IfTrue      Value       `json:"then"`
+	IfFalse     Value       `json:"else"`
 }
 
 // "and Observation = AndObs Observation Observation
@@ -18,6 +18,8 @@
 package language_test
 
 import (
+	"encoding/json"
+	"math/big"
 	"testing"
 
 	assert "github.com/menabrealabs/marlowe/assertion"
@@ -127,6 +129,17 @@ func TestTypes_SubValue(t *testing.T) {
 	assert.Json(t, contract, `{"let":"testValue","be":{"minus":10,"value":20},"then":"close"}`)
 }
 
+func TestTypes_Cond(t *testing.T) {
+	contract := setupLetContract(
+		m.Cond{
+			Observation: m.ValueGT{Value: m.SetConstant("10"), Gt: m.SetConstant("5")},
+			IfTrue:      m.SetConstant("1"),
+			IfFalse:     m.SetConstant("0"),
+		},
+	)
+	assert.Json(t, contract, `{"let":"testValue","be":{"if":{"value":10,"gt":5},"then":1,"else":0},"then":"close"}`)
+}
+
 func TestTypes_DivValue(t *testing.T) {
 	contract := setupLetContract(
 		m.DivValue{
@@ -256,3 +269,80 @@ func TestTypes_FalseObs(t *testing.T) {
 	contract := setupIfContract(m.FalseObs)
 	assert.Json(t, contract, `{"if":false,"then":"close","else":"close"}`)
 }
+
+func TestConstant_Zero(t *testing.T) {
+	var zero m.Constant
+
+	if zero.Int().Sign() != 0 {
+		t.Errorf("Expected the zero Constant to hold 0, got: %v", zero.Int())
+	}
+	if data, err := json.Marshal(zero); err != nil || string(data) != "0" {
+		t.Errorf("Expected the zero Constant to marshal as 0, got: %s (%v)", data, err)
+	}
+	if n := m.EvalValue(m.Environment{}, m.State{}, m.AddValue{Add: zero, To: m.SetConstant("2")}); n.Int64() != 2 {
+		t.Errorf("Expected 0 + 2 = 2, got: %v", n)
+	}
+}
+
+func TestSetConstantChecked(t *testing.T) {
+	for _, s := range []string{"0", "-5", "+7", "123456789012345678901234567890"} {
+		c, err := m.SetConstantChecked(s)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", s, err)
+			continue
+		}
+		if expected, _ := new(big.Int).SetString(s, 10); c.Int().Cmp(expected) != 0 {
+			t.Errorf("Expected %v, got: %v", expected, c.Int())
+		}
+	}
+
+	for _, s := range []string{"", "12x", "0x10", "1e6", "1.5", " 1", "1_000"} {
+		if c, err := m.SetConstantChecked(s); err == nil {
+			t.Errorf("Expected an error for %q, got: %v", s, c.Int())
+		}
+	}
+}
+
+// Build a balanced tree of AddValue and MulValue over the given number of
+// Constant leaves.
+func arithmeticTree(leaves int) m.Value {
+	if leaves == 1 {
+		return m.SetConstant("123456789012345678901234567890")
+	}
+	left, right := arithmeticTree(leaves/2), arithmeticTree(leaves-leaves/2)
+	if leaves%2 == 0 {
+		return m.AddValue{Add: left, To: right}
+	}
+	return m.MulValue{Multiply: left, By: right}
+}
+
+func BenchmarkConstant_BuildArithmetic(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		arithmeticTree(1024)
+	}
+}
+
+func BenchmarkConstant_EvalArithmetic(b *testing.B) {
+	tree := arithmeticTree(1024)
+	env := m.Environment{}
+	state := m.State{BoundValues: map[m.ValueId]*big.Int{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.EvalValue(env, state, tree)
+	}
+}
+
+func BenchmarkConstant_MarshalArithmetic(b *testing.B) {
+	tree := arithmeticTree(1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(tree); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+// FreeVariables reports which elements of the State and Environment an
+// Observation depends on: the choices it reads (via ChoiceValue or
+// ChoseSomething), the bound values it reads (via UseValue), and whether it
+// refers to the transaction's TimeIntervalStart or TimeIntervalEnd. A UI can use
+// this to decide which inputs could change the truth of an observation and
+// when it needs to be re-evaluated. Identifiers are reported once each, in the
+// order they are first encountered.
+func FreeVariables(o Observation) (choices []ChoiceId, values []ValueId, usesTime bool) {
+	return ValueFreeVariables(o)
+}
+
+// ValueFreeVariables is the Value counterpart of FreeVariables.
+func ValueFreeVariables(v Value) (choices []ChoiceId, values []ValueId, usesTime bool) {
+	fv := &freeVariables{
+		seenChoices: make(map[ChoiceId]bool),
+		seenValues:  make(map[ValueId]bool),
+	}
+	fv.value(v)
+	return fv.choices, fv.values, fv.usesTime
+}
+
+type freeVariables struct {
+	choices     []ChoiceId
+	values      []ValueId
+	usesTime    bool
+	seenChoices map[ChoiceId]bool
+	seenValues  map[ValueId]bool
+}
+
+func (fv *freeVariables) choice(id ChoiceId) {
+	if !fv.seenChoices[id] {
+		fv.seenChoices[id] = true
+		fv.choices = append(fv.choices, id)
+	}
+}
+
+func (fv *freeVariables) value(v Value) {
+	switch v := v.(type) {
+	case ChoiceValue:
+		fv.choice(v.Value)
+	case ChoseSomething:
+		fv.choice(v.Choice)
+	case UseValue:
+		if !fv.seenValues[v.Value] {
+			fv.seenValues[v.Value] = true
+			fv.values = append(fv.values, v.Value)
+		}
+	case TimeIntervalValue:
+		fv.usesTime = true
+	case NegValue:
+		fv.value(v.Neg)
+	case AddValue:
+		fv.value(v.Add)
+		fv.value(v.To)
+	case SubValue:
+		fv.value(v.Subtract)
+		fv.value(v.From)
+	case MulValue:
+		fv.value(v.Multiply)
+		fv.value(v.By)
+	case DivValue:
+		fv.value(v.Divide)
+		fv.value(v.By)
+	case Cond:
+		fv.value(v.Observation)
+		fv.value(v.IfTrue)
+		fv.value(v.IfFalse)
+	case AndObs:
+		fv.value(v.Both)
+		fv.value(v.And)
+	case OrObs:
+		fv.value(v.Either)
+		fv.value(v.Or)
+	case NotObs:
+		fv.value(v.Not)
+	case ValueGE:
+		fv.value(v.Value)
+		fv.value(v.Ge)
+	case ValueGT:
+		fv.value(v.Value)
+		fv.value(v.Gt)
+	case ValueLT:
+		fv.value(v.Value)
+		fv.value(v.Lt)
+	case ValueLE:
+		fv.value(v.Value)
+		fv.value(v.Le)
+	case ValueEQ:
+		fv.value(v.Value)
+		fv.value(v.Eq)
+	}
+}
+
+// DanglingChoiceReferences reports the choices that are read by a ChoiceValue
+// or ChoseSomething before any Choice action on the path to the read offers
+// them. Such a read always sees the default of zero, or false, which usually
+// means the contract is missing a Choice. Choices are reported once each, in
+// the order they are first encountered.
+func DanglingChoiceReferences(c Contract) []ChoiceId {
+	var dangling []ChoiceId
+	seen := make(map[ChoiceId]bool)
+
+	// Report the choices read by the values that no offered choice covers.
+	reads := func(offered map[ChoiceId]bool, values ...Value) {
+		for _, v := range values {
+			choices, _, _ := ValueFreeVariables(v)
+			for _, id := range choices {
+				if !offered[id] && !seen[id] {
+					seen[id] = true
+					dangling = append(dangling, id)
+				}
+			}
+		}
+	}
+
+	var walk func(c Contract, offered map[ChoiceId]bool)
+	walk = func(c Contract, offered map[ChoiceId]bool) {
+		switch c := c.(type) {
+		case Pay:
+			reads(offered, c.Pay)
+			walk(c.Then, offered)
+		case If:
+			reads(offered, c.Observe)
+			walk(c.Then, offered)
+			walk(c.Else, offered)
+		case When:
+			for _, cs := range c.Cases {
+				next := offered
+				switch a := cs.Action.(type) {
+				case Deposit:
+					reads(offered, a.Deposits)
+				case Notify:
+					reads(offered, a.If)
+				case Choice:
+					if !offered[a.ChoiceId] {
+						next = make(map[ChoiceId]bool, len(offered)+1)
+						for id := range offered {
+							next[id] = true
+						}
+						next[a.ChoiceId] = true
+					}
+				}
+				walk(cs.Then, next)
+			}
+			walk(c.Then, offered)
+		case Let:
+			reads(offered, c.Value)
+			walk(c.Then, offered)
+		case Assert:
+			reads(offered, c.Observe)
+			walk(c.Then, offered)
+		}
+	}
+	walk(c, map[ChoiceId]bool{})
+
+	return dangling
+}
@@ -0,0 +1,94 @@
+package language_test
+
+import (
+	"testing"
+
+	m "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+func TestFreeVariables_Observation(t *testing.T) {
+	choiceId := m.ChoiceId{Name: "price", Owner: m.Role{Name: "oracle"}}
+	obs := m.AndObs{
+		Both: m.ValueGT{
+			Value: m.ChoiceValue{Value: choiceId},
+			Gt:    m.UseValue{Value: "threshold"},
+		},
+		And: m.ChoseSomething{Choice: choiceId},
+	}
+
+	choices, values, usesTime := m.FreeVariables(obs)
+
+	if len(choices) != 1 || choices[0] != choiceId {
+		t.Errorf("Expected choices [%v], got: %v", choiceId, choices)
+	}
+
+	if len(values) != 1 || values[0] != "threshold" {
+		t.Errorf("Expected values [threshold], got: %v", values)
+	}
+
+	if usesTime {
+		t.Error("Expected usesTime to be false")
+	}
+}
+
+func TestFreeVariables_Value(t *testing.T) {
+	val := m.AddValue{
+		Add: m.TimeIntervalStart,
+		To:  m.SetConstant("1000"),
+	}
+
+	choices, values, usesTime := m.ValueFreeVariables(val)
+
+	if len(choices) != 0 || len(values) != 0 {
+		t.Errorf("Expected no choices or values, got: %v, %v", choices, values)
+	}
+
+	if !usesTime {
+		t.Error("Expected usesTime to be true")
+	}
+}
+
+func TestDanglingChoiceReferences(t *testing.T) {
+	oracle := m.Role{Name: "oracle"}
+	price := m.ChoiceId{Name: "price", Owner: oracle}
+	rate := m.ChoiceId{Name: "rate", Owner: oracle}
+
+	// The price is offered before it is read, but the rate is never offered.
+	contract := m.When{
+		Cases: []m.Case{
+			{
+				Action: m.Choice{ChoiceId: price, Bounds: []m.Bound{{Lower: 0, Upper: 100}}},
+				Then: m.If{
+					Observe: m.ValueGT{Value: m.ChoiceValue{Value: price}, Gt: m.ChoiceValue{Value: rate}},
+					Then:    m.Close,
+					Else:    m.Close,
+				},
+			},
+		},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Assert{Observe: m.ChoseSomething{Choice: price}, Then: m.Close},
+	}
+
+	dangling := m.DanglingChoiceReferences(contract)
+	if len(dangling) != 2 || dangling[0] != rate || dangling[1] != price {
+		t.Errorf("Expected [%v %v], got: %v", rate, price, dangling)
+	}
+
+	if dangling := m.DanglingChoiceReferences(contract.Cases[0].Then); len(dangling) != 2 {
+		t.Errorf("Expected both reads to dangle without the Choice, got: %v", dangling)
+	}
+
+	clean := m.When{
+		Cases: []m.Case{
+			{
+				Action: m.Choice{ChoiceId: price, Bounds: []m.Bound{{Lower: 0, Upper: 100}}},
+				Then:   m.Assert{Observe: m.ChoseSomething{Choice: price}, Then: m.Close},
+			},
+		},
+		Timeout: m.POSIXTime(1666078977926),
+		Then:    m.Close,
+	}
+	if dangling := m.DanglingChoiceReferences(clean); len(dangling) != 0 {
+		t.Errorf("Expected an offered choice not to dangle, got: %v", dangling)
+	}
+}
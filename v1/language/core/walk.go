@@ -0,0 +1,131 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+// inspect traverses a contract depth-first, calling fn for every Contract,
+// Action and Value (including Observations) in the tree in source order.
+func inspect(c Contract, fn func(node any)) {
+	inspectNested(c, 1, func(node any, _ int) { fn(node) })
+}
+
+// inspectNested traverses the contract as inspect does, also passing fn the
+// nesting depth of each node: the depth of its parent plus one, where the
+// contract itself has the given depth. The Case between a When and the action
+// and continuation of each of its cases counts as a level of its own.
+func inspectNested(c Contract, depth int, fn func(node any, depth int)) {
+	fn(c, depth)
+
+	switch c := c.(type) {
+	case Pay:
+		inspectValueNested(c.Pay, depth+1, fn)
+		inspectNested(c.Then, depth+1, fn)
+	case If:
+		inspectValueNested(c.Observe, depth+1, fn)
+		inspectNested(c.Then, depth+1, fn)
+		inspectNested(c.Else, depth+1, fn)
+	case When:
+		for _, cs := range c.Cases {
+			inspectActionNested(cs.Action, depth+2, fn)
+			inspectNested(cs.Then, depth+2, fn)
+		}
+		inspectNested(c.Then, depth+1, fn)
+	case Let:
+		inspectValueNested(c.Value, depth+1, fn)
+		inspectNested(c.Then, depth+1, fn)
+	case Assert:
+		inspectValueNested(c.Observe, depth+1, fn)
+		inspectNested(c.Then, depth+1, fn)
+	}
+}
+
+func inspectAction(a Action, fn func(node any)) {
+	inspectActionNested(a, 1, func(node any, _ int) { fn(node) })
+}
+
+func inspectActionNested(a Action, depth int, fn func(node any, depth int)) {
+	fn(a, depth)
+
+	switch a := a.(type) {
+	case Deposit:
+		inspectValueNested(a.Deposits, depth+1, fn)
+	case Notify:
+		inspectValueNested(a.If, depth+1, fn)
+	}
+}
+
+func inspectValue(v Value, fn func(node any)) {
+	inspectValueNested(v, 1, func(node any, _ int) { fn(node) })
+}
+
+func inspectValueNested(v Value, depth int, fn func(node any, depth int)) {
+	fn(v, depth)
+
+	inner := func(vs ...Value) {
+		for _, v := range vs {
+			inspectValueNested(v, depth+1, fn)
+		}
+	}
+	switch v := v.(type) {
+	case NegValue:
+		inner(v.Neg)
+	case AddValue:
+		inner(v.Add, v.To)
+	case SubValue:
+		inner(v.Subtract, v.From)
+	case MulValue:
+		inner(v.Multiply, v.By)
+	case DivValue:
+		inner(v.Divide, v.By)
+	case Cond:
+		inner(v.Observation, v.IfTrue, v.IfFalse)
+	case AndObs:
+		inner(v.Both, v.And)
+	case OrObs:
+		inner(v.Either, v.Or)
+	case NotObs:
+		inner(v.Not)
+	case ValueGE:
+		inner(v.Value, v.Ge)
+	case ValueGT:
+		inner(v.Value, v.Gt)
+	case ValueLT:
+		inner(v.Value, v.Lt)
+	case ValueLE:
+		inner(v.Value, v.Le)
+	case ValueEQ:
+		inner(v.Value, v.Eq)
+	}
+}
+
+// nodeParties returns the parties referenced directly by a node visited by
+// inspect, in field order.
+func nodeParties(node any) []Party {
+	switch n := node.(type) {
+	case Pay:
+		return []Party{n.From, n.To.owner()}
+	case Deposit:
+		return []Party{n.IntoAccount, n.Party}
+	case Choice:
+		return []Party{n.ChoiceId.Owner}
+	case ChoiceValue:
+		return []Party{n.Value.Owner}
+	case ChoseSomething:
+		return []Party{n.Choice.Owner}
+	case AvailableMoney:
+		return []Party{n.Account}
+	}
+
+	return nil
+}
@@ -0,0 +1,385 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	core "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// An ExtendedContract is a Core contract tree that may still contain Extended
+// parameters (TimeParam, ConstantParam, etc.) in place of concrete timeouts
+// and values.
+type ExtendedContract = core.Contract
+
+// Bindings supplies the concrete values substituted for the parameters of an
+// ExtendedContract when it is instantiated.
+type Bindings struct {
+	// Start of the contract, against which any RelativeTimeout is resolved.
+	// It must be set for a contract holding a RelativeTimeout.
+	Start  *core.POSIXTime
+	Times  map[string]core.POSIXTime
+	Values map[string]core.Constant
+}
+
+// Instantiate lowers an Extended contract to Core Marlowe by substituting each
+// parameter with its binding. An error naming the parameter is returned for
+// the first parameter that has no binding.
+func Instantiate(c ExtendedContract, params Bindings) (core.Contract, error) {
+	return params.contract(c)
+}
+
+// InstantiateAndValidate instantiates the template and then checks that the
+// result is both pure Core Marlowe and free of structural errors, returning
+// the first failure encountered.
+func InstantiateAndValidate(c ExtendedContract, bindings Bindings) (core.Contract, error) {
+	contract, err := Instantiate(c, bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateCoreOnly(contract); err != nil {
+		return nil, err
+	}
+
+	report := core.Validate(contract, core.DefaultValidateOptions())
+	for _, f := range report.Findings {
+		if f.Severity == core.SeverityError {
+			return nil, errors.New(f.String())
+		}
+	}
+
+	return contract, nil
+}
+
+func (b Bindings) contract(c core.Contract) (core.Contract, error) {
+	var err error
+
+	switch c := c.(type) {
+	case core.Pay:
+		if c.Pay, err = b.value(c.Pay); err != nil {
+			return nil, err
+		}
+		c.Then, err = b.contract(c.Then)
+		return c, err
+	case core.If:
+		if c.Observe, err = b.observation(c.Observe); err != nil {
+			return nil, err
+		}
+		if c.Then, err = b.contract(c.Then); err != nil {
+			return nil, err
+		}
+		c.Else, err = b.contract(c.Else)
+		return c, err
+	case core.When:
+		cases := make([]core.Case, len(c.Cases))
+		for i, cs := range c.Cases {
+			cases[i] = cs
+			if cases[i].Action, err = b.action(cs.Action); err != nil {
+				return nil, err
+			}
+			if cs.MerkleizedThen != "" {
+				continue
+			}
+			if cases[i].Then, err = b.contract(cs.Then); err != nil {
+				return nil, err
+			}
+		}
+		c.Cases = cases
+		if c.Timeout, err = b.timeout(c.Timeout); err != nil {
+			return nil, err
+		}
+		c.Then, err = b.contract(c.Then)
+		return c, err
+	case core.Let:
+		if c.Value, err = b.value(c.Value); err != nil {
+			return nil, err
+		}
+		c.Then, err = b.contract(c.Then)
+		return c, err
+	case core.Assert:
+		if c.Observe, err = b.observation(c.Observe); err != nil {
+			return nil, err
+		}
+		c.Then, err = b.contract(c.Then)
+		return c, err
+	}
+
+	return c, nil
+}
+
+func (b Bindings) action(a core.Action) (core.Action, error) {
+	var err error
+
+	switch a := a.(type) {
+	case core.Deposit:
+		a.Deposits, err = b.value(a.Deposits)
+		return a, err
+	case core.Notify:
+		a.If, err = b.observation(a.If)
+		return a, err
+	case Choice:
+		bounds := make([]core.Bound, len(a.Bounds))
+		for i, bound := range a.Bounds {
+			if bounds[i].Lower, err = b.boundLimit(bound.Lower); err != nil {
+				return nil, err
+			}
+			if bounds[i].Upper, err = b.boundLimit(bound.Upper); err != nil {
+				return nil, err
+			}
+		}
+		return core.Choice{ChoiceId: a.ChoiceId, Bounds: bounds}, nil
+	}
+
+	return a, nil
+}
+
+func (b Bindings) boundLimit(l BoundLimit) (uint64, error) {
+	switch l := l.(type) {
+	case BoundConstant:
+		return uint64(l), nil
+	case BoundParam:
+		constant, ok := b.Values[string(l)]
+		if !ok {
+			return 0, fmt.Errorf("unbound bound parameter %q", string(l))
+		}
+		num := constant.Int()
+		if num.Sign() < 0 || !num.IsUint64() {
+			return 0, fmt.Errorf("bound parameter %q is out of range: %v", string(l), num.String())
+		}
+		return num.Uint64(), nil
+	}
+
+	return 0, fmt.Errorf("unsupported bound limit %v", l)
+}
+
+func (b Bindings) timeout(t core.Timeout) (core.Timeout, error) {
+	switch t := t.(type) {
+	case TimeParam:
+		time, err := t.ToCore(b)
+		if err != nil {
+			return nil, err
+		}
+		return time, nil
+	case TimeConstant:
+		return core.POSIXTime(t), nil
+	case RelativeTimeout:
+		if b.Start == nil {
+			return nil, fmt.Errorf("relative timeout %v needs the start of the contract to be bound", time.Duration(t))
+		}
+		return t.Resolve(*b.Start), nil
+	}
+
+	return t, nil
+}
+
+func (b Bindings) observation(o core.Observation) (core.Observation, error) {
+	v, err := b.value(o)
+	if err != nil {
+		return nil, err
+	}
+	return v.(core.Observation), nil
+}
+
+func (b Bindings) value(v core.Value) (core.Value, error) {
+	// Each binary node substitutes both of its operands in turn.
+	pair := func(x, y core.Value) (core.Value, core.Value, error) {
+		x, err := b.value(x)
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err = b.value(y)
+		return x, y, err
+	}
+	obsPair := func(x, y core.Observation) (core.Observation, core.Observation, error) {
+		x, err := b.observation(x)
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err = b.observation(y)
+		return x, y, err
+	}
+
+	var err error
+
+	switch v := v.(type) {
+	case ConstantParam:
+		constant, err := v.ToCore(b)
+		if err != nil {
+			return nil, err
+		}
+		return constant, nil
+	case core.NegValue:
+		v.Neg, err = b.value(v.Neg)
+		return v, err
+	case core.AddValue:
+		v.Add, v.To, err = pair(v.Add, v.To)
+		return v, err
+	case core.SubValue:
+		v.Subtract, v.From, err = pair(v.Subtract, v.From)
+		return v, err
+	case core.MulValue:
+		v.Multiply, v.By, err = pair(v.Multiply, v.By)
+		return v, err
+	case core.DivValue:
+		v.Divide, v.By, err = pair(v.Divide, v.By)
+		return v, err
+	case core.Cond:
+		if v.Observation, err = b.observation(v.Observation); err != nil {
+			return nil, err
+		}
+		v.IfTrue, v.IfFalse, err = pair(v.IfTrue, v.IfFalse)
+		return v, err
+	case core.AndObs:
+		v.Both, v.And, err = obsPair(v.Both, v.And)
+		return v, err
+	case core.OrObs:
+		v.Either, v.Or, err = obsPair(v.Either, v.Or)
+		return v, err
+	case core.NotObs:
+		v.Not, err = b.observation(v.Not)
+		return v, err
+	case core.ValueGE:
+		v.Value, v.Ge, err = pair(v.Value, v.Ge)
+		return v, err
+	case core.ValueGT:
+		v.Value, v.Gt, err = pair(v.Value, v.Gt)
+		return v, err
+	case core.ValueLT:
+		v.Value, v.Lt, err = pair(v.Value, v.Lt)
+		return v, err
+	case core.ValueLE:
+		v.Value, v.Le, err = pair(v.Value, v.Le)
+		return v, err
+	case core.ValueEQ:
+		v.Value, v.Eq, err = pair(v.Value, v.Eq)
+		return v, err
+	}
+
+	return v, nil
+}
+
+// ValidateCoreOnly returns an error if the contract still contains any
+// Extended construct, i.e. if it is not deployable as Core Marlowe.
+func ValidateCoreOnly(c core.Contract) error {
+	switch c := c.(type) {
+	case core.CloseContract:
+		return nil
+	case core.Pay:
+		if err := validateCoreValue(c.Pay); err != nil {
+			return err
+		}
+		return ValidateCoreOnly(c.Then)
+	case core.If:
+		if err := validateCoreValue(c.Observe); err != nil {
+			return err
+		}
+		if err := ValidateCoreOnly(c.Then); err != nil {
+			return err
+		}
+		return ValidateCoreOnly(c.Else)
+	case core.When:
+		for _, cs := range c.Cases {
+			var err error
+			switch a := cs.Action.(type) {
+			case core.Deposit:
+				err = validateCoreValue(a.Deposits)
+			case core.Notify:
+				err = validateCoreValue(a.If)
+			case core.Choice:
+			default:
+				err = fmt.Errorf("unsupported action %T", a)
+			}
+			if err != nil {
+				return err
+			}
+			if cs.MerkleizedThen != "" {
+				continue
+			}
+			if err := ValidateCoreOnly(cs.Then); err != nil {
+				return err
+			}
+		}
+		if _, ok := c.Timeout.(core.POSIXTime); !ok {
+			return fmt.Errorf("timeout %v is not a Core POSIXTime", c.Timeout)
+		}
+		return ValidateCoreOnly(c.Then)
+	case core.Let:
+		if err := validateCoreValue(c.Value); err != nil {
+			return err
+		}
+		return ValidateCoreOnly(c.Then)
+	case core.Assert:
+		if err := validateCoreValue(c.Observe); err != nil {
+			return err
+		}
+		return ValidateCoreOnly(c.Then)
+	}
+
+	return fmt.Errorf("unsupported contract %T", c)
+}
+
+func validateCoreValue(v core.Value) error {
+	var err error
+
+	switch v := v.(type) {
+	case core.Constant, core.AvailableMoney, core.ChoiceValue, core.UseValue,
+		core.TimeIntervalValue, core.ChoseSomething, core.BoolObs:
+	case core.NegValue:
+		err = validateCoreValue(v.Neg)
+	case core.AddValue:
+		err = validateCoreValues(v.Add, v.To)
+	case core.SubValue:
+		err = validateCoreValues(v.Subtract, v.From)
+	case core.MulValue:
+		err = validateCoreValues(v.Multiply, v.By)
+	case core.DivValue:
+		err = validateCoreValues(v.Divide, v.By)
+	case core.Cond:
+		err = validateCoreValues(v.Observation, v.IfTrue, v.IfFalse)
+	case core.AndObs:
+		err = validateCoreValues(v.Both, v.And)
+	case core.OrObs:
+		err = validateCoreValues(v.Either, v.Or)
+	case core.NotObs:
+		err = validateCoreValue(v.Not)
+	case core.ValueGE:
+		err = validateCoreValues(v.Value, v.Ge)
+	case core.ValueGT:
+		err = validateCoreValues(v.Value, v.Gt)
+	case core.ValueLT:
+		err = validateCoreValues(v.Value, v.Lt)
+	case core.ValueLE:
+		err = validateCoreValues(v.Value, v.Le)
+	case core.ValueEQ:
+		err = validateCoreValues(v.Value, v.Eq)
+	default:
+		err = fmt.Errorf("value %v is not a Core value", v)
+	}
+
+	return err
+}
+
+func validateCoreValues(vs ...core.Value) error {
+	for _, v := range vs {
+		if err := validateCoreValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
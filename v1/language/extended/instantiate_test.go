@@ -0,0 +1,272 @@
+package language_test
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/menabrealabs/marlowe/v1/language/core"
+	ext "github.com/menabrealabs/marlowe/v1/language/extended"
+)
+
+func setupEscrowTemplate(timeout c.Timeout) ext.ExtendedContract {
+	return c.When{
+		Cases: []c.Case{
+			{
+				Action: c.Deposit{
+					IntoAccount: c.Role{Name: "seller"},
+					Party:       c.Role{Name: "buyer"},
+					Token:       c.Ada,
+					Deposits:    ext.ConstantParam("price"),
+				},
+				Then: c.Close,
+			},
+		},
+		Timeout: timeout,
+		Then:    c.Close,
+	}
+}
+
+func TestInstantiate_RelativeTimeout(t *testing.T) {
+	start := c.POSIXTime(1666078977926)
+	template := setupEscrowTemplate(ext.RelativeTimeout(24 * time.Hour))
+
+	contract, err := ext.Instantiate(template, ext.Bindings{
+		Start:  &start,
+		Values: map[string]c.Constant{"price": c.SetConstant("50000000")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := contract.(c.When)
+	if when.Timeout != start+86_400_000 {
+		t.Errorf("Expected timeout %v, got: %v", start+86_400_000, when.Timeout)
+	}
+}
+
+func TestInstantiate_RelativeTimeoutWithoutStart(t *testing.T) {
+	template := setupEscrowTemplate(ext.RelativeTimeout(24 * time.Hour))
+
+	_, err := ext.Instantiate(template, ext.Bindings{
+		Values: map[string]c.Constant{"price": c.SetConstant("50000000")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "start") {
+		t.Errorf("Expected an error for the unbound start, got: %v", err)
+	}
+}
+
+func TestInstantiateAndValidate_MissingBinding(t *testing.T) {
+	template := setupEscrowTemplate(ext.TimeParam("deadline"))
+
+	_, err := ext.InstantiateAndValidate(template, ext.Bindings{
+		Values: map[string]c.Constant{"price": c.SetConstant("50000000")},
+	})
+	if err == nil {
+		t.Fatal("Expected an error for the unbound deadline parameter")
+	}
+	t.Log("Expected error: ", err)
+}
+
+func TestInstantiateAndValidate_CompleteBinding(t *testing.T) {
+	template := setupEscrowTemplate(ext.TimeParam("deadline"))
+
+	contract, err := ext.InstantiateAndValidate(template, ext.Bindings{
+		Times:  map[string]c.POSIXTime{"deadline": 1666078977926},
+		Values: map[string]c.Constant{"price": c.SetConstant("50000000")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ext.ValidateCoreOnly(contract); err != nil {
+		t.Errorf("Instantiated contract should be pure Core: %v", err)
+	}
+
+	if ext.ValidateCoreOnly(template) == nil {
+		t.Error("Template should not validate as pure Core")
+	}
+}
+
+func TestInstantiate_BoundParam(t *testing.T) {
+	choiceId := c.ChoiceId{Name: "rating", Owner: c.Role{Name: "reviewer"}}
+	template := c.When{
+		Cases: []c.Case{
+			{
+				Action: ext.Choice{
+					Choice: c.Choice{ChoiceId: choiceId},
+					Bounds: []ext.Bound{{Lower: ext.BoundParam("min"), Upper: ext.BoundParam("max")}},
+				},
+				Then: c.Close,
+			},
+		},
+		Timeout: c.POSIXTime(1666078977926),
+		Then:    c.Close,
+	}
+
+	contract, err := ext.Instantiate(template, ext.Bindings{
+		Values: map[string]c.Constant{"min": c.SetConstant("1"), "max": c.SetConstant("5")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	choice, ok := contract.(c.When).Cases[0].Action.(c.Choice)
+	if !ok {
+		t.Fatalf("Expected a core Choice, got: %T", contract.(c.When).Cases[0].Action)
+	}
+
+	if choice.ChoiceId != choiceId || len(choice.Bounds) != 1 || choice.Bounds[0] != (c.Bound{Lower: 1, Upper: 5}) {
+		t.Errorf("Unexpected instantiated choice: %v", choice)
+	}
+
+	if _, err := ext.Instantiate(template, ext.Bindings{}); err == nil {
+		t.Error("Expected an error for unbound bound parameters")
+	}
+}
+
+func TestMatchesTemplate_Escrow(t *testing.T) {
+	template := setupEscrowTemplate(ext.TimeParam("deadline"))
+	instance := setupEscrowTemplate(c.POSIXTime(1666078977926))
+	when := instance.(c.When)
+	deposit := when.Cases[0].Action.(c.Deposit)
+	deposit.Deposits = c.SetConstant("50000000")
+	when.Cases = []c.Case{{Action: deposit, Then: c.Close}}
+
+	bindings, ok := ext.MatchesTemplate(template, when)
+	if !ok {
+		t.Fatal("Expected the escrow instance to match its template")
+	}
+	if deadline := bindings.Times["deadline"]; deadline != 1666078977926 {
+		t.Errorf("Expected the deadline binding to be recovered, got: %v", bindings.Times)
+	}
+	if price := bindings.Values["price"].Int(); price.String() != "50000000" {
+		t.Errorf("Expected the price binding to be recovered, got: %v", bindings.Values)
+	}
+
+	contract, err := ext.Instantiate(template, bindings)
+	if err != nil || !c.EqualIgnoringTimeouts(contract, when) || contract.(c.When).Timeout != when.Timeout {
+		t.Errorf("Expected the recovered bindings to instantiate the instance, got: %v, %v", contract, err)
+	}
+}
+
+func TestMatchesTemplate_Mismatch(t *testing.T) {
+	template := setupEscrowTemplate(ext.TimeParam("deadline"))
+
+	// The deposit is into the buyer's account rather than the seller's.
+	instance := c.When{
+		Cases: []c.Case{{
+			Action: c.Deposit{
+				IntoAccount: c.Role{Name: "buyer"},
+				Party:       c.Role{Name: "buyer"},
+				Token:       c.Ada,
+				Deposits:    c.SetConstant("50000000"),
+			},
+			Then: c.Close,
+		}},
+		Timeout: c.POSIXTime(1666078977926),
+		Then:    c.Close,
+	}
+
+	if _, ok := ext.MatchesTemplate(template, instance); ok {
+		t.Error("Expected a structurally different contract not to match")
+	}
+}
+
+func TestMatchesTemplate_Constants(t *testing.T) {
+	// Equal constants that hold different big.Ints, or none.
+	template := c.Let{Name: "x", Value: c.AddValue{Add: c.NewConstant(big.NewInt(5)), To: c.Constant{}}, Then: c.Close}
+	instance := c.Let{Name: "x", Value: c.AddValue{Add: c.SetConstant("5"), To: c.SetConstant("0")}, Then: c.Close}
+	if _, ok := ext.MatchesTemplate(template, instance); !ok {
+		t.Error("Expected constants to match by value")
+	}
+
+	instance.Value = c.AddValue{Add: c.SetConstant("6"), To: c.SetConstant("0")}
+	if _, ok := ext.MatchesTemplate(template, instance); ok {
+		t.Error("Expected different constants not to match")
+	}
+
+	// A leaf does not match a Constant.
+	leaf := c.Let{Name: "x", Value: c.UseValue{Value: "y"}, Then: c.Close}
+	if _, ok := ext.MatchesTemplate(leaf, c.Let{Name: "x", Value: c.SetConstant("0"), Then: c.Close}); ok {
+		t.Error("Expected a UseValue not to match a Constant")
+	}
+}
+
+func TestParameters(t *testing.T) {
+	template := c.When{
+		Cases: []c.Case{
+			{
+				Action: c.Deposit{
+					IntoAccount: c.Role{Name: "seller"},
+					Party:       c.Role{Name: "buyer"},
+					Token:       c.Ada,
+					Deposits:    c.AddValue{Add: ext.ConstantParam("price"), To: ext.ConstantParam("fee")},
+				},
+				Then: c.When{
+					Cases: []c.Case{
+						{
+							Action: ext.Choice{
+								Choice: c.Choice{ChoiceId: c.ChoiceId{Name: "rating", Owner: c.Role{Name: "buyer"}}},
+								Bounds: []ext.Bound{{Lower: ext.BoundConstant(0), Upper: ext.BoundParam("max")}},
+							},
+							Then: c.Pay{
+								From:  c.Role{Name: "seller"},
+								To:    c.Payee{Party: c.Role{Name: "buyer"}},
+								Token: c.Ada,
+								Pay:   ext.ConstantParam("price"),
+								Then:  c.Close,
+							},
+						},
+					},
+					Timeout: ext.TimeParam("review"),
+					Then:    c.Close,
+				},
+			},
+		},
+		Timeout: ext.TimeParam("payment"),
+		Then: c.When{
+			Cases:   []c.Case{{Action: c.Notify{If: c.ValueGT{Value: ext.ConstantParam("fee"), Gt: c.SetConstant("0")}}, Then: c.Close}},
+			Timeout: ext.TimeParam("review"),
+			Then:    c.Close,
+		},
+	}
+
+	expectedTimes, expectedValues := []string{"review", "payment"}, []string{"price", "fee", "max"}
+	for i := 0; i < 10; i++ {
+		times, values := ext.Parameters(template)
+		if !reflect.DeepEqual(times, expectedTimes) || !reflect.DeepEqual(values, expectedValues) {
+			t.Fatalf("Expected %v and %v, got: %v and %v", expectedTimes, expectedValues, times, values)
+		}
+	}
+
+	if times, values := ext.Parameters(c.Close); len(times) != 0 || len(values) != 0 {
+		t.Errorf("Expected no parameters, got: %v and %v", times, values)
+	}
+}
+
+func TestComputeTransaction_Uninstantiated(t *testing.T) {
+	interval := c.NewTimeInterval(0, 10)
+
+	// The timeout of the When cannot be compared with the interval.
+	template := setupEscrowTemplate(ext.TimeParam("deadline"))
+	out := c.ComputeTransaction(c.TransactionInput{Interval: interval}, c.State{}, template)
+	if out.Error != (c.TEUnsupportedNode{Node: "language.TimeParam"}) {
+		t.Errorf("Expected a TEUnsupportedNode for the timeout, got: %#v", out.Error)
+	}
+
+	// The amount of the deposit cannot be evaluated.
+	template = setupEscrowTemplate(c.POSIXTime(100))
+	deposit := c.NewIDeposit(c.Role{Name: "seller"}, c.Role{Name: "buyer"}, c.Ada, big.NewInt(1))
+	out = c.ComputeTransaction(c.TransactionInput{Interval: interval, Inputs: []c.Input{deposit}}, c.State{}, template)
+	if out.Error != (c.TEUnsupportedNode{Node: "language.ConstantParam"}) {
+		t.Errorf("Expected a TEUnsupportedNode for the deposit, got: %#v", out.Error)
+	}
+
+	// Evaluating the parameter on its own gives zero.
+	if n := c.EvalValue(c.Environment{TimeInterval: interval}, c.State{}, ext.ConstantParam("price")); n.Sign() != 0 {
+		t.Errorf("Expected zero, got: %v", n)
+	}
+}
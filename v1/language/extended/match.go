@@ -0,0 +1,229 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import (
+	"math/big"
+	"time"
+
+	core "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// MatchesTemplate unifies a candidate Core contract against a template,
+// reporting whether the candidate is an instance of the template and, if so,
+// the bindings that instantiate the template into it. Every part of the
+// template other than its parameters must match the candidate exactly, and a
+// parameter used more than once must be bound to the same value each time.
+func MatchesTemplate(template ExtendedContract, candidate core.Contract) (Bindings, bool) {
+	m := matcher{bindings: Bindings{
+		Times:  map[string]core.POSIXTime{},
+		Values: map[string]core.Constant{},
+	}}
+
+	if !m.contract(template, candidate) {
+		return Bindings{}, false
+	}
+	return m.bindings, true
+}
+
+// matcher accumulates the bindings inferred while walking a template and a
+// candidate in lockstep.
+type matcher struct {
+	bindings Bindings
+}
+
+func (m *matcher) contract(t, c core.Contract) bool {
+	switch t := t.(type) {
+	case core.CloseContract:
+		_, ok := c.(core.CloseContract)
+		return ok
+	case core.Pay:
+		c, ok := c.(core.Pay)
+		return ok && t.From == c.From && t.To == c.To && t.Token == c.Token &&
+			m.value(t.Pay, c.Pay) && m.contract(t.Then, c.Then)
+	case core.If:
+		c, ok := c.(core.If)
+		return ok && m.value(t.Observe, c.Observe) && m.contract(t.Then, c.Then) && m.contract(t.Else, c.Else)
+	case core.When:
+		c, ok := c.(core.When)
+		if !ok || len(t.Cases) != len(c.Cases) || !m.timeout(t.Timeout, c.Timeout) {
+			return false
+		}
+		for i := range t.Cases {
+			tc, cc := t.Cases[i], c.Cases[i]
+			if !m.action(tc.Action, cc.Action) || tc.MerkleizedThen != cc.MerkleizedThen {
+				return false
+			}
+			if tc.MerkleizedThen == "" && !m.contract(tc.Then, cc.Then) {
+				return false
+			}
+		}
+		return m.contract(t.Then, c.Then)
+	case core.Let:
+		c, ok := c.(core.Let)
+		return ok && t.Name == c.Name && m.value(t.Value, c.Value) && m.contract(t.Then, c.Then)
+	case core.Assert:
+		c, ok := c.(core.Assert)
+		return ok && m.value(t.Observe, c.Observe) && m.contract(t.Then, c.Then)
+	}
+
+	return false
+}
+
+func (m *matcher) action(t, c core.Action) bool {
+	switch t := t.(type) {
+	case core.Deposit:
+		c, ok := c.(core.Deposit)
+		return ok && t.IntoAccount == c.IntoAccount && t.Party == c.Party && t.Token == c.Token &&
+			m.value(t.Deposits, c.Deposits)
+	case core.Notify:
+		c, ok := c.(core.Notify)
+		return ok && m.value(t.If, c.If)
+	case core.Choice:
+		bounds := make([]Bound, len(t.Bounds))
+		for i, b := range t.Bounds {
+			bounds[i] = Bound{Lower: BoundConstant(b.Lower), Upper: BoundConstant(b.Upper)}
+		}
+		return m.action(Choice{Choice: t, Bounds: bounds}, c)
+	case Choice:
+		c, ok := c.(core.Choice)
+		if !ok || t.ChoiceId != c.ChoiceId || len(t.Bounds) != len(c.Bounds) {
+			return false
+		}
+		for i, b := range t.Bounds {
+			if !m.boundLimit(b.Lower, c.Bounds[i].Lower) || !m.boundLimit(b.Upper, c.Bounds[i].Upper) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (m *matcher) boundLimit(t BoundLimit, c uint64) bool {
+	switch t := t.(type) {
+	case BoundConstant:
+		return uint64(t) == c
+	case BoundParam:
+		return m.bindValue(string(t), core.NewConstant(new(big.Int).SetUint64(c)))
+	}
+	return false
+}
+
+func (m *matcher) timeout(t, c core.Timeout) bool {
+	at, ok := c.(core.POSIXTime)
+	if !ok {
+		return false
+	}
+
+	switch t := t.(type) {
+	case core.POSIXTime:
+		return t == at
+	case TimeConstant:
+		return core.POSIXTime(t) == at
+	case TimeParam:
+		bound, ok := m.bindings.Times[string(t)]
+		if ok {
+			return bound == at
+		}
+		m.bindings.Times[string(t)] = at
+		return true
+	case RelativeTimeout:
+		start := at - core.POSIXTime(time.Duration(t).Milliseconds())
+		if m.bindings.Start != nil {
+			return *m.bindings.Start == start
+		}
+		m.bindings.Start = &start
+		return true
+	}
+
+	return false
+}
+
+func (m *matcher) bindValue(name string, c core.Constant) bool {
+	if bound, ok := m.bindings.Values[name]; ok {
+		return constantsEqual(bound, c)
+	}
+	m.bindings.Values[name] = c
+	return true
+}
+
+// Compare the Constants by value, since equal Constants may hold different
+// big.Ints, and the zero Constant holds none.
+func constantsEqual(a, b core.Constant) bool {
+	return a.Int().Cmp(b.Int()) == 0
+}
+
+func (m *matcher) value(t, c core.Value) bool {
+	switch t := t.(type) {
+	case ConstantParam:
+		c, ok := c.(core.Constant)
+		return ok && m.bindValue(string(t), c)
+	case core.Constant:
+		c, ok := c.(core.Constant)
+		return ok && constantsEqual(t, c)
+	case core.NegValue:
+		c, ok := c.(core.NegValue)
+		return ok && m.value(t.Neg, c.Neg)
+	case core.AddValue:
+		c, ok := c.(core.AddValue)
+		return ok && m.value(t.Add, c.Add) && m.value(t.To, c.To)
+	case core.SubValue:
+		c, ok := c.(core.SubValue)
+		return ok && m.value(t.Subtract, c.Subtract) && m.value(t.From, c.From)
+	case core.MulValue:
+		c, ok := c.(core.MulValue)
+		return ok && m.value(t.Multiply, c.Multiply) && m.value(t.By, c.By)
+	case core.DivValue:
+		c, ok := c.(core.DivValue)
+		return ok && m.value(t.Divide, c.Divide) && m.value(t.By, c.By)
+	case core.Cond:
+		c, ok := c.(core.Cond)
+		return ok && m.value(t.Observation, c.Observation) && m.value(t.IfTrue, c.IfTrue) && m.value(t.IfFalse, c.IfFalse)
+	case core.AndObs:
+		c, ok := c.(core.AndObs)
+		return ok && m.value(t.Both, c.Both) && m.value(t.And, c.And)
+	case core.OrObs:
+		c, ok := c.(core.OrObs)
+		return ok && m.value(t.Either, c.Either) && m.value(t.Or, c.Or)
+	case core.NotObs:
+		c, ok := c.(core.NotObs)
+		return ok && m.value(t.Not, c.Not)
+	case core.ValueGE:
+		c, ok := c.(core.ValueGE)
+		return ok && m.value(t.Value, c.Value) && m.value(t.Ge, c.Ge)
+	case core.ValueGT:
+		c, ok := c.(core.ValueGT)
+		return ok && m.value(t.Value, c.Value) && m.value(t.Gt, c.Gt)
+	case core.ValueLT:
+		c, ok := c.(core.ValueLT)
+		return ok && m.value(t.Value, c.Value) && m.value(t.Lt, c.Lt)
+	case core.ValueLE:
+		c, ok := c.(core.ValueLE)
+		return ok && m.value(t.Value, c.Value) && m.value(t.Le, c.Le)
+	case core.ValueEQ:
+		c, ok := c.(core.ValueEQ)
+		return ok && m.value(t.Value, c.Value) && m.value(t.Eq, c.Eq)
+	case core.AvailableMoney, core.ChoiceValue, core.UseValue, core.TimeIntervalValue,
+		core.ChoseSomething, core.BoolObs:
+		// These leaves hold no parameters, and == compares them by value.
+		// It would compare a Constant by the pointer to its big.Int, which
+		// is why Constants are matched with constantsEqual instead.
+		return t == c
+	}
+
+	return false
+}
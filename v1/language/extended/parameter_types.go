@@ -18,6 +18,10 @@
 package language
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
 	core "github.com/menabrealabs/marlowe/v1/language/core"
 )
 
@@ -29,8 +33,90 @@ type TimeParam string
 func (t TimeConstant) IsTimeout() {}
 func (t TimeParam) IsTimeout()    {}
 
-func (t TimeParam) ToCore(key string) {}
+// ToCore resolves the time parameter to the POSIXTime bound to its name, or
+// returns an error naming the parameter if it is unbound.
+func (t TimeParam) ToCore(params Bindings) (core.POSIXTime, error) {
+	time, ok := params.Times[string(t)]
+	if !ok {
+		return 0, fmt.Errorf("unbound time parameter %q", string(t))
+	}
+	return time, nil
+}
+
+// Marshal the timeout parameter as Marlowe Extended does, e.g.
+// {"time_param":"deadline"}.
+func (t TimeParam) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name string `json:"time_param"`
+	}{string(t)})
+}
+
+// RelativeTimeout expresses a timeout as a duration after the start of the
+// contract, e.g. "deadline = start + 1 day", rather than as an absolute time.
+type RelativeTimeout time.Duration
+
+func (t RelativeTimeout) IsTimeout() {}
+
+// Resolve the relative timeout against the contract start time, returning the
+// absolute POSIXTime (in milliseconds) that the timeout falls on.
+func (t RelativeTimeout) Resolve(start core.POSIXTime) core.POSIXTime {
+	return start + core.POSIXTime(time.Duration(t).Milliseconds())
+}
+
+// Marshal the relative timeout as its duration in milliseconds, e.g.
+// {"relative_timeout":86400000}, so that it is not mistaken for a POSIXTime.
+func (t RelativeTimeout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Milliseconds int64 `json:"relative_timeout"`
+	}{time.Duration(t).Milliseconds()})
+}
 
 type ConstantParam string
 
 func (c ConstantParam) IsValue() {}
+
+// ToCore resolves the constant parameter to the Constant bound to its name, or
+// returns an error naming the parameter if it is unbound.
+func (c ConstantParam) ToCore(params Bindings) (core.Constant, error) {
+	constant, ok := params.Values[string(c)]
+	if !ok {
+		return core.Constant{}, fmt.Errorf("unbound constant parameter %q", string(c))
+	}
+	return constant, nil
+}
+
+// Marshal the constant parameter as Marlowe Extended does, e.g.
+// {"constant_param":"price"}.
+func (c ConstantParam) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name string `json:"constant_param"`
+	}{string(c)})
+}
+
+// A BoundLimit is either end of a parameterized choice Bound.
+type BoundLimit interface{ isBoundLimit() }
+
+// BoundConstant is a concrete end of a Bound.
+type BoundConstant uint64
+
+// BoundParam is a template parameter standing in for an end of a Bound,
+// e.g. "rating between min and max". It is bound through Bindings.Values.
+type BoundParam string
+
+func (b BoundConstant) isBoundLimit() {}
+func (b BoundParam) isBoundLimit()    {}
+
+// Bound is the Extended counterpart of core.Bound whose ends may be
+// parameters.
+type Bound struct {
+	Lower BoundLimit `json:"from"`
+	Upper BoundLimit `json:"to"`
+}
+
+// Choice is a Choice action whose bounds may be parameterized. The embedded
+// core.Choice supplies the ChoiceId; its Bounds are replaced by those of the
+// Extended Choice when the contract is instantiated.
+type Choice struct {
+	core.Choice
+	Bounds []Bound `json:"choose_between"`
+}
@@ -1,7 +1,9 @@
 package language_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	assert "github.com/menabrealabs/marlowe/assertion"
 	c "github.com/menabrealabs/marlowe/v1/language/core"
@@ -18,7 +20,7 @@ func TestTypes_WhenContract(t *testing.T) {
 				Action: c.Choice{
 					ChoiceId: c.ChoiceId{
 						Name:  "option",
-						Owner: c.Role{"creditor"},
+						Owner: c.Role{Name: "creditor"},
 					},
 					Bounds: []c.Bound{
 						{
@@ -34,5 +36,98 @@ func TestTypes_WhenContract(t *testing.T) {
 		Then:    c.Close,
 	}
 
-	assert.Json(t, contract, `{"when":[{"case":{"for_choice":{"choice_name":"option","choice_owner":{"role_token":"creditor"}},"choose_between":[{"from":3,"to":2}]},"then":"close"}],"timeout":1668250824063,"timeout_continuation":"close"}`)
+	assert.Json(t, contract, `{"when":[{"case":{"for_choice":{"choice_name":"option","choice_owner":{"role_token":"creditor"}},"choose_between":[{"from":2,"to":3}]},"then":"close"}],"timeout":{"time_param":"deadline"},"timeout_continuation":"close"}`)
+}
+
+func TestTypes_WhenContract_TimeConstant(t *testing.T) {
+	contract := c.When{
+		Timeout: ext.TimeConstant(1668250824063),
+		Then:    c.Close,
+	}
+
+	assert.Json(t, contract, `{"when":[],"timeout":1668250824063,"timeout_continuation":"close"}`)
+}
+
+func TestRelativeTimeout_Resolve(t *testing.T) {
+	start := c.POSIXTime(1666078977926)
+	deadline := ext.RelativeTimeout(24 * time.Hour)
+
+	resolved := deadline.Resolve(start)
+	if resolved != start+86_400_000 {
+		t.Errorf("Expected %v, got: %v", start+86_400_000, resolved)
+	}
+}
+
+func TestParams_ToCore(t *testing.T) {
+	bindings := ext.Bindings{
+		Times:  map[string]c.POSIXTime{"deadline": 1666078977926},
+		Values: map[string]c.Constant{"price": c.SetConstant("50000000")},
+	}
+
+	if time, err := ext.TimeParam("deadline").ToCore(bindings); err != nil || time != 1666078977926 {
+		t.Errorf("Expected the bound deadline, got: %v %v", time, err)
+	}
+	if _, err := ext.TimeParam("payday").ToCore(bindings); err == nil || !strings.Contains(err.Error(), `"payday"`) {
+		t.Errorf("Expected an error naming the parameter, got: %v", err)
+	}
+
+	if price, err := ext.ConstantParam("price").ToCore(bindings); err != nil || !c.EqualValues(price, c.SetConstant("50000000")) {
+		t.Errorf("Expected the bound price, got: %v %v", price, err)
+	}
+	if _, err := ext.ConstantParam("fee").ToCore(bindings); err == nil || !strings.Contains(err.Error(), `"fee"`) {
+		t.Errorf("Expected an error naming the parameter, got: %v", err)
+	}
+}
+
+func TestEqualIgnoringTimeouts_TemplateInstance(t *testing.T) {
+	setup := func(timeout c.Timeout) c.Contract {
+		return c.When{
+			Cases: []c.Case{
+				{
+					Action: c.Deposit{
+						IntoAccount: c.Role{Name: "seller"},
+						Party:       c.Role{Name: "buyer"},
+						Token:       c.Ada,
+						Deposits:    c.SetConstant("50000000"),
+					},
+					Then: c.Close,
+				},
+			},
+			Timeout: timeout,
+			Then:    c.Close,
+		}
+	}
+
+	template := setup(ext.TimeParam("deadline"))
+	instance := setup(c.POSIXTime(1666078977926))
+
+	if !c.EqualIgnoringTimeouts(template, instance) {
+		t.Error("Template and its instantiation should be structurally equal")
+	}
+}
+
+func TestTypes_ParameterJSON(t *testing.T) {
+	assert.Json(t, c.Let{Name: "price", Value: ext.ConstantParam("price"), Then: c.Close}, `{"let":"price","be":{"constant_param":"price"},"then":"close"}`)
+	assert.Json(t, c.When{Timeout: ext.RelativeTimeout(24 * time.Hour), Then: c.Close}, `{"when":[],"timeout":{"relative_timeout":86400000},"timeout_continuation":"close"}`)
+}
+
+func TestFingerprint_Template(t *testing.T) {
+	template := setupEscrowTemplate(ext.TimeParam("deadline"))
+	if c.FingerprintHex(template) != c.FingerprintHex(setupEscrowTemplate(ext.TimeParam("deadline"))) {
+		t.Error("Expected equal templates to have equal fingerprints")
+	}
+
+	different := []c.Contract{
+		setupEscrowTemplate(ext.TimeParam("payday")),
+		setupEscrowTemplate(ext.RelativeTimeout(24 * time.Hour)),
+		setupEscrowTemplate(c.POSIXTime(86_400_000)),
+	}
+	seen := map[string]bool{c.FingerprintHex(template): true}
+	for _, contract := range different {
+		hash := c.FingerprintHex(contract)
+		if seen[hash] {
+			t.Errorf("Expected a distinct fingerprint for %v", contract)
+		}
+		seen[hash] = true
+	}
 }
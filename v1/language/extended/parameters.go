@@ -0,0 +1,143 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package language
+
+import core "github.com/menabrealabs/marlowe/v1/language/core"
+
+// Parameters returns the names of the distinct parameters of the template, in
+// the order they are first seen walking the contract depth first: the time
+// parameters standing in for When timeouts, and the value parameters, which
+// are the ConstantParams and the BoundParams of parameterized choice bounds,
+// since both are bound through Bindings.Values.
+func Parameters(c ExtendedContract) (timeParams []string, valueParams []string) {
+	p := collector{seen: make(map[string]bool)}
+	p.contract(c)
+	return p.times, p.values
+}
+
+// collector accumulates parameter names, keyed in seen by kind and name so
+// that a time and a value parameter may share a name.
+type collector struct {
+	seen   map[string]bool
+	times  []string
+	values []string
+}
+
+func (p *collector) time(name string) {
+	if !p.seen["time:"+name] {
+		p.seen["time:"+name] = true
+		p.times = append(p.times, name)
+	}
+}
+
+func (p *collector) value(name string) {
+	if !p.seen["value:"+name] {
+		p.seen["value:"+name] = true
+		p.values = append(p.values, name)
+	}
+}
+
+func (p *collector) contract(c core.Contract) {
+	switch c := c.(type) {
+	case core.Pay:
+		p.expression(c.Pay)
+		p.contract(c.Then)
+	case core.If:
+		p.expression(c.Observe)
+		p.contract(c.Then)
+		p.contract(c.Else)
+	case core.When:
+		for _, cs := range c.Cases {
+			p.action(cs.Action)
+			p.contract(cs.Then)
+		}
+		if t, ok := c.Timeout.(TimeParam); ok {
+			p.time(string(t))
+		}
+		p.contract(c.Then)
+	case core.Let:
+		p.expression(c.Value)
+		p.contract(c.Then)
+	case core.Assert:
+		p.expression(c.Observe)
+		p.contract(c.Then)
+	}
+}
+
+func (p *collector) action(a core.Action) {
+	switch a := a.(type) {
+	case core.Deposit:
+		p.expression(a.Deposits)
+	case core.Notify:
+		p.expression(a.If)
+	case Choice:
+		for _, bound := range a.Bounds {
+			for _, limit := range []BoundLimit{bound.Lower, bound.Upper} {
+				if name, ok := limit.(BoundParam); ok {
+					p.value(string(name))
+				}
+			}
+		}
+	}
+}
+
+// Collect the parameters of a value or observation.
+func (p *collector) expression(v core.Value) {
+	switch v := v.(type) {
+	case ConstantParam:
+		p.value(string(v))
+	case core.NegValue:
+		p.expression(v.Neg)
+	case core.AddValue:
+		p.expression(v.Add)
+		p.expression(v.To)
+	case core.SubValue:
+		p.expression(v.Subtract)
+		p.expression(v.From)
+	case core.MulValue:
+		p.expression(v.Multiply)
+		p.expression(v.By)
+	case core.DivValue:
+		p.expression(v.Divide)
+		p.expression(v.By)
+	case core.Cond:
+		p.expression(v.Observation)
+		p.expression(v.IfTrue)
+		p.expression(v.IfFalse)
+	case core.AndObs:
+		p.expression(v.Both)
+		p.expression(v.And)
+	case core.OrObs:
+		p.expression(v.Either)
+		p.expression(v.Or)
+	case core.NotObs:
+		p.expression(v.Not)
+	case core.ValueGE:
+		p.expression(v.Value)
+		p.expression(v.Ge)
+	case core.ValueGT:
+		p.expression(v.Value)
+		p.expression(v.Gt)
+	case core.ValueLT:
+		p.expression(v.Value)
+		p.expression(v.Lt)
+	case core.ValueLE:
+		p.expression(v.Value)
+		p.expression(v.Le)
+	case core.ValueEQ:
+		p.expression(v.Value)
+		p.expression(v.Eq)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package runtime is a client for the REST API of Marlowe Runtime, which
+// builds the transactions that create contracts and apply inputs to them.
+// The transactions are returned unsigned, for the caller to sign and submit.
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	core "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+// A Client talks to a Marlowe Runtime web server at BaseURL, e.g.
+// "http://localhost:3780".
+type Client struct {
+	BaseURL string
+	// HTTPClient sends the requests; http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the Runtime web server at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL}
+}
+
+// Wallet identifies the wallet that funds and balances a transaction.
+type Wallet struct {
+	// ChangeAddress receives the change of the transaction.
+	ChangeAddress core.Address
+	// Addresses are further addresses of the wallet whose UTxOs may be spent.
+	Addresses []core.Address
+	// Collateral are the UTxOs, as "txid#index", that may be used as collateral.
+	Collateral []string
+}
+
+// CreateRequest describes a contract to create.
+type CreateRequest struct {
+	Wallet
+	Contract core.Contract
+	// Roles is either the policy id of existing role tokens, or the Runtime's
+	// configuration of the role tokens to mint. It is omitted when nil, for
+	// contracts without roles.
+	Roles any
+	// MinUTxODeposit is the lovelace deposited with the contract to satisfy the
+	// minimum UTxO rule; the Runtime computes it when zero.
+	MinUTxODeposit uint64
+	Metadata       map[string]any
+	Tags           map[string]any
+}
+
+// ContractCreated is the Runtime's response to CreateRequest.
+type ContractCreated struct {
+	// ContractId is the id the contract will have, as "txid#index".
+	ContractId string
+	// TxBody is the CBOR of the unsigned transaction, hex encoded.
+	TxBody string
+}
+
+// ApplyInputsRequest describes inputs to apply to an existing contract.
+type ApplyInputsRequest struct {
+	Wallet
+	ContractId string
+	Inputs     []core.Input
+	// InvalidBefore and InvalidHereafter bound the validity interval of the
+	// transaction; the Runtime chooses them when zero.
+	InvalidBefore    time.Time
+	InvalidHereafter time.Time
+	Metadata         map[string]any
+	Tags             map[string]any
+}
+
+// InputsApplied is the Runtime's response to ApplyInputsRequest.
+type InputsApplied struct {
+	ContractId    string
+	TransactionId string
+	// TxBody is the CBOR of the unsigned transaction, hex encoded.
+	TxBody string
+}
+
+// An Error is a request the Runtime refused, carrying the status code and
+// the message and error code from the body of the response.
+type Error struct {
+	StatusCode int             `json:"-"`
+	Message    string          `json:"message"`
+	ErrorCode  string          `json:"errorCode"`
+	Details    json.RawMessage `json:"details"`
+}
+
+func (e *Error) Error() string {
+	if e.ErrorCode != "" {
+		return fmt.Sprintf("runtime: %d %v: %v", e.StatusCode, e.ErrorCode, e.Message)
+	}
+	return fmt.Sprintf("runtime: %d: %v", e.StatusCode, e.Message)
+}
+
+// The JSON envelope of an unsigned transaction.
+type textEnvelope struct {
+	CborHex     string `json:"cborHex"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+}
+
+// CreateContract posts the contract to the /contracts endpoint, which builds
+// the transaction that creates it.
+func (c *Client) CreateContract(ctx context.Context, req CreateRequest) (ContractCreated, error) {
+	body := struct {
+		Version        string         `json:"version"`
+		Contract       core.Contract  `json:"contract"`
+		Roles          any            `json:"roles,omitempty"`
+		MinUTxODeposit uint64         `json:"minUTxODeposit,omitempty"`
+		Metadata       map[string]any `json:"metadata"`
+		Tags           map[string]any `json:"tags"`
+	}{"v1", req.Contract, req.Roles, req.MinUTxODeposit, orEmpty(req.Metadata), orEmpty(req.Tags)}
+
+	var resp struct {
+		Resource struct {
+			ContractId string       `json:"contractId"`
+			TxBody     textEnvelope `json:"txBody"`
+		} `json:"resource"`
+	}
+	if err := c.post(ctx, "/contracts", req.Wallet, body, &resp); err != nil {
+		return ContractCreated{}, err
+	}
+
+	return ContractCreated{ContractId: resp.Resource.ContractId, TxBody: resp.Resource.TxBody.CborHex}, nil
+}
+
+// ApplyInputs posts the inputs to the transactions endpoint of the contract,
+// which builds the transaction that applies them.
+func (c *Client) ApplyInputs(ctx context.Context, req ApplyInputsRequest) (InputsApplied, error) {
+	// The Runtime requires the inputs, even when there are none.
+	inputs := req.Inputs
+	if inputs == nil {
+		inputs = []core.Input{}
+	}
+
+	body := struct {
+		Version          string         `json:"version"`
+		Inputs           []core.Input   `json:"inputs"`
+		InvalidBefore    string         `json:"invalidBefore,omitempty"`
+		InvalidHereafter string         `json:"invalidHereafter,omitempty"`
+		Metadata         map[string]any `json:"metadata"`
+		Tags             map[string]any `json:"tags"`
+	}{"v1", inputs, timestamp(req.InvalidBefore), timestamp(req.InvalidHereafter), orEmpty(req.Metadata), orEmpty(req.Tags)}
+
+	var resp struct {
+		Resource struct {
+			ContractId    string       `json:"contractId"`
+			TransactionId string       `json:"transactionId"`
+			TxBody        textEnvelope `json:"txBody"`
+		} `json:"resource"`
+	}
+	path := "/contracts/" + url.PathEscape(req.ContractId) + "/transactions"
+	if err := c.post(ctx, path, req.Wallet, body, &resp); err != nil {
+		return InputsApplied{}, err
+	}
+
+	return InputsApplied{
+		ContractId:    resp.Resource.ContractId,
+		TransactionId: resp.Resource.TransactionId,
+		TxBody:        resp.Resource.TxBody.CborHex,
+	}, nil
+}
+
+// Post the body as JSON with the wallet in the headers the Runtime expects,
+// decoding a successful response into out.
+func (c *Client) post(ctx context.Context, path string, wallet Wallet, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.BaseURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Change-Address", string(wallet.ChangeAddress))
+	if len(wallet.Addresses) > 0 {
+		addresses := make([]string, len(wallet.Addresses))
+		for i, a := range wallet.Addresses {
+			addresses[i] = string(a)
+		}
+		req.Header.Set("X-Address", strings.Join(addresses, ","))
+	}
+	if len(wallet.Collateral) > 0 {
+		req.Header.Set("X-Collateral-UTxO", strings.Join(wallet.Collateral, ","))
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(data))
+		}
+		return e
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// Format the time as the RFC 3339 UTC timestamp the Runtime expects, or as
+// the empty string for the zero time so that the field is omitted.
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// The Runtime requires metadata and tags, even when empty.
+func orEmpty(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
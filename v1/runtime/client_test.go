@@ -0,0 +1,117 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	assert "github.com/menabrealabs/marlowe/assertion"
+	core "github.com/menabrealabs/marlowe/v1/language/core"
+	"github.com/menabrealabs/marlowe/v1/runtime"
+)
+
+const changeAddress = core.Address("addr_test1vz3ppzmmzuz0nlsjeyrqjm4pvdxl3cyfe8x06eg6htj2gwgv02qjt")
+
+// Serve a single request, recording it and replying with the status and body.
+func setupRuntime(t *testing.T, status int, reply string) (*runtime.Client, *http.Request, *[]byte) {
+	var got http.Request
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = *r
+		body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+
+	return runtime.NewClient(server.URL), &got, &body
+}
+
+func TestClient_CreateContract(t *testing.T) {
+	client, req, body := setupRuntime(t, http.StatusCreated, `{
+		"links": {"contract": "contracts/abc%231"},
+		"resource": {"contractId": "abc#1", "txBody": {"cborHex": "84a400", "description": "", "type": "TxBodyBabbage"}}
+	}`)
+
+	created, err := client.CreateContract(context.Background(), runtime.CreateRequest{
+		Wallet:   runtime.Wallet{ChangeAddress: changeAddress, Collateral: []string{"def#0"}},
+		Contract: core.Close,
+		Tags:     map[string]any{"escrow": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ContractId != "abc#1" || created.TxBody != "84a400" {
+		t.Errorf("Unexpected response: %+v", created)
+	}
+
+	if req.Method != http.MethodPost || req.URL.Path != "/contracts" {
+		t.Errorf("Unexpected request: %v %v", req.Method, req.URL.Path)
+	}
+	if req.Header.Get("X-Change-Address") != string(changeAddress) || req.Header.Get("X-Collateral-UTxO") != "def#0" {
+		t.Errorf("Unexpected headers: %v", req.Header)
+	}
+	if req.Header.Get("X-Address") != "" {
+		t.Errorf("Expected no X-Address header, got: %v", req.Header.Get("X-Address"))
+	}
+
+	var sent json.RawMessage = *body
+	assert.Json(t, sent, `{"version":"v1","contract":"close","metadata":{},"tags":{"escrow":null}}`)
+}
+
+func TestClient_ApplyInputs(t *testing.T) {
+	client, req, body := setupRuntime(t, http.StatusCreated, `{
+		"resource": {"contractId": "abc#1", "transactionId": "fed", "txBody": {"cborHex": "84a500"}}
+	}`)
+
+	buyer := core.Role{Name: "buyer"}
+	applied, err := client.ApplyInputs(context.Background(), runtime.ApplyInputsRequest{
+		Wallet:     runtime.Wallet{ChangeAddress: changeAddress, Addresses: []core.Address{changeAddress, "addr_test1other"}},
+		ContractId: "abc#1",
+		Inputs: []core.Input{
+			core.NewIDeposit(buyer, buyer, core.Ada, big.NewInt(50000000)),
+			core.IChoice{ChoiceId: core.ChoiceId{Name: "approve", Owner: buyer}, ChosenNum: core.SetChosenNum("1")},
+			core.INotify{},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.ContractId != "abc#1" || applied.TransactionId != "fed" || applied.TxBody != "84a500" {
+		t.Errorf("Unexpected response: %+v", applied)
+	}
+
+	if req.URL.EscapedPath() != "/contracts/abc%231/transactions" {
+		t.Errorf("Unexpected path: %v", req.URL.EscapedPath())
+	}
+	if req.Header.Get("X-Address") != string(changeAddress)+",addr_test1other" {
+		t.Errorf("Unexpected X-Address header: %v", req.Header.Get("X-Address"))
+	}
+
+	var sent json.RawMessage = *body
+	assert.Json(t, sent, `{"version":"v1","inputs":[`+
+		`{"input_from_party":{"role_token":"buyer"},"that_deposits":50000000,"of_token":{"currency_symbol":"","token_name":""},"into_account":{"role_token":"buyer"}},`+
+		`{"for_choice_id":{"choice_name":"approve","choice_owner":{"role_token":"buyer"}},"input_that_chooses_num":1},`+
+		`"input_notify"],"metadata":{},"tags":{}}`)
+}
+
+func TestClient_Error(t *testing.T) {
+	client, _, _ := setupRuntime(t, http.StatusBadRequest, `{"message": "Invalid contract", "errorCode": "BadRequest"}`)
+
+	_, err := client.CreateContract(context.Background(), runtime.CreateRequest{Contract: core.Close})
+
+	var runtimeErr *runtime.Error
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("Expected a runtime.Error, got: %v", err)
+	}
+	if runtimeErr.StatusCode != http.StatusBadRequest || runtimeErr.ErrorCode != "BadRequest" || runtimeErr.Message != "Invalid contract" {
+		t.Errorf("Unexpected error: %+v", runtimeErr)
+	}
+}
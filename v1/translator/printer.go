@@ -0,0 +1,321 @@
+// Copyright 2022 Menabrea Labs Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translator
+
+import (
+	"fmt"
+	"strings"
+
+	core "github.com/menabrealabs/marlowe/v1/language/core"
+)
+
+const indent = "    "
+
+// Print renders a Core contract as Marlowe source, the inverse of the parser.
+// Each argument of a contract is printed on its own line, indented one level
+// deeper than its construct, while actions, values and observations are
+// printed on a single line, so the layout depends only on the structure of
+// the contract. An error is returned for any construct that has no Core
+// Marlowe source form, such as an Extended parameter or a missing field.
+func Print(c core.Contract) (string, error) {
+	source, err := printContract(c, "")
+	if err != nil {
+		return "", err
+	}
+	return source + "\n", nil
+}
+
+// Print the contract with each line after the first indented by prefix.
+func printContract(c core.Contract, prefix string) (string, error) {
+	inner := prefix + indent
+	lines := func(head string, args ...string) string {
+		return head + "\n" + inner + strings.Join(args, "\n"+inner)
+	}
+
+	switch c := c.(type) {
+	case core.CloseContract:
+		return "Close", nil
+	case core.Pay:
+		payee, err := printPayee(c.To)
+		if err != nil {
+			return "", err
+		}
+		from, err := printParty(c.From)
+		if err != nil {
+			return "", err
+		}
+		value, err := printArg(c.Pay)
+		if err != nil {
+			return "", err
+		}
+		then, err := printContractArg(c.Then, inner)
+		if err != nil {
+			return "", err
+		}
+		return lines("Pay", wrap(from), wrap(payee), wrap(printToken(c.Token)), value, then), nil
+	case core.If:
+		observation, err := printArg(c.Observe)
+		if err != nil {
+			return "", err
+		}
+		then, err := printContractArg(c.Then, inner)
+		if err != nil {
+			return "", err
+		}
+		els, err := printContractArg(c.Else, inner)
+		if err != nil {
+			return "", err
+		}
+		return lines("If", observation, then, els), nil
+	case core.When:
+		cases, err := printCases(c.Cases, inner)
+		if err != nil {
+			return "", err
+		}
+		timeout, ok := c.Timeout.(core.POSIXTime)
+		if !ok {
+			return "", fmt.Errorf("unsupported timeout %v", c.Timeout)
+		}
+		then, err := printContractArg(c.Then, inner)
+		if err != nil {
+			return "", err
+		}
+		return lines("When", cases, fmt.Sprint(int(timeout)), then), nil
+	case core.Let:
+		value, err := printArg(c.Value)
+		if err != nil {
+			return "", err
+		}
+		then, err := printContractArg(c.Then, inner)
+		if err != nil {
+			return "", err
+		}
+		return lines("Let", quote(string(c.Name)), value, then), nil
+	case core.Assert:
+		observation, err := printArg(c.Observe)
+		if err != nil {
+			return "", err
+		}
+		then, err := printContractArg(c.Then, inner)
+		if err != nil {
+			return "", err
+		}
+		return lines("Assert", observation, then), nil
+	}
+
+	return "", fmt.Errorf("unsupported contract %v", c)
+}
+
+// Print a contract that is the argument of another, in parentheses unless it
+// is Close.
+func printContractArg(c core.Contract, prefix string) (string, error) {
+	source, err := printContract(c, prefix)
+	if err != nil {
+		return "", err
+	}
+	return wrap(source), nil
+}
+
+func printCases(cases []core.Case, prefix string) (string, error) {
+	inner := prefix + indent
+	printed := make([]string, len(cases))
+
+	for i, cs := range cases {
+		action, err := printAction(cs.Action)
+		if err != nil {
+			return "", err
+		}
+		if cs.MerkleizedThen != "" {
+			printed[i] = "MerkleizedCase\n" + inner + wrap(action) + "\n" + inner + quote(string(cs.MerkleizedThen))
+			continue
+		}
+		then, err := printContractArg(cs.Then, inner)
+		if err != nil {
+			return "", err
+		}
+		printed[i] = "Case\n" + inner + wrap(action) + "\n" + inner + then
+	}
+
+	return "[" + strings.Join(printed, ",\n"+prefix) + "]", nil
+}
+
+func printAction(a core.Action) (string, error) {
+	switch a := a.(type) {
+	case core.Deposit:
+		account, err := printParty(a.IntoAccount)
+		if err != nil {
+			return "", err
+		}
+		party, err := printParty(a.Party)
+		if err != nil {
+			return "", err
+		}
+		value, err := printArg(a.Deposits)
+		if err != nil {
+			return "", err
+		}
+		return join("Deposit", wrap(account), wrap(party), wrap(printToken(a.Token)), value), nil
+	case core.Choice:
+		choice, err := printChoiceId(a.ChoiceId)
+		if err != nil {
+			return "", err
+		}
+		bounds := make([]string, len(a.Bounds))
+		for i, b := range a.Bounds {
+			bounds[i] = fmt.Sprintf("Bound %d %d", b.Lower, b.Upper)
+		}
+		return join("Choice", wrap(choice), "["+strings.Join(bounds, ", ")+"]"), nil
+	case core.Notify:
+		observation, err := printArg(a.If)
+		if err != nil {
+			return "", err
+		}
+		return join("Notify", observation), nil
+	}
+
+	return "", fmt.Errorf("unsupported action %v", a)
+}
+
+func printParty(p core.Party) (string, error) {
+	switch p := p.(type) {
+	case core.Role:
+		return join("Role", quote(p.Name)), nil
+	case core.Address:
+		return join("Address", quote(string(p))), nil
+	}
+	return "", fmt.Errorf("unsupported party %v", p)
+}
+
+func printPayee(p core.Payee) (string, error) {
+	if p.Account != nil {
+		account, err := printParty(p.Account)
+		return join("Account", wrap(account)), err
+	}
+	party, err := printParty(p.Party)
+	return join("Party", wrap(party)), err
+}
+
+func printToken(t core.Token) string {
+	return join("Token", quote(t.Symbol), quote(t.Name))
+}
+
+func printChoiceId(c core.ChoiceId) (string, error) {
+	owner, err := printParty(c.Owner)
+	return join("ChoiceId", quote(c.Name), wrap(owner)), err
+}
+
+// Print a value or observation that is the argument of another construct.
+func printArg(v core.Value) (string, error) {
+	source, err := printValue(v)
+	return wrap(source), err
+}
+
+func printValue(v core.Value) (string, error) {
+	binary := func(head string, x, y core.Value) (string, error) {
+		a, err := printArg(x)
+		if err != nil {
+			return "", err
+		}
+		b, err := printArg(y)
+		return join(head, a, b), err
+	}
+
+	switch v := v.(type) {
+	case core.Constant:
+		// Negative numbers are parenthesized, as Haskell's Show does
+		if v.Int().Sign() < 0 {
+			return join("Constant", "("+v.Int().String()+")"), nil
+		}
+		return join("Constant", v.Int().String()), nil
+	case core.AvailableMoney:
+		account, err := printParty(v.Account)
+		return join("AvailableMoney", wrap(account), wrap(printToken(v.Amount))), err
+	case core.ChoiceValue:
+		choice, err := printChoiceId(v.Value)
+		return join("ChoiceValue", wrap(choice)), err
+	case core.UseValue:
+		return join("UseValue", quote(string(v.Value))), nil
+	case core.TimeIntervalValue:
+		if v == core.TimeIntervalStart {
+			return "TimeIntervalStart", nil
+		}
+		return "TimeIntervalEnd", nil
+	case core.NegValue:
+		neg, err := printArg(v.Neg)
+		return join("NegValue", neg), err
+	case core.AddValue:
+		return binary("AddValue", v.Add, v.To)
+	case core.SubValue:
+		return binary("SubValue", v.From, v.Subtract)
+	case core.MulValue:
+		return binary("MulValue", v.Multiply, v.By)
+	case core.DivValue:
+		return binary("DivValue", v.Divide, v.By)
+	case core.Cond:
+		observation, err := printArg(v.Observation)
+		if err != nil {
+			return "", err
+		}
+		branches, err := binary("Cond", v.IfTrue, v.IfFalse)
+		return strings.Replace(branches, "Cond", "Cond "+observation, 1), err
+	case core.AndObs:
+		return binary("AndObs", v.Both, v.And)
+	case core.OrObs:
+		return binary("OrObs", v.Either, v.Or)
+	case core.NotObs:
+		not, err := printArg(v.Not)
+		return join("NotObs", not), err
+	case core.ChoseSomething:
+		choice, err := printChoiceId(v.Choice)
+		return join("ChoseSomething", wrap(choice)), err
+	case core.ValueGE:
+		return binary("ValueGE", v.Value, v.Ge)
+	case core.ValueGT:
+		return binary("ValueGT", v.Value, v.Gt)
+	case core.ValueLT:
+		return binary("ValueLT", v.Value, v.Lt)
+	case core.ValueLE:
+		return binary("ValueLE", v.Value, v.Le)
+	case core.ValueEQ:
+		return binary("ValueEQ", v.Value, v.Eq)
+	case core.BoolObs:
+		if v {
+			return "TrueObs", nil
+		}
+		return "FalseObs", nil
+	}
+
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+func join(head string, args ...string) string {
+	return strings.Join(append([]string{head}, args...), " ")
+}
+
+// Parenthesize a construct with arguments, leaving bare keywords, numbers and
+// strings as they are.
+func wrap(source string) string {
+	if !strings.ContainsAny(source, " \n") || strings.HasPrefix(source, `"`) {
+		return source
+	}
+	return "(" + source + ")"
+}
+
+// Quote the string as the scanner expects, escaping quotes, backslashes,
+// newlines and tabs.
+func quote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(s) + `"`
+}
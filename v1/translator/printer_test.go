@@ -0,0 +1,124 @@
+package translator_test
+
+import (
+	"strings"
+	"testing"
+
+	c "github.com/menabrealabs/marlowe/v1/language/core"
+	scan "github.com/menabrealabs/marlowe/v1/translator"
+)
+
+func setupPrintContract() c.Contract {
+	buyer, seller := c.Role{Name: "buyer"}, c.Role{Name: "seller"}
+
+	return c.When{
+		Cases: []c.Case{
+			{
+				Action: c.Deposit{IntoAccount: seller, Party: buyer, Token: c.Ada, Deposits: c.SetConstant("50")},
+				Then: c.If{
+					Observe: c.ValueGT{Value: c.UseValue{Value: "price"}, Gt: c.TimeIntervalStart},
+					Then: c.Pay{
+						From:  seller,
+						To:    c.Payee{Party: buyer},
+						Token: c.Ada,
+						Pay:   c.AvailableMoney{Amount: c.Ada, Account: seller},
+						Then:  c.Close,
+					},
+					Else: c.Close,
+				},
+			},
+			{
+				Action: c.Choice{
+					ChoiceId: c.ChoiceId{Name: "say \"no\"", Owner: c.Address("addr1_buyer")},
+					Bounds:   []c.Bound{{Lower: 0, Upper: 1}},
+				},
+				Then: c.Close,
+			},
+		},
+		Timeout: c.POSIXTime(1666078977926),
+		Then:    c.Close,
+	}
+}
+
+func TestPrint(t *testing.T) {
+	source, err := scan.Print(setupPrintContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `When
+    [Case
+        (Deposit (Role "seller") (Role "buyer") (Token "" "") (Constant 50))
+        (If
+            (ValueGT (UseValue "price") TimeIntervalStart)
+            (Pay
+                (Role "seller")
+                (Party (Role "buyer"))
+                (Token "" "")
+                (AvailableMoney (Role "seller") (Token "" ""))
+                Close)
+            Close),
+    Case
+        (Choice (ChoiceId "say \"no\"" (Address "addr1_buyer")) [Bound 0 1])
+        Close]
+    1666078977926
+    Close
+`
+
+	if source != expected {
+		t.Errorf("Failed to print contract.\nExpected:\n%v\nGot:\n%v", expected, source)
+	}
+}
+
+func TestPrint_Scans(t *testing.T) {
+	source, err := scan.Print(setupPrintContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range testScanner(source) {
+		if token.Type == scan.INVALID || token.Type == scan.ERROR {
+			t.Errorf("Printed source does not scan: %v", token)
+		}
+	}
+}
+
+func TestPrint_Unsupported(t *testing.T) {
+	contract := c.When{Timeout: nil, Then: c.Close}
+
+	if _, err := scan.Print(contract); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Expected an error for the missing timeout, got: %v", err)
+	}
+}
+
+func TestPrint_NegativeConstant(t *testing.T) {
+	contract := c.Let{
+		Name:  "change",
+		Value: c.SubValue{From: c.SetConstant("-5"), Subtract: c.UseValue{Value: "price"}},
+		Then:  c.Close,
+	}
+
+	source, err := scan.Print(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SubValue x y is x − y, so the minuend From is printed first.
+	expected := "Let\n    \"change\"\n    (SubValue (Constant (-5)) (UseValue \"price\"))\n    Close\n"
+	if source != expected {
+		t.Errorf("Failed to print contract.\nExpected:\n%v\nGot:\n%v", expected, source)
+	}
+
+	var ints []string
+	for _, token := range testScanner(source) {
+		if token.Type == scan.INVALID || token.Type == scan.ERROR {
+			t.Errorf("Printed source does not scan: %v", token)
+		}
+		if token.Type == scan.INT {
+			ints = append(ints, token.Value)
+		}
+	}
+	if len(ints) != 1 || ints[0] != "-5" {
+		t.Errorf("Expected the constant to rescan as INT -5, got: %v", ints)
+	}
+}
@@ -19,7 +19,9 @@ package translator
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"unicode"
 )
 
@@ -36,6 +38,13 @@ const (
 	SQUARE_L // [
 	SQUARE_R // ]
 	COMMA    // ,
+
+	// Trivia, emitted only when the Scanner's Trivia option is set
+	WHITESPACE // a run of spaces and tabs
+	NEWLINE    // \n
+	COMMENT    // -- line comment, or {- block comment -}
+
+	ERROR // the reader failed; see Scanner.Err
 )
 
 var tokens = [...]string{
@@ -49,16 +58,24 @@ var tokens = [...]string{
 	SQUARE_L: "[",
 	SQUARE_R: "]",
 	COMMA:    ",",
+
+	WHITESPACE: "WHITESPACE",
+	NEWLINE:    "NEWLINE",
+	COMMENT:    "COMMENT",
+
+	ERROR: "ERROR",
 }
 
 var validKeywords = [...]string{
 	// Contracts
-	"Let", "When", "If", "Pay", "Assert", "Close",
+	"Let", "When", "If", "Pay", "Assert", "Close", "Case", "MerkleizedCase",
+	// Parties, payees and tokens
+	"Role", "Address", "Party", "Account", "Token",
 	//Actions
 	"Deposit", "Notify", "Choice", "ChoiceId", "Bound",
 	//Values
 	"AvailableMoney", "Constant", "NegValue", "AddValue", "SubValue", "MulValue", "DivValue",
-	"ChoiceValue", "TimeIntervalValue", "UseValue", "Cond",
+	"ChoiceValue", "TimeIntervalValue", "TimeIntervalStart", "TimeIntervalEnd", "UseValue", "Cond",
 	// Observations
 	"AndObs", "OrObs", "NotObs", "ChoseSomething", "ValueGE", "ValueGT", "ValueLE", "ValueLT", "ValueEQ", "TrueObs", "FalseObs",
 }
@@ -79,8 +96,15 @@ type Position struct {
 }
 
 type Scanner struct {
-	position Position
-	reader   *bufio.Reader
+	// Trivia makes Scan emit WHITESPACE, NEWLINE and COMMENT tokens instead of
+	// skipping them, so that the source can be reproduced exactly from the
+	// tokens, e.g. by a formatter. It is off by default.
+	Trivia bool
+
+	position  Position
+	reader    *bufio.Reader
+	err       error
+	unscanned []Token // pushed back by Unscan, the next to scan last
 }
 
 func NewScanner(reader io.Reader) *Scanner {
@@ -90,25 +114,56 @@ func NewScanner(reader io.Reader) *Scanner {
 	}
 }
 
+// Scan returns the next token, or an EOF token at the end of the input. If
+// reading the input fails, it returns an ERROR token holding the error, which
+// is also reported by Err; every later call returns the same ERROR token.
 func (scan *Scanner) Scan() Token {
-	for {
-		rune, _, err := scan.reader.ReadRune()
+	if n := len(scan.unscanned); n > 0 {
+		token := scan.unscanned[n-1]
+		scan.unscanned = scan.unscanned[:n-1]
+		return token
+	}
 
-		// Return EOF when we get an io.EOF from the reader
-		if err == io.EOF {
-			return Token{Type: EOF}
-		}
+	token := scan.next()
+	if scan.err != nil {
+		return Token{Type: ERROR, Value: scan.err.Error(), Position: scan.position}
+	}
+	return token
+}
 
-		// Panic on any other unhandled error
-		if err != nil {
-			panic(err)
-		}
+// Peek returns the next token without consuming it, so that the following
+// Scan or Peek returns the same token.
+func (scan *Scanner) Peek() Token {
+	token := scan.Scan()
+	scan.Unscan(token)
+	return token
+}
+
+// Unscan pushes the token back onto the stream, to be returned by the next
+// Scan. Tokens pushed back in turn are returned in the reverse order.
+func (scan *Scanner) Unscan(token Token) {
+	scan.unscanned = append(scan.unscanned, token)
+}
 
-		scan.position.Column++
+// Err returns the first error, other than io.EOF, met while reading the input.
+func (scan *Scanner) Err() error {
+	return scan.err
+}
+
+func (scan *Scanner) next() Token {
+	for {
+		rune, ok := scan.read()
+		if !ok {
+			return Token{Type: EOF}
+		}
 
 		switch rune {
 		case '\n':
+			token := Token{Type: NEWLINE, Value: "\n", Position: scan.position}
 			scan.resetPosition()
+			if scan.Trivia {
+				return token
+			}
 		case '(':
 			return Token{Type: PARENS_L, Value: "(", Position: scan.position}
 		case ')':
@@ -119,14 +174,48 @@ func (scan *Scanner) Scan() Token {
 			return Token{Type: SQUARE_R, Value: "]", Position: scan.position}
 		case ',':
 			return Token{Type: COMMA, Value: ",", Position: scan.position}
+		case '-', '{':
+			// Scan Haskell-style comments, which may be nested
+			comment, ok, err := scan.comment(rune)
+			if !ok && rune == '-' {
+				// A minus sign directly before a digit negates an INT,
+				// as in (Constant (-5))
+				if next, ok := scan.read(); ok {
+					scan.backup()
+					if unicode.IsDigit(next) {
+						num, err := scan.integer()
+						if err != nil {
+							return Token{Type: INVALID, Value: "-" + num, Position: scan.position}
+						}
+						return Token{Type: INT, Value: "-" + num, Position: scan.position}
+					}
+				}
+			}
+			if !ok {
+				return Token{Type: INVALID, Value: string(rune), Position: scan.position}
+			}
+			if err != nil {
+				return Token{Type: INVALID, Value: comment, Position: scan.position}
+			}
+			if scan.Trivia {
+				return Token{Type: COMMENT, Value: comment, Position: scan.position}
+			}
 		case '"':
 			// Scan the string including quotes
 			scan.backup()
-			str := scan.str()
+			str, at, err := scan.str()
+			if err != nil {
+				return Token{Type: INVALID, Value: str, Position: at}
+			}
 			return Token{Type: STRING, Value: str, Position: scan.position}
 		default:
-			// Ignore spaces
+			// Ignore spaces, unless they are emitted as trivia
 			if unicode.IsSpace(rune) {
+				if scan.Trivia {
+					scan.backup()
+					space := scan.whitespace()
+					return Token{Type: WHITESPACE, Value: space, Position: scan.position}
+				}
 				continue
 			}
 
@@ -168,84 +257,237 @@ func (scan *Scanner) isValidKeyword(word string) bool {
 	return false
 }
 
+// Read the next rune, advancing the column. At the end of the input, or if the
+// reader fails, it returns false, recording any error other than io.EOF.
+func (scan *Scanner) read() (rune, bool) {
+	r, _, err := scan.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			scan.fail(err)
+		}
+		return 0, false
+	}
+
+	scan.position.Column++
+	return r, true
+}
+
 func (scan *Scanner) backup() {
 	if err := scan.reader.UnreadRune(); err != nil {
-		panic(err)
+		scan.fail(err)
+		return
 	}
 	scan.position.Column--
 }
 
+func (scan *Scanner) fail(err error) {
+	if scan.err == nil {
+		scan.err = err
+	}
+}
+
 func (scan *Scanner) integer() (string, error) {
-	var number string
+	var number strings.Builder
 
 	for {
-		rune, _, err := scan.reader.ReadRune()
-		if err == io.EOF {
-			return number, nil
+		rune, ok := scan.read()
+		if !ok {
+			return number.String(), nil
 		}
 
-		scan.position.Column++
-
-		if unicode.IsLetter(rune) || unicode.IsPunct(rune) {
+		// Brackets and commas delimit an integer, e.g. (Constant 50)
+		if unicode.IsLetter(rune) || unicode.IsPunct(rune) && !strings.ContainsRune("()[],", rune) {
 			scan.backup()
-			return number, errors.New("invalid character in an integer")
+			return number.String(), errors.New("invalid character in an integer")
 		}
 
 		if unicode.IsDigit(rune) {
-			number += string(rune)
+			number.WriteRune(rune)
 			continue
 		}
 
 		scan.backup()
-		return number, nil
+		return number.String(), nil
 
 	}
 }
 
-func (scan *Scanner) str() string {
-	var str string
-	var quote uint8
+// The escape sequences allowed in a string, and the runes they stand for.
+var escapes = map[rune]rune{
+	'"':  '"',
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+}
+
+// Scan a quoted string, returning it with its quotes and with any escape
+// sequences as written; Unquote decodes them. Spaces and any other runes are
+// part of the string up to the closing quote. If the input ends first, or the
+// string holds an invalid escape, the string is returned with an error and
+// the position of the problem.
+func (scan *Scanner) str() (string, Position, error) {
+	open, ok := scan.read()
+	if !ok {
+		return "", scan.position, errors.New("missing string")
+	}
+	var str strings.Builder
+	str.WriteRune(open)
 
+	var invalid error
+	var at Position
 	for {
-		rune, _, err := scan.reader.ReadRune()
-		if err == io.EOF {
-			return str
+		rune, ok := scan.read()
+		if !ok {
+			return str.String(), scan.position, errors.New("unterminated string")
+		}
+
+		str.WriteRune(rune)
+		switch rune {
+		case '"':
+			if invalid != nil {
+				return str.String(), at, invalid
+			}
+			return str.String(), scan.position, nil
+		case '\\':
+			escaped, ok := scan.read()
+			if !ok {
+				return str.String(), scan.position, errors.New("unterminated string")
+			}
+			str.WriteRune(escaped)
+			if _, valid := escapes[escaped]; !valid && invalid == nil {
+				invalid, at = fmt.Errorf("invalid escape \\%c", escaped), scan.position
+			}
+		case '\n':
+			scan.resetPosition()
+		}
+	}
+}
+
+// Unquote returns the text of a STRING token without its quotes and with its
+// escape sequences decoded, e.g. "say \"hi\"" becomes say "hi".
+func Unquote(str string) (string, error) {
+	if len(str) < 2 || str[0] != '"' || str[len(str)-1] != '"' {
+		return "", fmt.Errorf("string %v is not quoted", str)
+	}
+
+	var unquoted []rune
+	escaped := false
+	for _, rune := range str[1 : len(str)-1] {
+		if escaped {
+			r, ok := escapes[rune]
+			if !ok {
+				return "", fmt.Errorf("invalid escape \\%c", rune)
+			}
+			unquoted = append(unquoted, r)
+			escaped = false
+			continue
+		}
+		if rune == '\\' {
+			escaped = true
+			continue
 		}
+		unquoted = append(unquoted, rune)
+	}
+	if escaped {
+		return "", fmt.Errorf("string %v ends in an escape", str)
+	}
 
-		scan.position.Column++
+	return string(unquoted), nil
+}
 
-		if rune == '"' {
-			quote++
+func (scan *Scanner) keyword() string {
+	var str strings.Builder
+
+	for {
+		rune, ok := scan.read()
+		if !ok {
+			return str.String()
 		}
 
-		if quote < 2 {
-			str += string(rune)
+		if unicode.IsLetter(rune) {
+			str.WriteRune(rune)
 			continue
 		}
 
 		scan.backup()
-		return str
+		return str.String()
 	}
 }
 
-func (scan *Scanner) keyword() string {
-	var str string
+// Scan the rest of a comment opened by the rune, returning its text including
+// its delimiters, or false if the rune does not open a comment. A line comment
+// runs up to, but not including, the end of the line; block comments nest, as
+// in Haskell, and an error is returned if one is left unterminated.
+func (scan *Scanner) comment(open rune) (string, bool, error) {
+	next, ok := scan.read()
+	if !ok {
+		return "", false, nil
+	}
 
-	for {
-		rune, _, err := scan.reader.ReadRune()
-		if err == io.EOF {
-			return str
+	if next != '-' {
+		scan.backup()
+		return "", false, nil
+	}
+
+	var str strings.Builder
+	str.WriteRune(open)
+	str.WriteRune(next)
+	if open == '-' {
+		for {
+			rune, ok := scan.read()
+			if !ok {
+				return str.String(), true, nil
+			}
+
+			if rune == '\n' {
+				scan.backup()
+				return str.String(), true, nil
+			}
+			str.WriteRune(rune)
+		}
+	}
+
+	depth := 1
+	var prev rune
+	for depth > 0 {
+		rune, ok := scan.read()
+		if !ok {
+			return str.String(), true, errors.New("unterminated block comment")
 		}
+		str.WriteRune(rune)
 
-		scan.position.Column++
+		switch {
+		case rune == '\n':
+			scan.resetPosition()
+		case prev == '{' && rune == '-':
+			depth++
+			rune = 0 // a delimiter does not start another, as in "{-}"
+		case prev == '-' && rune == '}':
+			depth--
+			rune = 0
+		}
+		prev = rune
+	}
 
-		if unicode.IsLetter(rune) {
-			str += string(rune)
+	return str.String(), true, nil
+}
+
+func (scan *Scanner) whitespace() string {
+	var str strings.Builder
+
+	for {
+		rune, ok := scan.read()
+		if !ok {
+			return str.String()
+		}
+
+		if unicode.IsSpace(rune) && rune != '\n' {
+			str.WriteRune(rune)
 			continue
 		}
 
 		scan.backup()
-		return str
+		return str.String()
 	}
 }
 
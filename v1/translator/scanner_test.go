@@ -1,8 +1,11 @@
 package translator_test
 
 import (
+	"errors"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	scan "github.com/menabrealabs/marlowe/v1/translator"
 )
@@ -62,7 +65,7 @@ func TestInvalidKeywords(t *testing.T) {
 }
 
 func TestValidIntegers(t *testing.T) {
-	ints := []string{"1454", "4848844032", "2223454"}
+	ints := []string{"1454", "4848844032", "2223454", "-42"}
 	input := strings.Join(ints, " ")
 	tokens := testScanner(input)
 
@@ -91,7 +94,7 @@ func TestValidStrings(t *testing.T) {
 	tokens := testScanner(input)
 
 	for i, str := range strs {
-		if tokens[i].Type != scan.STRING && tokens[i].Value != str {
+		if tokens[i].Type != scan.STRING || tokens[i].Value != str {
 			t.Errorf("Failed to tokenize string.\nExpected: STRING %v\nGot: %v", str, tokens[i])
 		}
 	}
@@ -148,3 +151,237 @@ func TestValidNewlineReset(t *testing.T) {
 		t.Errorf("Failed to reset newline.\nLine expected: 2\nLine got: %v", tokens[2].Position.Line)
 	}
 }
+
+func TestTriviaTokens(t *testing.T) {
+	scanner := scan.NewScanner(strings.NewReader("Close  \tClose\n Close"))
+	scanner.Trivia = true
+
+	var tokens []scan.Token
+	for token := scanner.Scan(); token.Type != scan.EOF; token = scanner.Scan() {
+		tokens = append(tokens, token)
+	}
+
+	expected := []scan.Token{
+		{Type: scan.KEYWORD, Value: "Close", Position: scan.Position{Line: 1, Column: 5}},
+		{Type: scan.WHITESPACE, Value: "  \t", Position: scan.Position{Line: 1, Column: 8}},
+		{Type: scan.KEYWORD, Value: "Close", Position: scan.Position{Line: 1, Column: 13}},
+		{Type: scan.NEWLINE, Value: "\n", Position: scan.Position{Line: 1, Column: 14}},
+		{Type: scan.WHITESPACE, Value: " ", Position: scan.Position{Line: 2, Column: 1}},
+		{Type: scan.KEYWORD, Value: "Close", Position: scan.Position{Line: 2, Column: 6}},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Failed to emit trivia.\nExpected: %v\nGot: %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Failed to emit trivia.\nExpected: %v\nGot: %v", expected[i], tokens[i])
+		}
+	}
+}
+
+func TestTriviaOffByDefault(t *testing.T) {
+	for _, token := range testScanner("Close \n Close") {
+		if token.Type == scan.WHITESPACE || token.Type == scan.NEWLINE {
+			t.Errorf("Expected trivia to be skipped, got: %v", token)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	tokens := testScanner("Close -- When \"ignored\" Pay\n{- If\n \"also\" {- nested -} ignored -} Let")
+
+	expected := []scan.Token{
+		{Type: scan.KEYWORD, Value: "Close", Position: scan.Position{Line: 1, Column: 5}},
+		{Type: scan.KEYWORD, Value: "Let", Position: scan.Position{Line: 3, Column: 35}},
+		{Type: scan.EOF},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Failed to skip comments.\nExpected: %v\nGot: %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Failed to skip comments.\nExpected: %v\nGot: %v", expected[i], tokens[i])
+		}
+	}
+}
+
+func TestCommentTrivia(t *testing.T) {
+	scanner := scan.NewScanner(strings.NewReader("{- a -}--b"))
+	scanner.Trivia = true
+
+	for _, want := range []string{"{- a -}", "--b"} {
+		if token := scanner.Scan(); token.Type != scan.COMMENT || token.Value != want {
+			t.Errorf("Failed to emit comment.\nExpected: COMMENT %v\nGot: %v", want, token)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	tokens := testScanner("Close {- {- -}")
+
+	if tokens[1].Type != scan.INVALID {
+		t.Errorf("Failed to identify unterminated comment.\nExpected: INVALID\nGot: %v", tokens[1])
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	failure := errors.New("connection reset")
+	reader := io.MultiReader(strings.NewReader("Close Clo"), iotest.ErrReader(failure))
+	scanner := scan.NewScanner(reader)
+
+	if token := scanner.Scan(); token.Type != scan.KEYWORD || token.Value != "Close" {
+		t.Errorf("Failed to tokenize keyword before the error.\nExpected: KEYWORD Close\nGot: %v", token)
+	}
+
+	for i := 0; i < 2; i++ {
+		token := scanner.Scan()
+		if token.Type != scan.ERROR || token.Value != failure.Error() {
+			t.Errorf("Failed to surface reader error.\nExpected: ERROR %v\nGot: %v", failure, token)
+		}
+	}
+
+	if !errors.Is(scanner.Err(), failure) {
+		t.Errorf("Failed to report reader error.\nExpected: %v\nGot: %v", failure, scanner.Err())
+	}
+}
+
+func TestNoErrorAtEOF(t *testing.T) {
+	scanner := scan.NewScanner(strings.NewReader("Close"))
+	for token := scanner.Scan(); token.Type != scan.EOF; token = scanner.Scan() {
+		if token.Type == scan.ERROR {
+			t.Fatalf("Unexpected error token: %v", token)
+		}
+	}
+
+	if scanner.Err() != nil {
+		t.Errorf("Expected no error at EOF, got: %v", scanner.Err())
+	}
+}
+
+func TestStringFollowedByTokens(t *testing.T) {
+	tokens := testScanner(`Close "LeFt & Right3", Close`)
+
+	expected := []scan.Token{
+		{Type: scan.KEYWORD, Value: "Close", Position: scan.Position{Line: 1, Column: 5}},
+		{Type: scan.STRING, Value: `"LeFt & Right3"`, Position: scan.Position{Line: 1, Column: 21}},
+		{Type: scan.COMMA, Value: ",", Position: scan.Position{Line: 1, Column: 22}},
+		{Type: scan.KEYWORD, Value: "Close", Position: scan.Position{Line: 1, Column: 28}},
+		{Type: scan.EOF},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Failed to tokenize string.\nExpected: %v\nGot: %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Failed to tokenize string.\nExpected: %v\nGot: %v", expected[i], tokens[i])
+		}
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	tokens := testScanner(`"buyer`)
+
+	if tokens[0].Type != scan.INVALID || tokens[0].Value != `"buyer` {
+		t.Errorf("Failed to identify unterminated string.\nExpected: INVALID \"buyer\nGot: %v", tokens[0])
+	}
+	if tokens[1].Type != scan.EOF {
+		t.Errorf("Expected EOF after the unterminated string, got: %v", tokens[1])
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tokens := testScanner(`"say \"hi\"" "back\\slash\n"`)
+
+	expected := []struct{ value, unquoted string }{
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash\n"`, "back\\slash\n"},
+	}
+
+	for i, want := range expected {
+		if tokens[i].Type != scan.STRING || tokens[i].Value != want.value {
+			t.Errorf("Failed to tokenize escaped string.\nExpected: STRING %v\nGot: %v", want.value, tokens[i])
+			continue
+		}
+		if unquoted, err := scan.Unquote(tokens[i].Value); err != nil || unquoted != want.unquoted {
+			t.Errorf("Failed to unquote string.\nExpected: %q\nGot: %q, %v", want.unquoted, unquoted, err)
+		}
+	}
+
+	if tokens[2].Type != scan.EOF {
+		t.Errorf("Expected EOF after the strings, got: %v", tokens[2])
+	}
+}
+
+func TestInvalidEscape(t *testing.T) {
+	tokens := testScanner(`Close "bad \q escape" Close`)
+
+	if tokens[1].Type != scan.INVALID || tokens[1].Value != `"bad \q escape"` {
+		t.Errorf("Failed to identify invalid escape.\nExpected: INVALID\nGot: %v", tokens[1])
+	}
+	if tokens[1].Position != (scan.Position{Line: 1, Column: 13}) {
+		t.Errorf("Expected the position of the invalid escape, got: %v", tokens[1].Position)
+	}
+	if tokens[2].Type != scan.KEYWORD {
+		t.Errorf("Expected scanning to resume after the string, got: %v", tokens[2])
+	}
+}
+
+func TestPeekAndUnscan(t *testing.T) {
+	scanner := scan.NewScanner(strings.NewReader("When [\n  Close"))
+
+	first := scanner.Peek()
+	if again := scanner.Peek(); again != first {
+		t.Errorf("Expected peeking twice to return the same token.\nFirst: %v\nSecond: %v", first, again)
+	}
+	if scanned := scanner.Scan(); scanned != first {
+		t.Errorf("Expected Scan to return the peeked token.\nPeeked: %v\nScanned: %v", first, scanned)
+	}
+
+	bracket := scanner.Scan()
+	closeToken := scanner.Scan()
+	if closeToken.Position != (scan.Position{Line: 2, Column: 7}) {
+		t.Errorf("Expected Close at line 2, column 7, got: %v", closeToken.Position)
+	}
+
+	scanner.Unscan(closeToken)
+	scanner.Unscan(bracket)
+	for _, want := range []scan.Token{bracket, closeToken, {Type: scan.EOF}} {
+		if got := scanner.Scan(); got != want {
+			t.Errorf("Failed to rescan pushed back token.\nExpected: %v\nGot: %v", want, got)
+		}
+	}
+}
+
+// A contract source of a few kilobytes, with long strings, integers and
+// keywords, and a trivia-bearing comment on each repetition.
+var benchmarkSource = strings.Repeat(`When
+    [Case
+        (Deposit
+            (Role "a seller with a rather long role name")
+            (Role "a buyer with a rather long role name")
+            (Token "8bb3b343d8e404472337966a722150048c768d0a92a9813596c5338d" "coin")
+            (Constant 123456789012345678901234567890)
+        )
+        (Pay
+            (Role "a buyer with a rather long role name")
+            (Party (Address "addr_test1vqhqudxtwqcpjqesns79hqgqq2q0xx5q0hnzz5es9492yaqpxltpy"))
+            (Token "" "")
+            (AvailableMoney (Role "a buyer") (Token "" ""))
+            Close
+        )] 1666078977926 Close {- a block comment -}
+`, 8)
+
+func BenchmarkScan(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkSource)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		scanner := scan.NewScanner(strings.NewReader(benchmarkSource))
+		scanner.Trivia = true
+		for scanner.Scan().Type != scan.EOF {
+		}
+	}
+}